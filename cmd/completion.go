@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vourteen14/ngcli/layout"
+	"github.com/vourteen14/ngcli/template"
+	"github.com/vourteen14/ngcli/utils"
+)
+
+// Shell completion. Cobra's completion command writes the scripts for
+// bash, zsh, fish and PowerShell; the functions below supply candidates
+// read from the nginx and template directories when the shell asks.
+// Candidates are "value\tdescription" pairs, filtered by the prefix typed.
+
+type completionFunc func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+// Configurations offered by completeConfigs.
+const (
+	allConfigs = iota
+	enabledConfigs
+	disabledConfigs
+	// brokenConfigs are those 'ngcli repair' fixes
+	brokenConfigs
+)
+
+// completeConfigs completes the first argument with configuration names.
+// Where configurations cannot be enabled separately, enable and disable
+// have nothing to offer.
+func completeConfigs(which int) completionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		lay, err := siteLayout()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		sites, err := lay.List()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		var candidates []string
+		for _, site := range sites {
+			name := strings.TrimSuffix(site.Name, ".conf")
+			if !strings.HasPrefix(name, toComplete) {
+				continue
+			}
+			if site.Status == layout.Unknown {
+				if which == allConfigs {
+					candidates = append(candidates, name)
+				}
+				continue
+			}
+
+			if which == brokenConfigs {
+				if site.Status.Broken() {
+					candidates = append(candidates, name+"\t"+string(site.Status))
+				}
+				continue
+			}
+
+			enabled := site.Status == layout.Enabled
+			if which == allConfigs || enabled == (which == enabledConfigs) {
+				candidates = append(candidates, name+"\t"+string(site.Status))
+			}
+		}
+		return candidates, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeTemplates completes the first argument with template names; see
+// templateCandidates.
+func completeTemplates(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return templateCandidates(nil, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeTemplateList completes every argument with a template name not
+// given yet.
+func completeTemplateList(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return templateCandidates(args, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeTemplateFlag completes --template and --from.
+func completeTemplateFlag(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return templateCandidates(nil, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// templateCandidates returns the templates starting with prefix, except
+// those in exclude, described by their metadata.
+func templateCandidates(exclude []string, prefix string) []string {
+	names, _, err := template.ListTemplates(templateDir)
+	if err != nil {
+		return nil
+	}
+
+	var candidates []string
+	for _, name := range names {
+		if !strings.HasPrefix(name, prefix) || slices.Contains(exclude, name) {
+			continue
+		}
+		if tmpl, err := template.LoadTemplate(name, templateDir); err == nil && tmpl.Metadata.Description != "" {
+			name += "\t" + tmpl.Metadata.Description
+		}
+		candidates = append(candidates, name)
+	}
+	return candidates
+}
+
+// completeBuiltinTemplates completes the first argument with the names of
+// the built-in templates.
+func completeBuiltinTemplates(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var candidates []string
+	for _, name := range template.BuiltinNames() {
+		if strings.HasPrefix(name, toComplete) {
+			candidates = append(candidates, name)
+		}
+	}
+	return candidates, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completePackages completes installed template packages not given yet.
+func completePackages(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	packages, _, err := template.ListInstalledPackages(templateDir)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var candidates []string
+	for _, pkg := range packages {
+		if strings.HasPrefix(pkg.Name, toComplete) && !slices.Contains(args, pkg.Name) {
+			candidates = append(candidates, pkg.Name)
+		}
+	}
+	return candidates, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeSetFlag completes generate --set: parameter keys of the template
+// given with --template, then, after "key=", the declared options of the
+// parameter (true and false for booleans).
+func completeSetFlag(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if templateName == "" {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	tmpl, err := template.LoadTemplate(templateName, templateDir)
+	if err != nil || tmpl.Metadata == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var candidates []string
+	if key, value, hasValue := strings.Cut(toComplete, "="); hasValue {
+		param, declared := tmpl.Metadata.Parameter(key)
+		if !declared {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		options := param.Options
+		if param.Type == "boolean" {
+			options = []string{"true", "false"}
+		}
+		for _, option := range options {
+			if strings.HasPrefix(option, value) {
+				candidates = append(candidates, key+"="+option)
+			}
+		}
+		return candidates, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	given, _ := utils.ParseSetFlags(setFlags)
+	for _, param := range tmpl.Metadata.Parameters {
+		if _, exists := given[param.Name]; exists || !strings.HasPrefix(param.Name, toComplete) {
+			continue
+		}
+		description := param.Description
+		if param.Required {
+			description = strings.TrimSpace(description + " (required)")
+		}
+		candidate := param.Name + "="
+		if description != "" {
+			candidate += "\t" + description
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
+
+// completeWords completes a flag with a fixed list of values.
+func completeWords(words ...string) completionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return words, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeDirs completes a flag with directories.
+func completeDirs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return nil, cobra.ShellCompDirectiveFilterDirs
+}
@@ -6,6 +6,9 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/vourteen14/ngcli/filesystem"
+	"github.com/vourteen14/ngcli/layout"
+	"github.com/vourteen14/ngcli/prompt"
+	"github.com/vourteen14/ngcli/state"
 	"github.com/vourteen14/ngcli/system"
 	"github.com/vourteen14/ngcli/utils"
 )
@@ -19,11 +22,16 @@ var deleteCmd = &cobra.Command{
 	Use:   "delete <config_name>",
 	Short: "Delete nginx configuration file",
 	Long: `Delete nginx configuration file and remove any associated symlink.
+Disabled configurations are found under their disabled name.
+
+Configurations generated from templates with several outputs are deleted
+together with all of their files (see ~/.ngcli/state).
 
 The config name should be without the .conf extension.
 Use --force to skip confirmation prompt.`,
-	Args: cobra.ExactArgs(1),
-	RunE: runDelete,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeConfigs(allConfigs),
+	RunE:              runDelete,
 }
 
 func init() {
@@ -36,57 +44,83 @@ func init() {
 func runDelete(cmd *cobra.Command, args []string) error {
 	configName := args[0]
 
-	var configDir string
-	if outputDir != "" {
-		configDir = outputDir
-	} else {
-		var err error
-		configDir, err = utils.DetectNginxConfigPath()
-		if err != nil {
-			return fmt.Errorf("failed to detect nginx config directory: %w", err)
-		}
+	lay, err := siteLayout()
+	if err != nil {
+		return fmt.Errorf("failed to detect nginx layout: %w", err)
 	}
 
-	configPath, err := utils.ResolveConfigPath(configDir, configName)
+	manifest, err := state.Load(configName)
 	if err != nil {
 		return err
 	}
 
-	// Get the actual filename for display and symlink operations
-	configFilename := filepath.Base(configPath)
+	site, err := lay.Find(configName)
+	if err != nil {
+		// Written elsewhere with --out-file
+		main, tracked := mainFile(manifest)
+		if !tracked || !utils.FileExists(main.Path) {
+			return err
+		}
+		site = layout.Site{Name: filepath.Base(main.Path), Path: main.Path, Status: layout.Unknown}
+	}
+
+	// Get the actual filename for display
+	configFilename := site.Name
+	
+	// Files generated together with the configuration
+	var extraFiles []string
+	if manifest != nil {
+		for _, file := range manifest.Files {
+			if file.Output != "" && utils.FileExists(file.Path) {
+				extraFiles = append(extraFiles, file.Path)
+			}
+		}
+	}
 	
 	if !deleteForce {
-		fmt.Printf("Are you sure you want to delete %s? (y/N): ", configFilename)
-		var response string
-		if _, err := fmt.Scanln(&response); err != nil {
-			// Treat scan error or empty input as cancellation
+		if len(extraFiles) > 0 {
+			fmt.Printf("%s was generated together with:\n", configFilename)
+			for _, path := range extraFiles {
+				fmt.Printf("  %s\n", path)
+			}
+		}
+		confirmed, err := prompt.Confirm(fmt.Sprintf("Are you sure you want to delete %s?", configFilename), false, "use --yes or --force to delete it")
+		if err != nil {
+			return err
+		}
+		if !confirmed {
 			fmt.Println("Deletion cancelled")
 			return nil
 		}
+	}
 
-		if response != "y" && response != "Y" && response != "yes" {
-			fmt.Println("Deletion cancelled")
-			return nil
+	changes, err := lay.Remove(site)
+	if err != nil {
+		return fmt.Errorf("failed to delete configuration: %w", err)
+	}
+	if verbose {
+		for _, change := range changes {
+			fmt.Println(change)
 		}
 	}
 
-	if enabledDir, hasEnabled := utils.DetectNginxEnabledPath(); hasEnabled {
-		symlinkPath := filepath.Join(enabledDir, configFilename)
-		if utils.FileExists(symlinkPath) {
-			if err := filesystem.RemoveSymlink(symlinkPath); err != nil {
-				fmt.Printf("Warning: failed to remove symlink %s: %v\n", symlinkPath, err)
-			} else if verbose {
-				fmt.Printf("Removed symlink: %s\n", symlinkPath)
-			}
+	fmt.Printf("Deleted configuration: %s\n", configFilename)
+	
+	for _, path := range extraFiles {
+		// Generated files can hold secrets or hand edits, so keep a copy
+		if err := filesystem.BackupFile(path); err != nil {
+			return fmt.Errorf("failed to create backup: %w", err)
 		}
+		if err := filesystem.DeleteFile(path); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", path, err)
+		}
+		fmt.Printf("Deleted file: %s\n", path)
 	}
 	
-	if err := filesystem.DeleteFile(configPath); err != nil {
-		return fmt.Errorf("failed to delete configuration: %w", err)
+	if err := state.Remove(configName); err != nil {
+		fmt.Printf("Warning: %v\n", err)
 	}
 
-	fmt.Printf("Deleted configuration: %s\n", configFilename)
-
 	if !deleteNoReload {
 		if verbose {
 			fmt.Println("Reloading nginx configuration")
@@ -102,4 +136,11 @@ func runDelete(cmd *cobra.Command, args []string) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+func mainFile(manifest *state.Manifest) (state.File, bool) {
+	if manifest == nil {
+		return state.File{}, false
+	}
+	return manifest.Main()
+}
@@ -2,26 +2,30 @@ package cmd
 
 import (
 	"fmt"
-	"path/filepath"
 
 	"github.com/spf13/cobra"
-	"github.com/vourteen14/ngcli/filesystem"
+	"github.com/vourteen14/ngcli/errcode"
 	"github.com/vourteen14/ngcli/system"
-	"github.com/vourteen14/ngcli/utils"
 )
 
 var disableNoReload bool
 
 var disableCmd = &cobra.Command{
 	Use:   "disable <config_name>",
-	Short: "Disable nginx configuration by removing symlink",
-	Long: `Disable nginx configuration by removing the symbolic link 
-from sites-enabled directory and reload nginx configuration.
+	Short: "Disable nginx configuration without deleting it",
+	Long: `Disable nginx configuration without deleting it and reload nginx
+configuration.
+
+On Debian and Ubuntu the symbolic link in sites-enabled is removed; on
+conf.d systems the configuration is renamed to <name>.conf.disabled, or
+moved to conf.available with 'disable: move' in the layout section of
+~/.ngcli/config.yaml.
 
 The config name should be without the .conf extension.
 Use --no-reload to skip automatic nginx reload.`,
-	Args: cobra.ExactArgs(1),
-	RunE: runDisable,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeConfigs(enabledConfigs),
+	RunE:              runDisable,
 }
 
 func init() {
@@ -33,31 +37,29 @@ func init() {
 func runDisable(cmd *cobra.Command, args []string) error {
 	configName := args[0]
 
-	enabledDir, hasEnabled := utils.DetectNginxEnabledPath()
-	if !hasEnabled {
-		return fmt.Errorf("sites-enabled directory not found (this system may not support enable/disable)")
+	lay, err := siteLayout()
+	if err != nil {
+		return fmt.Errorf("failed to detect nginx layout: %w", err)
 	}
 
-	// Try to resolve the config name to get the actual filename
-	// First check in sites-enabled directly
-	targetPath := filepath.Join(enabledDir, configName)
-	if !utils.FileExists(targetPath) {
-		// Try with .conf extension
-		targetPath = filepath.Join(enabledDir, configName+".conf")
-		if !utils.FileExists(targetPath) {
-			return fmt.Errorf("configuration not enabled: %s", configName)
-		}
+	site, err := lay.Find(configName)
+	if err != nil {
+		return err
 	}
 
-	configFilename := filepath.Base(targetPath)
-	
-	if err := filesystem.RemoveSymlink(targetPath); err != nil {
+	changes, err := lay.Disable(site)
+	if err != nil {
+		if errcode.CodeOf(err) == errcode.ConfigNotEnabled {
+			return err
+		}
 		return fmt.Errorf("failed to disable configuration: %w", err)
 	}
 	
-	fmt.Printf("Disabled configuration: %s\n", configFilename)
+	fmt.Printf("Disabled configuration: %s\n", site.Name)
 	if verbose {
-		fmt.Printf("Removed symlink: %s\n", targetPath)
+		for _, change := range changes {
+			fmt.Println(change)
+		}
 	}
 	
 	if !disableNoReload {
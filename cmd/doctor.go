@@ -0,0 +1,386 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vourteen14/ngcli/errcode"
+	"github.com/vourteen14/ngcli/filesystem"
+	"github.com/vourteen14/ngcli/layout"
+	"github.com/vourteen14/ngcli/output"
+	"github.com/vourteen14/ngcli/system"
+	"github.com/vourteen14/ngcli/template"
+	"github.com/vourteen14/ngcli/utils"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that this host is ready for ngcli",
+	Long: `Check the nginx installation and the directories ngcli works with:
+the nginx binary, its version and modules, the running nginx processes,
+the layout and whether its directories exist and are writable, whether
+nginx.conf includes them, dangling symlinks, backup files, and the
+templates.
+
+Each check passes, warns or fails, with a hint on how to fix it. The exit
+status is 1 (NGCLI-E060) if a check failed. Use -o json for a report
+scripts can read.`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	result := output.NewDoctor(doctorChecks())
+	if printed, err := printResult(result); printed {
+		if err != nil {
+			return err
+		}
+	} else {
+		printChecks(result)
+	}
+
+	if result.Failures > 0 {
+		return errcode.Errorf(errcode.ChecksFailed, "%d of %d checks failed", result.Failures, len(result.Checks))
+	}
+	return nil
+}
+
+func printChecks(result *output.Doctor) {
+	for _, check := range result.Checks {
+		fmt.Printf("%-6s%-20s%s\n", strings.ToUpper(check.Status), check.Name, check.Message)
+		if check.Status != output.Pass || verbose || outputFormat == output.Wide {
+			for _, detail := range check.Details {
+				fmt.Printf("%26s- %s\n", "", detail)
+			}
+		}
+		if check.Hint != "" {
+			fmt.Printf("%26sFix: %s\n", "", check.Hint)
+		}
+	}
+	fmt.Printf("\n%d passed, %s, %d failed\n", result.Passed, plural(result.Warnings, "warning"), result.Failures)
+}
+
+// plural returns the count and the noun, with an s unless there is one.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// doctorChecks runs the checks; the layout checks are skipped when the
+// layout is broken.
+func doctorChecks() []output.Check {
+	build, buildErr := system.NginxBuildInfo()
+	checks := []output.Check{checkNginxBinary(build, buildErr), checkNginxProcesses(build)}
+
+	var discovery *layout.Discovery
+	if build != nil {
+		discovery, _ = layout.Discover(false)
+	}
+
+	lay, layoutCheck := checkLayout()
+	checks = append(checks, layoutCheck)
+	if lay != nil {
+		checks = append(checks, checkLayoutDirs(lay)...)
+		checks = append(checks, checkIncludes(lay, discovery), checkDanglingSymlinks(lay), checkBackupFiles(lay, discovery))
+	}
+
+	return append(checks, checkTemplates())
+}
+
+func checkNginxBinary(build *system.NginxBuild, err error) output.Check {
+	check := output.Check{Name: "nginx binary"}
+	if err != nil {
+		check.Status = output.Fail
+		check.Message = err.Error()
+		check.Hint = "install nginx (e.g. apt install nginx, dnf install nginx, apk add nginx) or add it to PATH"
+		if !errors.Is(err, system.ErrNginxNotFound) {
+			check.Hint = "check that nginx -V runs"
+		}
+		return check
+	}
+
+	check.Status = output.Pass
+	check.Message = fmt.Sprintf("%s at %s, %s", build.Version, build.Binary, plural(len(build.Modules), "module"))
+	check.Details = build.Modules
+	return check
+}
+
+func checkNginxProcesses(build *system.NginxBuild) output.Check {
+	check := output.Check{Name: "nginx process"}
+	processes, err := system.NginxProcesses()
+	if err != nil {
+		check.Status = output.Warn
+		check.Message = err.Error()
+		return check
+	}
+
+	var masters []system.NginxProcess
+	workerUsers := make(map[string]bool)
+	var users []string
+	for _, process := range processes {
+		if process.Master {
+			masters = append(masters, process)
+		} else if !workerUsers[process.User] {
+			workerUsers[process.User] = true
+			users = append(users, process.User)
+		}
+	}
+
+	switch {
+	case len(masters) == 0:
+		check.Status = output.Warn
+		check.Message = "nginx is not running"
+		check.Hint = "start it with 'systemctl start nginx' (or run nginx); reloads fail until it runs"
+		return check
+	case len(masters) > 1:
+		check.Status = output.Warn
+		check.Message = fmt.Sprintf("%d nginx master processes are running", len(masters))
+		check.Hint = "stop the extra instances; 'nginx -s reload' only signals the one in the pid file"
+		for _, master := range masters {
+			check.Details = append(check.Details, fmt.Sprintf("pid %d (%s)", master.PID, master.User))
+		}
+		return check
+	}
+
+	check.Status = output.Pass
+	check.Message = fmt.Sprintf("master pid %d runs as %s", masters[0].PID, masters[0].User)
+	if len(users) > 0 {
+		check.Message += ", workers as " + strings.Join(users, ", ")
+	}
+	if workerUsers["root"] {
+		check.Status = output.Warn
+		confPath := "nginx.conf"
+		if build != nil {
+			confPath = build.ConfPath
+		}
+		check.Hint = fmt.Sprintf("workers should not run as root; set 'user www-data;' (or nginx) at the top of %s", confPath)
+	}
+	return check
+}
+
+// checkLayout returns the layout, or nil if it cannot be used.
+func checkLayout() (layout.Layout, output.Check) {
+	check := output.Check{Name: "layout"}
+	opts, err := layoutOptions()
+	if err == nil {
+		var lay layout.Layout
+		resolved, source := layout.Resolve(opts)
+		if lay, err = layout.New(opts); err == nil {
+			check.Status = output.Pass
+			check.Message = fmt.Sprintf("%s (%s)", lay.Name(), layoutSources[source])
+			if resolved.Name == "" {
+				check.Status = output.Warn
+				check.Hint = "set the layout section of ~/.ngcli/config.yaml (see 'ngcli info')"
+			}
+			return lay, check
+		}
+	}
+
+	check.Status = output.Fail
+	check.Message = err.Error()
+	check.Hint = "fix the layout section of ~/.ngcli/config.yaml (names: " + strings.Join(layout.Names(), ", ") + ")"
+	return nil, check
+}
+
+// checkLayoutDirs checks that the directories of the layout exist and
+// are writable.
+func checkLayoutDirs(lay layout.Layout) []output.Check {
+	var checks []output.Check
+	for _, dir := range layoutDirs(lay) {
+		check := output.Check{Name: "config directory", Status: output.Pass}
+		if dir != lay.ConfigDir() {
+			check.Name = "include directory"
+		}
+
+		switch {
+		case !utils.FileExists(dir):
+			check.Status = output.Fail
+			check.Message = dir + " does not exist"
+			check.Hint = fmt.Sprintf("create it with 'mkdir -p %s', or set the layout section of ~/.ngcli/config.yaml", dir)
+		case filesystem.CheckWritePermission(dir) != nil:
+			check.Status = output.Fail
+			check.Message = dir + " is not writable"
+			check.Hint = "run ngcli as root (e.g. with sudo) or give your user write access to " + dir
+		default:
+			check.Message = dir + " exists and is writable"
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// checkIncludes checks that the http block of nginx.conf includes the
+// configurations of the layout.
+func checkIncludes(lay layout.Layout, discovery *layout.Discovery) output.Check {
+	check := output.Check{Name: "includes"}
+	dir := lay.IncludeDir()
+	switch {
+	case discovery == nil:
+		check.Status = output.Warn
+		check.Message = "cannot read the nginx configuration without nginx"
+		return check
+	case dir == "":
+		check.Status = output.Warn
+		check.Message = "the layout has no include directory to look for"
+		return check
+	}
+	check.Details = discovery.Problems
+
+	for _, include := range discovery.Includes {
+		if include.Context != "http" {
+			continue
+		}
+		if matched, _ := filepath.Match(include.Pattern, filepath.Join(dir, "example.conf")); matched {
+			check.Status = output.Pass
+			check.Message = fmt.Sprintf("%s includes %s (%s:%d)", filepath.Base(discovery.ConfPath), include.Pattern, filepath.Base(include.File), include.Line)
+			return check
+		}
+	}
+
+	pattern := filepath.Join(dir, "*.conf")
+	if dir != lay.ConfigDir() {
+		pattern = filepath.Join(dir, "*")
+	}
+	check.Status = output.Fail
+	check.Message = fmt.Sprintf("%s does not include %s, so nginx never loads its configurations", discovery.ConfPath, dir)
+	check.Hint = fmt.Sprintf("add 'include %s;' to the http block of %s", pattern, discovery.ConfPath)
+	return check
+}
+
+// checkDanglingSymlinks looks for links to missing files in the include
+// directory; nginx refuses to start with them.
+func checkDanglingSymlinks(lay layout.Layout) output.Check {
+	check := output.Check{Name: "dangling symlinks", Status: output.Pass}
+	dir := lay.IncludeDir()
+	if dir == "" {
+		check.Message = "no include directory to check"
+		return check
+	}
+
+	entries, _ := os.ReadDir(dir)
+	var paths []string
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.Type()&os.ModeSymlink == 0 || utils.FileExists(path) {
+			continue
+		}
+		target, _ := os.Readlink(path)
+		check.Details = append(check.Details, fmt.Sprintf("%s -> %s", path, target))
+		paths = append(paths, path)
+	}
+
+	if len(paths) == 0 {
+		check.Message = "no dangling symlinks in " + dir
+		return check
+	}
+	check.Status = output.Fail
+	check.Message = fmt.Sprintf("%s in %s with a missing target; nginx -t fails on them", plural(len(paths), "symlink"), dir)
+	check.Hint = "remove them: rm " + strings.Join(paths, " ")
+	if _, ok := lay.(layout.Repairer); ok {
+		check.Hint = "run 'ngcli repair' to link them to their configurations again, or remove those without one"
+	}
+	return check
+}
+
+// checkBackupFiles looks for the backups ngcli and editors leave next to
+// the configurations. Backups nginx loads are a failure.
+func checkBackupFiles(lay layout.Layout, discovery *layout.Discovery) output.Check {
+	check := output.Check{Name: "backup files", Status: output.Pass}
+	var backups, loaded []string
+	for _, dir := range layoutDirs(lay) {
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || !(strings.Contains(name, ".backup-") || strings.HasSuffix(name, "~") || strings.HasSuffix(name, ".bak")) {
+				continue
+			}
+			path := filepath.Join(dir, name)
+			backups = append(backups, path)
+			if discovery != nil && includedByNginx(discovery, path) {
+				loaded = append(loaded, path)
+			}
+		}
+	}
+
+	switch {
+	case len(backups) == 0:
+		check.Message = "no backup files next to the configurations"
+		return check
+	case len(loaded) > 0:
+		check.Status = output.Fail
+		check.Message = fmt.Sprintf("nginx loads %s as configurations", plural(len(loaded), "backup file"))
+		check.Details = loaded
+	default:
+		check.Status = output.Warn
+		check.Message = fmt.Sprintf("%s next to the configurations", plural(len(backups), "backup file"))
+		check.Details = backups
+	}
+	check.Hint = "move them elsewhere, e.g. mkdir -p /var/backups/nginx && mv <file> /var/backups/nginx/"
+	return check
+}
+
+// includedByNginx reports whether an include of the http block matches
+// path.
+func includedByNginx(discovery *layout.Discovery, path string) bool {
+	for _, include := range discovery.Includes {
+		if matched, _ := filepath.Match(include.Pattern, path); matched && include.Context == "http" {
+			return true
+		}
+	}
+	return false
+}
+
+func checkTemplates() output.Check {
+	check := output.Check{Name: "templates"}
+	if !utils.FileExists(templateDir) {
+		check.Status = output.Warn
+		check.Message = templateDir + " does not exist"
+		check.Hint = "run 'ngcli init' to install the built-in templates"
+		return check
+	}
+
+	names, warnings, err := template.ListTemplates(templateDir)
+	if err != nil {
+		check.Status = output.Fail
+		check.Message = err.Error()
+		return check
+	}
+	if len(names) == 0 {
+		check.Status = output.Warn
+		check.Message = "no templates in " + templateDir
+		check.Hint = "run 'ngcli init' to install the built-in templates"
+		return check
+	}
+
+	for _, name := range names {
+		if _, err := template.LoadTemplate(name, templateDir); err != nil {
+			check.Details = append(check.Details, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	if len(check.Details) > 0 {
+		check.Status = output.Fail
+		check.Message = fmt.Sprintf("%d of %d templates do not parse", len(check.Details), len(names))
+		check.Hint = "'ngcli template validate <name>' shows the problem"
+		return check
+	}
+	if len(warnings) > 0 {
+		check.Status = output.Warn
+		check.Message = fmt.Sprintf("%s parse cleanly, %s skipped", plural(len(names), "template"), plural(len(warnings), "package"))
+		check.Details = warnings
+		check.Hint = "reinstall the package with 'ngcli template install --force <source>'"
+		return check
+	}
+	check.Status = output.Pass
+	check.Message = fmt.Sprintf("%s parse cleanly", plural(len(names), "template"))
+	return check
+}
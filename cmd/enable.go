@@ -2,26 +2,27 @@ package cmd
 
 import (
 	"fmt"
-	"path/filepath"
 
 	"github.com/spf13/cobra"
-	"github.com/vourteen14/ngcli/filesystem"
 	"github.com/vourteen14/ngcli/system"
-	"github.com/vourteen14/ngcli/utils"
 )
 
 var enableNoReload bool
 
 var enableCmd = &cobra.Command{
 	Use:   "enable <config_name>",
-	Short: "Enable nginx configuration by creating symlink",
-	Long: `Enable nginx configuration by creating a symbolic link 
-in sites-enabled directory and reload nginx configuration.
+	Short: "Enable nginx configuration",
+	Long: `Enable nginx configuration and reload nginx configuration.
+
+On Debian and Ubuntu a symbolic link is created in sites-enabled; on
+conf.d systems a disabled configuration is renamed or moved back into
+conf.d. The layout is detected or set in ~/.ngcli/config.yaml.
 
 The config name should be without the .conf extension.
 Use --no-reload to skip automatic nginx reload.`,
-	Args: cobra.ExactArgs(1),
-	RunE: runEnable,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeConfigs(disabledConfigs),
+	RunE:              runEnable,
 }
 
 func init() {
@@ -33,38 +34,26 @@ func init() {
 func runEnable(cmd *cobra.Command, args []string) error {
 	configName := args[0]
 
-	enabledDir, hasEnabled := utils.DetectNginxEnabledPath()
-	if !hasEnabled {
-		return fmt.Errorf("sites-enabled directory not found (this system may not support enable/disable)")
-	}
-
-	var configDir string
-	if outputDir != "" {
-		configDir = outputDir
-	} else {
-		var err error
-		configDir, err = utils.DetectNginxConfigPath()
-		if err != nil {
-			return fmt.Errorf("failed to detect nginx config directory: %w", err)
-		}
+	lay, err := siteLayout()
+	if err != nil {
+		return fmt.Errorf("failed to detect nginx layout: %w", err)
 	}
 
-	sourcePath, err := utils.ResolveConfigPath(configDir, configName)
+	site, err := lay.Find(configName)
 	if err != nil {
 		return err
 	}
 
-	// Get the actual filename for symlink
-	configFilename := filepath.Base(sourcePath)
-	targetPath := filepath.Join(enabledDir, configFilename)
-	
-	if err := filesystem.CreateSymlink(sourcePath, targetPath); err != nil {
+	changes, err := lay.Enable(site)
+	if err != nil {
 		return fmt.Errorf("failed to enable configuration: %w", err)
 	}
 	
-	fmt.Printf("Enabled configuration: %s\n", configFilename)
+	fmt.Printf("Enabled configuration: %s\n", site.Name)
 	if verbose {
-		fmt.Printf("Created symlink: %s -> %s\n", targetPath, sourcePath)
+		for _, change := range changes {
+			fmt.Println(change)
+		}
 	}
 	
 	if !enableNoReload {
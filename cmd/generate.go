@@ -1,23 +1,38 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/vourteen14/ngcli/config"
+	"github.com/vourteen14/ngcli/errcode"
 	"github.com/vourteen14/ngcli/filesystem"
+	"github.com/vourteen14/ngcli/layout"
+	"github.com/vourteen14/ngcli/output"
+	"github.com/vourteen14/ngcli/prompt"
+	"github.com/vourteen14/ngcli/state"
 	"github.com/vourteen14/ngcli/system"
 	"github.com/vourteen14/ngcli/template"
+	"github.com/vourteen14/ngcli/tui"
 	"github.com/vourteen14/ngcli/utils"
+	"gopkg.in/yaml.v2"
 )
 
 var (
 	setFlags     []string
 	dryRun       bool
-	output       string
+	outputFile   string
 	templateName string
 	interactive  bool
+	valuesFile   string
+	noTUI        bool
 )
 
 var generateCmd = &cobra.Command{
@@ -26,15 +41,24 @@ var generateCmd = &cobra.Command{
 	Long: `Generate nginx configuration file from a template with specified parameters.
 
 The config_name will be used as the output filename (config_name.conf).
+Templates can refer to it as {{.config_name}}, for example in computed
+defaults; --dry-run shows the values computed defaults resolve to.
 After generation, the configuration will be automatically validated (nginx -t),
 enabled (symlink created), and nginx will be reloaded.
 
-If a file already exists, you will be prompted to confirm overwrite.
+On a terminal, choosing a template and entering parameters happen in a
+full-screen interface: a fuzzy-searchable template list, and a form that
+checks each value while you type and previews the rendered configuration.
+--no-tui uses line-by-line prompts instead.
+
+If a file already exists, you will be prompted to confirm overwrite (--yes
+overwrites without asking, keeping a backup).
 Use --dry-run to preview the configuration without writing files.
 
 Examples:
   ngcli generate mysite --template prod --set domain=example.com
   ngcli generate api-server --template custom-api --set domain=api.example.com
+  ngcli generate mysite --template prod --values site.yaml --set domain=example.org
   ngcli generate blog                    # Shows available templates to choose from
   ngcli generate test --dry-run          # Preview configuration without writing`,
 	Args: cobra.ExactArgs(1),
@@ -46,16 +70,48 @@ func init() {
 
 	generateCmd.Flags().StringArrayVar(&setFlags, "set", []string{}, "set template parameters (key=value)")
 	generateCmd.Flags().BoolVar(&dryRun, "dry-run", false, "preview output without writing files")
-	generateCmd.Flags().StringVarP(&output, "output", "o", "", "override output file path")
+	generateCmd.Flags().StringVar(&outputFile, "out-file", "", "override output file path")
+	// --output was the output file before it became the global output
+	// format; it still works here but is hidden. It hides the global flag
+	// on this command, so -o is bound to the format again here
+	generateCmd.Flags().StringVar(&outputFile, "output", "", "override output file path")
+	generateCmd.Flags().MarkDeprecated("output", "use --out-file")
+	generateCmd.Flags().StringVarP(&outputFormat, "output-format", "o", output.Table, "output format")
+	generateCmd.Flags().MarkHidden("output-format")
 	generateCmd.Flags().StringVarP(&templateName, "template", "t", "", "template to use (if not specified, shows available templates)")
 	generateCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "interactive mode for parameter input")
+	generateCmd.Flags().StringVarP(&valuesFile, "values", "f", "", "read template parameters from a YAML or JSON file (--set takes precedence)")
+	generateCmd.Flags().BoolVar(&noTUI, "no-tui", false, "use line-by-line prompts instead of the full-screen interface")
+
+	generateCmd.ValidArgsFunction = cobra.NoFileCompletions
+	generateCmd.RegisterFlagCompletionFunc("template", completeTemplateFlag)
+	generateCmd.RegisterFlagCompletionFunc("set", completeSetFlag)
 }
 
-func runGenerate(cmd *cobra.Command, args []string) error {
+func runGenerate(cmd *cobra.Command, args []string) (err error) {
 	configName := args[0] 
 
+	// 'generate x --output json' meant the format, not a file named json
+	if cmd.Flags().Changed("output") && slices.Contains(output.Formats, outputFile) {
+		return errcode.Errorf(errcode.Usage, "--output %s names an output format; generate writes its file with --out-file, and -o %s sets the format", outputFile, outputFile)
+	}
+
+	// answers are the values of a prompted session, saved for the next
+	// attempt if this one fails
+	var answers map[string]string
+	var tmpl *template.Template
+	defer func() {
+		if err != nil && len(answers) > 0 {
+			saveAnswers(tmpl, configName, answers)
+		}
+	}()
+
 	if templateName == "" {
 		selectedTemplate, err := selectTemplate()
+		if errors.Is(err, tui.ErrCancelled) {
+			fmt.Println("Operation cancelled")
+			return nil
+		}
 		if err != nil {
 			return err
 		}
@@ -67,35 +123,69 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to parse set flags: %w", err)
 	}
 
-	tmpl, err := template.LoadTemplate(templateName, templateDir)
+	tmpl, err = template.LoadTemplate(templateName, templateDir)
 	if err != nil {
 		return fmt.Errorf("failed to load template: %w", err)
 	}
 
-	if interactive {
-		params, err = interactiveParameterInput(tmpl, params)
+	if valuesFile != "" {
+		params, err = mergeValuesFile(tmpl, valuesFile, params)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Templates and computed defaults can refer to the configuration name
+	if _, exists := params[template.ConfigNameParameter]; !exists {
+		params[template.ConfigNameParameter] = configName
+	}
+
+	// given keeps secret references unresolved, for saving answers
+	given := make(map[string]string, len(params))
+	for key, value := range params {
+		given[key] = value
+	}
+
+	resolver, err := secretResolver()
+	if err != nil {
+		return err
+	}
+	if tmpl.Metadata != nil {
+		params, err = tmpl.Metadata.ResolveSecrets(params, resolver)
 		if err != nil {
-			return fmt.Errorf("interactive input failed: %w", err)
+			var verr *template.ValidationError
+			if errors.As(err, &verr) {
+				fmt.Println("Failed to resolve secrets:")
+				for _, pe := range verr.Errors {
+					fmt.Printf("  - %s: %s\n", pe.Parameter, pe.Message)
+				}
+				return &errcode.Error{Code: errcode.SecretUnresolved, Err: errors.New("secret resolution failed"), Fields: verr.FieldErrors()}
+			}
+			return err
 		}
 	}
 
+	// confirmed is set once the full-screen form asked before writing
+	confirmed := false
+
 	var content string
+	var secrets []string
 	if tmpl.Metadata != nil && len(tmpl.Metadata.Parameters) > 0 {
-		if len(params) == 0 && !interactive && !dryRun {
+		if !hasParameters(params) && !interactive && !dryRun && useTUI() {
+			interactive = true
+		}
+		if !hasParameters(params) && !interactive && !dryRun {
 			fmt.Printf("Template: %s\n", templateName)
 			if tmpl.Metadata.Description != "" {
 				fmt.Printf("Description: %s\n", tmpl.Metadata.Description)
 			}
 			fmt.Printf("\n%s", tmpl.Metadata.GetParameterHelp())
 			
-			fmt.Print("Enter parameters interactively? (Y/n): ")
-			var response string
-			if _, err := fmt.Scanln(&response); err != nil {
-				// Treat scan error or empty input as "yes" (default)
-				response = ""
+			useInteractive, err := prompt.Confirm("Enter parameters interactively?", true, "use --set key=value or --values <file>")
+			if err != nil {
+				return err
 			}
-
-			if response == "" || response == "y" || response == "Y" || response == "yes" {
+			if useInteractive {
 				interactive = true
 			} else {
 				fmt.Println("Use --set key=value to provide parameters manually")
@@ -103,14 +193,28 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 			}
 		}
 
-		if interactive {
-			params, err = interactiveParameterInput(tmpl, params)
+		if interactive && useTUI() {
+			var values map[string]string
+			params, values, err = tuiParameterInput(tmpl, configName, params, resolver)
+			if errors.Is(err, tui.ErrCancelled) {
+				fmt.Println("Operation cancelled")
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			answers = sessionAnswers(tmpl.Metadata, values, nil)
+			confirmed = !dryRun
+		} else if interactive {
+			var typed map[string]string
+			params, typed, err = interactiveParameterInput(tmpl, params, resolver)
 			if err != nil {
 				return fmt.Errorf("interactive input failed: %w", err)
 			}
+			answers = sessionAnswers(tmpl.Metadata, given, typed)
 		}
 
-		if len(params) == 0 && dryRun {
+		if !hasParameters(params) && dryRun {
 			fmt.Printf("Template: %s\n", templateName)
 			if tmpl.Metadata.Description != "" {
 				fmt.Printf("Description: %s\n", tmpl.Metadata.Description)
@@ -120,31 +224,93 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 			return nil
 		}
 
+		for _, warning := range tmpl.Metadata.DeprecationWarnings(params) {
+			fmt.Printf("Warning: %s\n", warning)
+		}
+
+		secrets = tmpl.Metadata.SecretValues(params)
 		content, err = tmpl.RenderWithValidation(params)
 		if err != nil {
-			fmt.Printf("Template validation failed: %v\n\n", err)
+			var verr *template.ValidationError
+			if !errors.As(err, &verr) {
+				// Render errors can quote values
+				err = errcode.Errorf(errcode.CodeOf(err), "%s", template.MaskSecrets(err.Error(), secrets))
+			}
+			// The error report carries the problem of each parameter
+			if jsonErrors() {
+				return err
+			}
+			if verr != nil {
+				fmt.Println("Template validation failed:")
+				for _, pe := range verr.Errors {
+					fmt.Printf("  - %s: %s\n", pe.Parameter, pe.Message)
+				}
+				fmt.Println()
+			} else {
+				fmt.Printf("Template validation failed: %s\n\n", err)
+			}
 			fmt.Printf("%s", tmpl.Metadata.GetParameterHelp())
-			return fmt.Errorf("template validation failed")
+			return errcode.Summarize(err, "template validation failed")
 		}
 	} else {
 		if err := validateRequiredParamsLegacy(templateName, params); err != nil {
 			return err
 		}
+		// Without declared parameters every --set value is substituted as
+		// given, so only the injection check applies
+		metadata := tmpl.Metadata
+		if metadata == nil {
+			metadata = &template.TemplateMetadata{}
+		}
+		if err := metadata.ValidateParameters(params); err != nil {
+			var verr *template.ValidationError
+			if errors.As(err, &verr) && !jsonErrors() {
+				fmt.Println("Template validation failed:")
+				for _, pe := range verr.Errors {
+					fmt.Printf("  - %s: %s\n", pe.Parameter, pe.Message)
+				}
+			}
+			return errcode.Summarize(err, "template validation failed")
+		}
 		content, err = tmpl.Render(params)
 		if err != nil {
 			return fmt.Errorf("failed to render template: %w", err)
 		}
 	}
 
+	var outputs []template.RenderedFile
+	if tmpl.Metadata != nil {
+		outputs, err = tmpl.RenderOutputs(params)
+		if err != nil {
+			return errcode.Errorf(errcode.CodeOf(err), "failed to render template outputs: %s", template.MaskSecrets(err.Error(), secrets))
+		}
+	}
+
 	if dryRun {
 		fmt.Printf("Config: %s (using template: %s)\n", configName, templateName)
 		if tmpl.Metadata != nil && tmpl.Metadata.Description != "" {
 			fmt.Printf("Description: %s\n", tmpl.Metadata.Description)
 		}
+		if tmpl.Metadata != nil {
+			printComputedDefaults(tmpl.Metadata, params)
+		}
 		fmt.Println("Generated configuration preview:")
 		fmt.Println(strings.Repeat("-", 50))
-		fmt.Println(content)
+		fmt.Println(template.MaskSecrets(content, secrets))
 		fmt.Println(strings.Repeat("-", 50))
+
+		// Relative output paths stay relative when the configuration
+		// directory cannot be detected
+		baseDir := ""
+		if outputPath, err := getOutputPath(configName); err == nil {
+			baseDir = filepath.Dir(outputPath)
+		}
+		for _, file := range outputs {
+			fmt.Printf("\nOutput %s: %s (mode %s)\n", file.Name, resolveOutputPath(baseDir, file.Path), template.FormatFileMode(template.OutputMode(file.Mode, file.Content, secrets)))
+			fmt.Println(strings.Repeat("-", 50))
+			fmt.Println(template.MaskSecrets(file.Content, secrets))
+			fmt.Println(strings.Repeat("-", 50))
+		}
 		return nil
 	}
 
@@ -153,37 +319,113 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to determine output path: %w", err)
 	}
 
-	// Check if file exists and prompt for overwrite
-	if utils.FileExists(outputPath) {
-		fmt.Printf("Configuration file already exists: %s\n", outputPath)
-		fmt.Print("Overwrite existing file? (y/N): ")
-		var response string
-		if _, err := fmt.Scanln(&response); err != nil {
-			// Treat scan error or empty input as "no"
-			fmt.Println("Operation cancelled")
-			return nil
+	files, err := generatedFiles(outputPath, content, outputs, secrets)
+	if err != nil {
+		return err
+	}
+
+	lay, err := siteLayout()
+	if err != nil {
+		return fmt.Errorf("failed to detect nginx layout: %w", err)
+	}
+	// A failed nginx -t only disables what this run enabled
+	wasEnabled := false
+	if site, err := lay.Find(configName); err == nil {
+		wasEnabled = site.Status == layout.Enabled
+	}
+
+	previous, err := state.Load(configName)
+	if err != nil {
+		return err
+	}
+
+	// Check if files exist and prompt for overwrite
+	var existing []string
+	for _, file := range files {
+		if utils.FileExists(file.Path) {
+			existing = append(existing, file.Path)
+		}
+	}
+	if len(existing) > 0 {
+		for _, path := range existing {
+			fmt.Printf("Configuration file already exists: %s\n", path)
+		}
+		if !confirmed {
+			question := "Overwrite existing file?"
+			if len(existing) > 1 {
+				question = "Overwrite existing files?"
+			}
+			overwrite, err := prompt.Confirm(question, false, "use --yes to overwrite (backups are kept)")
+			if err != nil {
+				return err
+			}
+			if !overwrite {
+				fmt.Println("Operation cancelled")
+				return nil
+			}
 		}
 
-		if response != "y" && response != "Y" && response != "yes" {
-			fmt.Println("Operation cancelled")
-			return nil
+		// Create backups before overwriting
+		for _, path := range existing {
+			if err := filesystem.BackupFile(path); err != nil {
+				return fmt.Errorf("failed to create backup: %w", err)
+			}
+			if verbose {
+				fmt.Printf("Created backup of %s\n", path)
+			}
 		}
+	}
 
-		// Create backup before overwriting
-		if err := filesystem.BackupFile(outputPath); err != nil {
-			return fmt.Errorf("failed to create backup: %w", err)
+	// Write configuration files
+	manifest := &state.Manifest{
+		Config:          configName,
+		Template:        templateName,
+		TemplateVersion: tmpl.Metadata.Version,
+		GeneratedAt:     time.Now().UTC(),
+	}
+	var written []string
+	for _, file := range files {
+		if err := filesystem.WriteFileMode(file.Path, file.Content, file.Mode, true); err != nil {
+			// Leave no partial set of files without a manifest entry
+			rollbackFiles(written, existing)
+			return fmt.Errorf("failed to write configuration: %w", err)
 		}
-		if verbose {
-			fmt.Printf("Created backup of existing configuration\n")
+		written = append(written, file.Path)
+		if verbose && file.Mode == 0600 && len(secrets) > 0 {
+			fmt.Printf("Restricted permissions of %s to 0600 (contains secrets)\n", file.Path)
+		}
+		manifest.Files = append(manifest.Files, state.File{
+			Path:   file.Path,
+			Mode:   template.FormatFileMode(file.Mode),
+			Output: file.Name,
+		})
+	}
+
+	// Outputs of the previous generate that were not written this time,
+	// e.g. a conditional htpasswd file, go away with a backup
+	if previous != nil {
+		for _, old := range previous.Files {
+			if manifest.Has(old.Path) || !utils.FileExists(old.Path) {
+				continue
+			}
+			if err := filesystem.BackupFile(old.Path); err != nil {
+				return fmt.Errorf("failed to create backup: %w", err)
+			}
+			if err := filesystem.DeleteFile(old.Path); err != nil {
+				return fmt.Errorf("failed to remove previous output: %w", err)
+			}
+			fmt.Printf("Removed file no longer generated: %s\n", old.Path)
 		}
 	}
 
-	// Write configuration file
-	if err := filesystem.WriteFile(outputPath, content, true); err != nil {
-		return fmt.Errorf("failed to write configuration: %w", err)
+	if err := manifest.Save(); err != nil {
+		return err
 	}
 
 	fmt.Printf("Generated configuration: %s\n", outputPath)
+	for _, file := range files[1:] {
+		fmt.Printf("Generated %s: %s\n", file.Name, file.Path)
+	}
 	if tmpl.Metadata != nil && tmpl.Metadata.Description != "" {
 		fmt.Printf("Template: %s - %s\n", templateName, tmpl.Metadata.Description)
 	}
@@ -194,37 +436,68 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 	}
 
 	if err := system.NginxTest(); err != nil {
-		fmt.Printf("\nError: nginx -t validation failed: %v\n", err)
+		err = errcode.Errorf(errcode.CodeOf(err), "nginx -t validation failed: %s", template.MaskSecrets(err.Error(), secrets))
+		notInstalled := errcode.CodeOf(err) == errcode.NginxNotInstalled
+		if !jsonErrors() {
+			fmt.Printf("\nError: %v\n", err)
+			summary := "nginx validation failed"
+			if notInstalled {
+				summary = "nginx -t skipped: nginx is not installed"
+			}
+			err = errcode.Summarize(err, summary)
+		}
+		// Where nginx includes the output directory, the new file is
+		// enabled as soon as it is written
+		if site, findErr := lay.Find(configName); findErr == nil && site.Path == outputPath && site.Status == layout.Enabled && !wasEnabled {
+			changes, disableErr := lay.Disable(site)
+			if disableErr != nil {
+				fmt.Printf("Warning: failed to disable configuration: %v\n", disableErr)
+				fmt.Println("Configuration file generated and still enabled; fix it before nginx is reloaded")
+				return err
+			}
+			for _, change := range changes {
+				fmt.Println(change)
+			}
+		}
+		if notInstalled {
+			fmt.Println("Configuration file generated but NOT enabled (nginx is not installed, so nginx -t was skipped)")
+			fmt.Println("Install nginx and run 'ngcli enable' when ready")
+			return err
+		}
 		fmt.Println("Configuration file generated but NOT enabled (syntax errors detected)")
 		fmt.Println("Please fix the configuration manually and run 'ngcli enable' when ready")
-		return fmt.Errorf("nginx validation failed")
+		return err
 	}
 
 	if verbose {
 		fmt.Println("nginx -t validation passed")
 	}
 
-	// Auto-enable: Create symlink in sites-enabled (Ubuntu/Debian only)
-	enabledDir, hasEnabled := utils.DetectNginxEnabledPath()
-	if hasEnabled {
-		configFilename := filepath.Base(outputPath)
-		sourcePath := outputPath
-		targetPath := filepath.Join(enabledDir, configFilename)
-
-		if err := filesystem.CreateSymlink(sourcePath, targetPath); err != nil {
+	// Auto-enable, as the layout of the system does it
+	site, err := lay.Find(configName)
+	switch {
+	case err != nil || site.Path != outputPath:
+		if verbose {
+			fmt.Printf("Configuration written outside %s (not enabled)\n", lay.ConfigDir())
+		}
+	case site.Status == layout.Unknown:
+		if verbose {
+			fmt.Println("No nginx layout detected (configuration is active if nginx includes it)")
+		}
+	default:
+		changes, err := lay.Enable(site)
+		if err != nil {
 			fmt.Printf("Warning: failed to enable configuration: %v\n", err)
 			fmt.Println("Configuration generated but not enabled")
-			fmt.Printf("Run 'ngcli enable %s' manually to enable it\n", configFilename)
+			fmt.Printf("Run 'ngcli enable %s' manually to enable it\n", site.Name)
 			return nil
 		}
 
-		fmt.Printf("Enabled configuration: %s\n", configFilename)
-		if verbose {
-			fmt.Printf("Created symlink: %s -> %s\n", targetPath, sourcePath)
-		}
-	} else {
+		fmt.Printf("Enabled configuration: %s\n", site.Name)
 		if verbose {
-			fmt.Println("sites-enabled directory not found (configuration is active by default on this system)")
+			for _, change := range changes {
+				fmt.Println(change)
+			}
 		}
 	}
 
@@ -247,7 +520,7 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 }
 
 func selectTemplate() (string, error) {
-	templates, err := template.ListTemplates(templateDir)
+	templates, err := templateNames()
 	if err != nil {
 		return "", fmt.Errorf("failed to list templates: %w", err)
 	}
@@ -256,6 +529,22 @@ func selectTemplate() (string, error) {
 		return "", fmt.Errorf("no templates found. Run 'ngcli init' to create default templates")
 	}
 
+	if !prompt.Interactive() {
+		return "", prompt.Required("choosing a template", "use --template <name>")
+	}
+
+	if useTUI() {
+		items := make([]tui.Item, 0, len(templates))
+		for _, name := range templates {
+			item := tui.Item{Name: name}
+			if tmplObj, err := template.LoadTemplate(name, templateDir); err == nil {
+				item.Description = tmplObj.Metadata.Description
+			}
+			items = append(items, item)
+		}
+		return tui.PickTemplate(items)
+	}
+
 	fmt.Println("Available templates:")
 	for i, tmpl := range templates {
 		if tmplObj, err := template.LoadTemplate(tmpl, templateDir); err == nil {
@@ -269,9 +558,13 @@ func selectTemplate() (string, error) {
 		}
 	}
 
-	fmt.Printf("\nSelect template (1-%d): ", len(templates))
-	var choice int
-	if _, err := fmt.Scanln(&choice); err != nil {
+	fmt.Println()
+	answer, err := prompt.Line(fmt.Sprintf("Select template (1-%d): ", len(templates)), "use --template <name>")
+	if err != nil {
+		return "", err
+	}
+	choice, err := strconv.Atoi(answer)
+	if err != nil {
 		return "", fmt.Errorf("invalid input")
 	}
 
@@ -282,12 +575,21 @@ func selectTemplate() (string, error) {
 	return templates[choice-1], nil
 }
 
-func interactiveParameterInput(tmpl *template.Template, existingParams map[string]string) (map[string]string, error) {
+// interactiveParameterInput asks for the parameters that have no value
+// yet. Every answer is checked right away and the question asked again
+// until it is valid. answers holds what was entered, with secrets only as
+// references, for saving if generate fails later.
+func interactiveParameterInput(tmpl *template.Template, existingParams map[string]string, resolver *template.SecretResolver) (params, answers map[string]string, err error) {
 	if tmpl.Metadata == nil || len(tmpl.Metadata.Parameters) == 0 {
-		return existingParams, nil
+		return existingParams, nil, nil
 	}
 
-	params := make(map[string]string)
+	if !prompt.Interactive() {
+		return nil, nil, prompt.Required("interactive parameter input", "use --set key=value or --values <file>")
+	}
+
+	params = make(map[string]string)
+	answers = make(map[string]string)
 	
 	for k, v := range existingParams {
 		params[k] = v
@@ -304,38 +606,325 @@ func interactiveParameterInput(tmpl *template.Template, existingParams map[strin
 			continue
 		}
 
-		prompt := fmt.Sprintf("%s (%s)", param.Name, param.Description)
-		if param.Default != "" {
-			prompt += fmt.Sprintf(" [default: %s]", param.Default)
+		// Skip parameters whose @when condition is not met by earlier answers
+		defaults := tmpl.Metadata.ApplyDefaults(params)
+		if !tmpl.Metadata.IsActive(param, defaults) {
+			continue
+		}
+
+		// Computed defaults are shown resolved against the answers so far
+		value, answer, err := askParameter(tmpl.Metadata, param, defaults[param.Name], resolver)
+		if err != nil {
+			return nil, nil, err
+		}
+		if value != "" {
+			params[param.Name] = value
+		}
+		if answer != "" {
+			answers[param.Name] = answer
 		}
-		if param.Required {
-			prompt += " *required*"
+	}
+
+	return params, answers, nil
+}
+
+// askParameter asks for the value of param until the answer is valid. The
+// answer is the value as entered, for secrets the reference. Secrets must
+// be references here too, as with --set and values files.
+func askParameter(metadata *template.TemplateMetadata, param template.ParameterInfo, defaultValue string, resolver *template.SecretResolver) (value, answer string, err error) {
+	label := fmt.Sprintf("%s (%s)", param.Name, param.Description)
+	if len(param.Options) > 0 {
+		// Options are chosen by number; typing the option itself works too
+		fmt.Printf("%s:\n", label)
+		for i, option := range param.Options {
+			fmt.Printf("  %d) %s\n", i+1, option)
 		}
-		prompt += ": "
+		label = fmt.Sprintf("Choose %s (1-%d)", param.Name, len(param.Options))
+	}
+	if param.Type == "boolean" {
+		label += " (true/false)"
+	}
+	if param.Type == "secret" {
+		label += " (env:VAR, file:/path or store:key, not echoed)"
+	}
+	if defaultValue != "" && !param.Sensitive {
+		label += fmt.Sprintf(" [default: %s]", defaultValue)
+	}
+	if param.Required {
+		label += " *required*"
+	}
+	label += ": "
 
-		fmt.Print(prompt)
+	hint := fmt.Sprintf("use --set %s=...", param.Name)
+	for {
+		if param.Type == "secret" {
+			value, err = prompt.Secret(label, hint)
+		} else {
+			value, err = prompt.Line(label, hint)
+		}
+		if err != nil {
+			return "", "", err
+		}
 
-		var value string
-		if _, err := fmt.Scanln(&value); err != nil {
-			// Treat scan error or empty input as empty string
-			value = ""
+		if n, err := strconv.Atoi(value); err == nil && n >= 1 && n <= len(param.Options) {
+			value = param.Options[n-1]
+		}
+		if param.Type == "boolean" {
+			switch strings.ToLower(value) {
+			case "y", "yes":
+				value = "true"
+			case "n", "no":
+				value = "false"
+			}
 		}
 
-		if value == "" && param.Default != "" {
-			value = param.Default
+		if value == "" {
+			value = defaultValue
 		}
-		
-		if value == "" && param.Required {
-			fmt.Printf("Error: %s is required\n", param.Name)
-			return nil, fmt.Errorf("missing required parameter: %s", param.Name)
+		if value == "" {
+			if !param.Required {
+				return "", "", nil
+			}
+			fmt.Printf("  %s is required, please enter a value\n", param.Name)
+			continue
 		}
-		
-		if value != "" {
-			params[param.Name] = value
+
+		answer = value
+		if param.Type == "secret" {
+			if !template.IsSecretReference(value) {
+				fmt.Printf("  Invalid %s: %s, please try again\n", param.Name, template.LiteralSecretMessage)
+				continue
+			}
+			if value, err = resolver.Resolve(value); err != nil {
+				fmt.Printf("  %s: %v\n", param.Name, err)
+				continue
+			}
+		}
+
+		problems := metadata.ValidateValue(param.Name, value)
+		if len(problems) == 0 {
+			return value, answer, nil
+		}
+		for _, problem := range problems {
+			if param.Sensitive {
+				problem = template.MaskSecrets(problem, []string{value})
+			}
+			fmt.Printf("  Invalid %s: %s, please try again\n", param.Name, problem)
+		}
+	}
+}
+
+// sessionAnswers merges the given values and the answers typed at the
+// prompts into the values to save for another attempt. config_name and
+// secrets other than references are left out.
+func sessionAnswers(metadata *template.TemplateMetadata, given, typed map[string]string) map[string]string {
+	answers := make(map[string]string, len(given)+len(typed))
+	for _, values := range []map[string]string{given, typed} {
+		for name, value := range values {
+			if param, declared := metadata.Parameter(name); declared && param.Type == "secret" && !template.IsSecretReference(value) {
+				continue
+			}
+			answers[name] = value
+		}
+	}
+	delete(answers, template.ConfigNameParameter)
+	return answers
+}
+
+// answersPath is where the answers of a failed prompted session for
+// configName are saved.
+func answersPath(configName string) string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".ngcli", "answers", filepath.Base(configName)+".yaml")
+}
+
+// saveAnswers writes the answers of a failed session to a values file and
+// says how to retry with it. Failing to save is only reported.
+func saveAnswers(tmpl *template.Template, configName string, answers map[string]string) {
+	data, err := yaml.Marshal(tmpl.Metadata.ValuesDocument(answers))
+	if err != nil {
+		return
+	}
+
+	path := answersPath(configName)
+	if err := filesystem.WriteFileMode(path, string(data), 0600, true); err != nil {
+		fmt.Printf("Warning: failed to save your answers: %v\n", err)
+		return
+	}
+	fmt.Printf("\nYour answers were saved to %s\n", path)
+	fmt.Printf("Retry with: ngcli generate %s --template %s --values %s\n", configName, templateName, path)
+}
+
+// useTUI reports whether generate asks its questions in the full-screen
+// interface.
+func useTUI() bool {
+	return !noTUI && tui.Available()
+}
+
+// tuiParameterInput edits the parameters in the full-screen form, which
+// asks before files are written unless this is a dry run. Secret
+// references entered in the form are resolved afterwards; values are the
+// form values before that.
+func tuiParameterInput(tmpl *template.Template, configName string, params map[string]string, resolver *template.SecretResolver) (resolved, values map[string]string, err error) {
+	opts := tui.FormOptions{Title: fmt.Sprintf("Generate %s.conf", configName)}
+	if outputPath, err := getOutputPath(configName); err == nil {
+		opts.Title = "Generate " + outputPath
+	}
+	if !dryRun {
+		opts.Confirm = func(values map[string]string) string {
+			return writeQuestion(tmpl, configName, values)
+		}
+	}
+
+	values, err = tui.EditParameters(tmpl, params, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resolved, err = tmpl.Metadata.ResolveSecrets(values, resolver)
+	if err != nil {
+		return nil, nil, errcode.Wrap(errcode.SecretUnresolved, err)
+	}
+	return resolved, values, nil
+}
+
+// writeQuestion asks whether to write the files of a generate with values,
+// pointing out the ones that already exist.
+func writeQuestion(tmpl *template.Template, configName string, values map[string]string) string {
+	outputPath, err := getOutputPath(configName)
+	if err != nil {
+		return ""
+	}
+
+	paths := []string{outputPath}
+	if outputs, err := tmpl.RenderOutputs(values); err == nil {
+		for _, output := range outputs {
+			paths = append(paths, resolveOutputPath(filepath.Dir(outputPath), output.Path))
+		}
+	}
+
+	lines := []string{"Write this file?"}
+	if len(paths) > 1 {
+		lines[0] = "Write these files?"
+	}
+	for _, path := range paths {
+		line := "  " + path
+		if utils.FileExists(path) {
+			line += " (exists; a backup is kept)"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// printComputedDefaults lists the values computed defaults resolved to.
+func printComputedDefaults(metadata *template.TemplateMetadata, params map[string]string) {
+	withDefaults := metadata.ApplyDefaults(params)
+
+	var lines []string
+	for _, param := range metadata.Parameters {
+		value, resolved := withDefaults[param.Name]
+		if _, given := params[param.Name]; given || !resolved || !param.IsComputed() {
+			continue
+		}
+		if param.Sensitive {
+			value = template.SecretMask
+		}
+		lines = append(lines, fmt.Sprintf("  %s = %s", param.Name, value))
+	}
+
+	if len(lines) > 0 {
+		fmt.Println("Computed defaults:")
+		fmt.Println(strings.Join(lines, "\n"))
+	}
+}
+
+// generatedFile is a file about to be written by generate; Name is empty
+// for the main configuration.
+type generatedFile struct {
+	Name    string
+	Path    string
+	Mode    os.FileMode
+	Content string
+}
+
+// generatedFiles lists the main configuration followed by the template
+// outputs, with output paths resolved against the configuration directory.
+func generatedFiles(outputPath, content string, outputs []template.RenderedFile, secrets []string) ([]generatedFile, error) {
+	files := []generatedFile{{
+		Path:    outputPath,
+		Mode:    template.OutputMode(0, content, secrets),
+		Content: content,
+	}}
+
+	owner := map[string]string{outputPath: "the main configuration"}
+	for _, output := range outputs {
+		path := resolveOutputPath(filepath.Dir(outputPath), output.Path)
+		if other, exists := owner[path]; exists {
+			return nil, fmt.Errorf("output %s writes to %s, as does %s", output.Name, path, other)
+		}
+		owner[path] = "output " + output.Name
+
+		files = append(files, generatedFile{
+			Name:    output.Name,
+			Path:    path,
+			Mode:    template.OutputMode(output.Mode, output.Content, secrets),
+			Content: output.Content,
+		})
+	}
+
+	return files, nil
+}
+
+// rollbackFiles undoes the writes of a generate that failed part way:
+// files it created are removed and files it overwrote are restored from
+// the backups taken before.
+func rollbackFiles(written, existing []string) {
+	overwritten := make(map[string]bool)
+	for _, path := range existing {
+		overwritten[path] = true
+	}
+	for _, path := range written {
+		var err error
+		if overwritten[path] {
+			err = filesystem.RestoreLatestBackup(path)
+		} else {
+			err = filesystem.DeleteFile(path)
+		}
+		if err != nil {
+			fmt.Printf("Warning: failed to roll back %s: %v\n", path, err)
+			continue
+		}
+		fmt.Printf("Rolled back %s\n", path)
+	}
+}
+
+func resolveOutputPath(baseDir, path string) string {
+	if filepath.IsAbs(path) || baseDir == "" {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
+
+// hasParameters reports whether any parameter besides the implicit
+// config_name was given.
+func hasParameters(params map[string]string) bool {
+	for name := range params {
+		if name != template.ConfigNameParameter {
+			return true
 		}
 	}
+	return false
+}
 
-	return params, nil
+// secretResolver returns a resolver for the secret store configured in
+// ~/.ngcli/config.yaml.
+func secretResolver() (*template.SecretResolver, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	return &template.SecretResolver{StorePath: cfg.SecretStore, Identity: cfg.AgeIdentity}, nil
 }
 
 func validateRequiredParamsLegacy(templateName string, params map[string]string) error {
@@ -356,21 +945,15 @@ func validateRequiredParamsLegacy(templateName string, params map[string]string)
 }
 
 func getOutputPath(templateName string) (string, error) {
-	if output != "" {
-		return output, nil
+	if outputFile != "" {
+		return outputFile, nil
 	}
 
-	var baseDir string
-	if outputDir != "" {
-		baseDir = outputDir
-	} else {
-		var err error
-		baseDir, err = utils.DetectNginxConfigPath()
-		if err != nil {
-			return "", err
-		}
+	lay, err := siteLayout()
+	if err != nil {
+		return "", err
 	}
 
 	filename := templateName + ".conf"
-	return filepath.Join(baseDir, filename), nil
+	return filepath.Join(lay.ConfigDir(), filename), nil
 }
\ No newline at end of file
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vourteen14/ngcli/filesystem"
+	"github.com/vourteen14/ngcli/template"
+)
+
+func TestGeneratedFiles(t *testing.T) {
+	outputPath := "/etc/nginx/sites-available/shop.conf"
+	outputs := []template.RenderedFile{
+		{Name: "upstream", Path: "../conf.d/shop-upstream.conf", Content: "upstream shop {}"},
+		{Name: "htpasswd", Path: "/etc/nginx/shop.htpasswd", Content: "admin:s3cret"},
+		{Name: "hosts", Path: "/etc/nginx/shop.hosts", Mode: 0640, Content: "s3cret"},
+	}
+
+	files, err := generatedFiles(outputPath, "server {}", outputs, []string{"s3cret"})
+	if err != nil {
+		t.Fatalf("generatedFiles: %v", err)
+	}
+	want := []generatedFile{
+		{Path: outputPath, Mode: 0644, Content: "server {}"},
+		{Name: "upstream", Path: "/etc/nginx/conf.d/shop-upstream.conf", Mode: 0644, Content: "upstream shop {}"},
+		{Name: "htpasswd", Path: "/etc/nginx/shop.htpasswd", Mode: 0600, Content: "admin:s3cret"},
+		{Name: "hosts", Path: "/etc/nginx/shop.hosts", Mode: 0640, Content: "s3cret"},
+	}
+	if len(files) != len(want) {
+		t.Fatalf("files = %+v, want %+v", files, want)
+	}
+	for i := range want {
+		if files[i] != want[i] {
+			t.Errorf("file %d = %+v, want %+v", i, files[i], want[i])
+		}
+	}
+
+	outputs = append(outputs, template.RenderedFile{Name: "copy", Path: "shop.conf"})
+	if _, err := generatedFiles(outputPath, "server {}", outputs, nil); err == nil || !strings.Contains(err.Error(), "as does the main configuration") {
+		t.Errorf("generatedFiles = %v, want an error about the main configuration", err)
+	}
+}
+
+func TestRollbackFiles(t *testing.T) {
+	dir := t.TempDir()
+	overwritten := filepath.Join(dir, "shop.conf")
+	created := filepath.Join(dir, "shop.htpasswd")
+	untouched := filepath.Join(dir, "shop.hosts")
+
+	// A generate backed up and overwrote shop.conf and created
+	// shop.htpasswd, then failed before writing shop.hosts
+	if err := filesystem.WriteFileMode(overwritten, "server { listen 80; }", 0640, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := filesystem.BackupFile(overwritten); err != nil {
+		t.Fatal(err)
+	}
+	if err := filesystem.WriteFileMode(overwritten, "server { listen 8080; }", 0644, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := filesystem.WriteFileMode(created, "admin:s3cret", 0600, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := filesystem.WriteFileMode(untouched, "10.0.0.1", 0644, false); err != nil {
+		t.Fatal(err)
+	}
+
+	rollbackFiles([]string{overwritten, created}, []string{overwritten, untouched})
+
+	info, err := os.Stat(overwritten)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(overwritten); string(content) != "server { listen 80; }" || info.Mode().Perm() != 0640 {
+		t.Errorf("shop.conf = %q mode %04o, want the backup with mode 0640", content, info.Mode().Perm())
+	}
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Errorf("created file not removed: %v", err)
+	}
+	if content, _ := os.ReadFile(untouched); string(content) != "10.0.0.1" {
+		t.Errorf("file that was not written changed: %q", content)
+	}
+}
@@ -46,6 +46,12 @@ func runHelp(cmd *cobra.Command, args []string) {
 		showDeleteHelp()
 	case "reload":
 		showReloadHelp()
+	case "info":
+		showInfoHelp()
+	case "doctor":
+		showDoctorHelp()
+	case "repair":
+		showRepairHelp()
 	case "template":
 		showTemplateHelp()
 	default:
@@ -65,17 +71,39 @@ AVAILABLE COMMANDS:
   generate    Generate nginx configuration from template
   list        List nginx configurations or templates
   show        Show contents of nginx configuration file
-  enable      Enable nginx configuration (create symlink + reload)
-  disable     Disable nginx configuration (remove symlink + reload)
+  enable      Enable nginx configuration (symlink or rename + reload)
+  disable     Disable nginx configuration (remove symlink or rename + reload)
   delete      Delete nginx configuration file
   reload      Reload nginx configuration
+  info        Show the nginx installation and layout found
+  doctor      Check that this host is ready for ngcli
+  repair      Repair broken, foreign or copied entries in sites-enabled
   template    Manage nginx configuration templates
+  validate-values Validate a values file against a template
+  completion  Generate a shell completion script (bash, zsh, fish, powershell);
+              sites, templates and --set keys complete dynamically
   help        Display help information
 
 GLOBAL FLAGS:
   --template-dir string   Directory containing templates
   --output-dir string     Override output directory
   -v, --verbose          Verbose output
+  -o, --output string    Output format of list and show commands: table,
+                         wide, json, yaml or name
+  --error-format string  Error format on stderr: text or json
+  -y, --yes              Answer yes to overwrite and delete confirmations
+  --no-input             Never prompt (also NGCLI_NONINTERACTIVE=1); commands
+                         that need input fail instead. Prompts also require
+                         stdin to be a terminal
+
+EXIT STATUS:
+  0 success, 1 other failure, 2 invalid command line or input needed
+  without a terminal, 3 invalid parameters or values, 4 template or
+  configuration not found, 5 invalid template or failed lint/tests,
+  6 nginx -t failed, 7 nginx missing or reload failed, 8 permission
+  denied. Errors carry codes such as
+  NGCLI-E012 (nginx test failed), NGCLI-E020 (template not found) and
+  NGCLI-E030 (missing parameters).
 
 QUICK START:
   ngcli init                                    Initialize ngcli
@@ -138,18 +166,34 @@ USAGE:
 FLAGS:
   -t, --template string   Template to use (if not specified, shows available templates)
       --set stringArray   Set template parameters (key=value)
+  -f, --values string     Read template parameters from a YAML or JSON file
   -i, --interactive      Interactive mode for parameter input
+      --no-tui           Use line-by-line prompts instead of the full-screen interface
       --dry-run          Preview output without writing files
-      --force            Overwrite existing files without confirmation
-  -o, --output string    Override output file path
+  -y, --yes              Overwrite existing files without confirmation (backups are kept)
+      --out-file string  Override output file path (was --output)
 
 DESCRIPTION:
   Generates nginx configuration file from a template with specified parameters.
   The config_name becomes the output filename (config_name.conf).
+  Templates with @output declarations also write their additional files;
+  all files of a configuration are backed up, replaced and tracked together
+  in ~/.ngcli/state.
   
   If no template is specified, shows available templates to choose from.
   If no parameters are provided, automatically prompts for interactive input.
 
+  On a terminal, questions are asked in a full-screen interface: a template
+  list filtered as you type, and a parameter form that checks each value
+  against its declaration while typing, offers declared options as a list,
+  prefills defaults and previews the rendered configuration. It asks before
+  writing files. Keys: Tab/arrows move, Ctrl-S generates, Esc cancels.
+
+  Line prompts (--no-tui) check each answer right away and ask again when
+  it is invalid; options are chosen by number. If the session fails at the
+  end, the answers are saved to ~/.ngcli/answers/<config_name>.yaml for a
+  retry with --values.
+
 WORKFLOW OPTIONS:
 
   1. Interactive Mode (Recommended):
@@ -175,10 +219,13 @@ EXAMPLES:
   ngcli generate api --template prod --set domain=api.example.com --set ssl_cert=/path/to/cert --set ssl_key=/path/to/key --set root_path=/var/www/api
   
   # Custom output location
-  ngcli generate temp --template dev --set domain=temp.local --output /tmp/nginx-temp.conf
+  ngcli generate temp --template dev --set domain=temp.local --out-file /tmp/nginx-temp.conf
   
   # Preview without creating file
   ngcli generate preview --template staging --set domain=staging.example.com --dry-run
+  
+  # Parameters from a values file, --set takes precedence
+  ngcli generate shop --template prod --values shop.yaml --set upstream_port=8081
 
 TEMPLATE PARAMETER SYSTEM:
   Templates use comment-based metadata for parameter definitions:
@@ -206,7 +253,10 @@ DESCRIPTION:
 EXAMPLES:
   ngcli list              List all nginx configurations
   ngcli list --templates  List available templates
-  ngcli list -t           List available templates (short form)`)
+  ngcli list -t           List available templates (short form)
+  ngcli list -o wide      Also show template, server names and path
+  ngcli list -o json      Machine-readable list (apiVersion ngcli/v1)
+  ngcli list -o name      Only the configuration names`)
 }
 
 func showShowHelp() {
@@ -226,7 +276,7 @@ EXAMPLES:
 }
 
 func showEnableHelp() {
-	fmt.Println(`Enable nginx configuration by creating symlink
+	fmt.Println(`Enable nginx configuration
 
 USAGE:
   ngcli enable <config_name> [flags]
@@ -235,9 +285,11 @@ FLAGS:
   --no-reload   Skip automatic nginx reload
 
 DESCRIPTION:
-  Enables nginx configuration by creating a symbolic link in sites-enabled 
-  directory (Debian/Ubuntu systems only). Automatically reloads nginx 
-  configuration to apply changes unless --no-reload flag is used.
+  Enables nginx configuration as the layout of the system does it: a
+  symbolic link in sites-enabled on Debian/Ubuntu, or moving a disabled
+  configuration back into conf.d on RHEL/CentOS/Alpine. Automatically
+  reloads nginx configuration to apply changes unless --no-reload flag
+  is used.
   
   This command makes the configuration active immediately.
 
@@ -247,13 +299,14 @@ EXAMPLES:
   ngcli enable blog --no-reload    Enable configuration without reloading
   
 NOTES:
-  - Only works on Debian/Ubuntu systems with sites-enabled directory
-  - Requires write permissions to /etc/nginx/sites-enabled
-  - Automatically creates backup if configuration already enabled`)
+  - The layout is detected, or set in the layout section of ~/.ngcli/config.yaml
+  - Requires write permissions to /etc/nginx/sites-enabled or conf.d
+  - Leftover disabled copies of a conf.d configuration are removed with
+    a backup`)
 }
 
 func showDisableHelp() {
-	fmt.Println(`Disable nginx configuration by removing symlink
+	fmt.Println(`Disable nginx configuration without deleting it
 
 USAGE:
   ngcli disable <config_name> [flags]
@@ -262,9 +315,11 @@ FLAGS:
   --no-reload   Skip automatic nginx reload
 
 DESCRIPTION:
-  Disables nginx configuration by removing the symbolic link from 
-  sites-enabled directory (Debian/Ubuntu systems only). Automatically 
-  reloads nginx configuration to apply changes unless --no-reload flag is used.
+  Disables nginx configuration as the layout of the system does it: the
+  symbolic link in sites-enabled is removed on Debian/Ubuntu, and on
+  conf.d systems x.conf is renamed to x.conf.disabled, or moved to
+  /etc/nginx/conf.available with 'disable: move'. Automatically reloads
+  nginx configuration to apply changes unless --no-reload flag is used.
   
   This command makes the configuration inactive immediately.
 
@@ -274,7 +329,7 @@ EXAMPLES:
   ngcli disable blog --no-reload    Disable configuration without reloading
   
 NOTES:
-  - Only works on Debian/Ubuntu systems with sites-enabled directory
+  - Disabled configurations keep their status in 'ngcli list'
   - Does not delete the original configuration file
   - Use 'ngcli delete' to remove the configuration file entirely`)
 }
@@ -290,7 +345,8 @@ FLAGS:
 
 DESCRIPTION:
   Deletes nginx configuration file and removes any associated symlink.
-  Prompts for confirmation unless --force flag is used.
+  Files generated together with it by a multi-file template are deleted
+  as well. Prompts for confirmation unless --force flag is used.
 
 EXAMPLES:
   ngcli delete mysite         Delete configuration with confirmation
@@ -317,6 +373,100 @@ EXAMPLES:
   ngcli reload --dry-run    Preview reload command without executing`)
 }
 
+func showInfoHelp() {
+	fmt.Println(`Show the nginx installation and layout ngcli uses
+
+USAGE:
+  ngcli info [flags]
+
+FLAGS:
+  --refresh   Discover the nginx installation again instead of using the cache
+
+DESCRIPTION:
+  Runs 'nginx -V' for the binary, version, --prefix and --conf-path, then
+  reads the include directives of the main configuration file (with
+  'nginx -T' where the files cannot be read). The wildcard includes of
+  the http block tell where site configurations are loaded from:
+  sites-enabled next to sites-available is a debian layout, anything else
+  a conf.d layout. Custom builds (/usr/local/nginx/conf) and OpenResty
+  (/usr/local/openresty/nginx/conf) are found the same way.
+
+  The result is cached in ~/.ngcli/cache/nginx.yaml until nginx or one of
+  the files read changes. A layout set in ~/.ngcli/config.yaml takes
+  precedence; without nginx the layout is detected from /etc/nginx.
+
+EXAMPLES:
+  ngcli info                Show nginx, its includes and the layout
+  ngcli info --refresh      Discover again, e.g. after moving nginx.conf
+  ngcli info -o json        Print the result as JSON`)
+}
+
+func showDoctorHelp() {
+	fmt.Println(`Check that this host is ready for ngcli
+
+USAGE:
+  ngcli doctor [flags]
+
+DESCRIPTION:
+  Runs a series of checks and reports each as PASS, WARN or FAIL, with a
+  hint on how to fix what is wrong:
+
+    nginx binary       nginx is on PATH and 'nginx -V' runs
+    nginx process      a single master is running, workers not as root
+    layout             a layout is configured or detected
+    config directory   the directory configurations are written to exists
+                       and is writable
+    include directory  the same for the directory nginx includes
+    includes           the http block includes the directory ngcli writes to
+    dangling symlinks  no enabled configuration points to a missing file
+    backup files       nginx does not load backup files as configurations
+    templates          every installed template parses
+
+  Exits with status 1 (NGCLI-E060) if any check fails; warnings do not
+  change the exit status. Details are shown for failed checks, or for all
+  checks with --verbose.
+
+EXAMPLES:
+  ngcli doctor              Check this host
+  ngcli doctor -v           Show the details of every check
+  ngcli doctor -o json      Print the checks as JSON`)
+}
+
+func showRepairHelp() {
+	fmt.Println(`Repair configurations enabled wrongly
+
+USAGE:
+  ngcli repair [config_name...] [flags]
+
+FLAGS:
+  --dry-run     Show the repairs without making them
+  --no-reload   Skip the nginx test and reload
+
+DESCRIPTION:
+  In a symlink layout (debian, or custom with a separate config_dir) a
+  configuration is enabled by a symbolic link in sites-enabled to its
+  file in sites-available. 'ngcli list' reports the entries of
+  sites-enabled that are not such a link, and repair fixes them:
+
+    dangling        a link to a missing file: linked to the configuration
+                    again, or removed if there is none
+    foreign-target  a link to another file: linked to the configuration;
+                    left alone if there is none
+    unmanaged-copy  a copy instead of a link: the copy is what nginx
+                    loads, so it replaces the configuration, which is
+                    backed up if it differs, and is linked
+
+  Links are also rewritten to be absolute (the default) or relative, as
+  set with 'symlinks' in the layout section of ~/.ngcli/config.yaml.
+  Without names every configuration is repaired; nginx is then tested
+  and reloaded.
+
+EXAMPLES:
+  ngcli repair --dry-run     Show what would be repaired
+  ngcli repair               Repair every configuration and reload nginx
+  ngcli repair mysite        Repair one configuration`)
+}
+
 func showTemplateHelp() {
 	fmt.Println(`Manage nginx configuration templates
 
@@ -329,7 +479,17 @@ AVAILABLE SUBCOMMANDS:
   show        Show template content and metadata
   edit        Edit template in text editor
   delete      Delete a custom template
-  validate    Validate template syntax
+  validate    Validate template syntax and lint for nginx mistakes (alias: lint)
+  test        Run template test suites (<name>.tests.yaml)
+  install     Install a template package (directory, .tar.gz or git)
+  upgrade     Upgrade installed template packages
+  uninstall   Remove an installed template package
+  search      Search template packages in configured sources
+  builtin     Compare/merge local copies with the built-in templates
+  import      Create a template from an existing nginx config
+  docs        Generate Markdown/HTML/JSON documentation for templates
+  schema      Export template parameters as JSON Schema (draft 2020-12)
+  migrate     Convert metadata between comments and YAML front-matter
 
 EXAMPLES:
   # Template creation
@@ -347,8 +507,28 @@ EXAMPLES:
   
   # Template management
   ngcli template validate api-server            Check template syntax
+  ngcli template lint api-server --strict       Fail on lint warnings such as unquoted substitutions
+  ngcli template migrate api-server --to yaml   Convert metadata to YAML front-matter
+  ngcli template test api-server --junit r.xml  Run template tests with a JUnit report
   ngcli template delete api-server              Delete custom template
 
+  # Template packages
+  ngcli template install ./acme-templates       Install package, templates become acme/<name>
+  ngcli template upgrade                        Upgrade all installed packages
+  ngcli template search proxy                   Search configured package sources
+
+  # Built-in templates
+  ngcli template builtin diff                   Show upstream changes to prod/staging/dev
+  ngcli template builtin update                 Three-way merge upstream changes into local copies
+
+  # Importing existing configs
+  ngcli template import shop.conf --name shop   Parameterize a hand-written config
+
+  # Template catalog
+  ngcli template docs --out ./catalog           Markdown page per template plus index.md
+  ngcli template docs --format html --out ./public
+  ngcli template schema prod --out prod.schema.json
+
 TEMPLATE METADATA FORMAT:
   Templates use comment-based metadata for parameter definitions:
   
@@ -360,6 +540,25 @@ TEMPLATE METADATA FORMAT:
   # @param domain string required "Primary domain"
   # @param port integer optional "Server port" default=3000
   # @param ssl_cert file_path required "SSL certificate path"
+  # @param ssl_key file_path optional "SSL key path"
+  # @require ssl_key if ssl_cert
+
+  Attributes: default=, options=[...], min=, max=, minlen=, maxlen=,
+  pattern="...", deprecated="...", sensitive
+  Rules: @require <params> if <param>, @conflicts <params>
+  Conditions: "# @when enable_ssl" applies to the @param lines below it
+  Computed defaults: default="{{ .domain }}_backend" is evaluated from other
+  parameters (and config_name) when the header is wrapped in {{/* ... */}}
+  Outputs: "# @output upstream \"conf.d/{{.config_name}}.conf\" mode=0644"
+  writes the {{define "upstream"}} block as an additional file
+  Secrets: type "secret" takes env:VAR, file:/path or store:key instead of
+  a literal value, and is masked in previews
+  Injection: values containing ';', '{', '}', line breaks or unbalanced
+  quotes are rejected unless the parameter has type "raw"
+
+  Alternatively, declare metadata as YAML front-matter between
+  "{{/* ---" and "--- */}}" (or "# ---" lines), which also supports nested
+  types such as arrays of objects. Convert with 'ngcli template migrate'.
   
 EDITOR SELECTION:
   Editor priority: --editor flag → $VISUAL → $EDITOR → system default
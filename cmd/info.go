@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/vourteen14/ngcli/layout"
+	"github.com/vourteen14/ngcli/output"
+	"github.com/vourteen14/ngcli/system"
+)
+
+var infoRefresh bool
+
+var infoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Show the nginx installation and layout ngcli uses",
+	Long: `Show the nginx installation found with 'nginx -V', the include
+directives of its main configuration file, and the layout ngcli uses
+to write, enable and disable configurations.
+
+The result is cached in ~/.ngcli/cache/nginx.yaml until nginx or one of
+the files read changes. Use --refresh to discover it again.`,
+	Args: cobra.NoArgs,
+	RunE: runInfo,
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+
+	infoCmd.Flags().BoolVar(&infoRefresh, "refresh", false, "discover the nginx installation again instead of using the cache")
+}
+
+// layoutSources describes where the layout came from.
+var layoutSources = map[string]string{
+	layout.SourceConfig:   "set in ~/.ngcli/config.yaml",
+	layout.SourceNginx:    "from the includes of the nginx configuration",
+	layout.SourceDetected: "detected from the directories in /etc/nginx",
+	layout.SourceNone:     "not detected; enable and disable are unavailable",
+}
+
+func runInfo(cmd *cobra.Command, args []string) error {
+	var nginx *output.Nginx
+	discovery, err := layout.Discover(infoRefresh)
+	if err != nil && !errors.Is(err, system.ErrNginxNotFound) {
+		return err
+	}
+	if discovery != nil {
+		nginx = &output.Nginx{
+			Binary:       discovery.Binary,
+			Version:      discovery.Version,
+			Prefix:       discovery.Prefix,
+			ConfPath:     discovery.ConfPath,
+			Source:       discovery.Source,
+			Problems:     discovery.Problems,
+			DiscoveredAt: discovery.DiscoveredAt,
+		}
+		for _, include := range discovery.Includes {
+			nginx.Includes = append(nginx.Includes, output.NginxInclude(include))
+		}
+	}
+
+	opts, err := layoutOptions()
+	if err != nil {
+		return err
+	}
+	resolved, source := layout.Resolve(opts)
+	info := output.Layout{Name: resolved.Name, Source: source}
+	if lay, err := layout.New(opts); err != nil {
+		info.Error = err.Error()
+	} else {
+		info.Name = lay.Name()
+		info.ConfigDir = lay.ConfigDir()
+		info.IncludeDir = lay.IncludeDir()
+	}
+
+	result := output.NewInfo(nginx, info, templateDir, layout.CachePath())
+	if printed, err := printResult(result); printed {
+		return err
+	}
+
+	if nginx == nil {
+		fmt.Println("nginx:        not found in PATH")
+	} else {
+		fmt.Printf("nginx:        %s (%s)\n", nginx.Version, nginx.Binary)
+		fmt.Printf("Prefix:       %s\n", nginx.Prefix)
+		fmt.Printf("Main config:  %s (read from %s)\n", nginx.ConfPath, nginx.Source)
+		if len(nginx.Includes) > 0 {
+			fmt.Println("Includes:")
+			for _, include := range nginx.Includes {
+				fmt.Printf("  %-6s %-45s %s:%d\n", include.Context, include.Pattern, filepath.Base(include.File), include.Line)
+			}
+		}
+		for _, problem := range nginx.Problems {
+			fmt.Printf("Warning: %s\n", problem)
+		}
+	}
+
+	fmt.Printf("Layout:       %s (%s)\n", info.Name, layoutSources[source])
+	if info.Error != "" {
+		fmt.Printf("Error:        %s\n", info.Error)
+	} else {
+		fmt.Printf("Config dir:   %s\n", info.ConfigDir)
+		if info.IncludeDir != "" {
+			fmt.Printf("Include dir:  %s\n", info.IncludeDir)
+		}
+	}
+	fmt.Printf("Templates:    %s\n", templateDir)
+	if nginx != nil {
+		fmt.Printf("Cache:        %s (discovered %s)\n", layout.CachePath(), nginx.DiscoveredAt.Local().Format("2006-01-02 15:04:05"))
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"github.com/vourteen14/ngcli/config"
+	"github.com/vourteen14/ngcli/layout"
+)
+
+// siteLayout returns the layout of the nginx configurations, as set in
+// ~/.ngcli/config.yaml or found by layout.Resolve.
+func siteLayout() (layout.Layout, error) {
+	opts, err := layoutOptions()
+	if err != nil {
+		return nil, err
+	}
+	return layout.New(opts)
+}
+
+// layoutOptions returns the layout section of ~/.ngcli/config.yaml, with
+// --output-dir as the configuration directory.
+func layoutOptions() (layout.Options, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return layout.Options{}, err
+	}
+	opts := cfg.Layout
+	if outputDir != "" {
+		opts.ConfigDir = outputDir
+	}
+	return opts, nil
+}
+
+// layoutDirs returns the directories ngcli writes to in lay.
+func layoutDirs(lay layout.Layout) []string {
+	dirs := []string{lay.ConfigDir()}
+	if include := lay.IncludeDir(); include != "" && include != lay.ConfigDir() {
+		dirs = append(dirs, include)
+	}
+	return dirs
+}
@@ -2,12 +2,14 @@ package cmd
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/vourteen14/ngcli/filesystem"
-	"github.com/vourteen14/ngcli/utils"
+	"github.com/vourteen14/ngcli/layout"
+	"github.com/vourteen14/ngcli/nginxconf"
+	"github.com/vourteen14/ngcli/output"
+	"github.com/vourteen14/ngcli/state"
 )
 
 var listTemplates bool
@@ -18,7 +20,8 @@ var listCmd = &cobra.Command{
 	Long: `List nginx configuration files in the output directory or 
 available templates in the template directory.
 
-Use --templates flag to list available templates instead of configurations.`,
+Use --templates flag to list available templates instead of configurations,
+as 'ngcli template list' does.`,
 	RunE: runList,
 }
 
@@ -30,112 +33,143 @@ func init() {
 
 func runList(cmd *cobra.Command, args []string) error {
 	if listTemplates {
-		return listAvailableTemplates()
+		return runTemplateList(cmd, args)
 	}
 	
 	return listConfigurations()
 }
 
-func listAvailableTemplates() error {
-	if _, err := os.Stat(templateDir); os.IsNotExist(err) {
-		fmt.Printf("Template directory does not exist: %s\n", templateDir)
-		fmt.Println("Run 'ngcli init' to initialize templates")
-		return nil
+func listConfigurations() error {
+	lay, err := siteLayout()
+	if err != nil {
+		return fmt.Errorf("failed to detect nginx layout: %w", err)
 	}
+	configDir := lay.ConfigDir()
 	
-	entries, err := os.ReadDir(templateDir)
+	sites, err := lay.List()
 	if err != nil {
-		return fmt.Errorf("failed to read template directory: %w", err)
+		return fmt.Errorf("failed to list configurations: %w", err)
 	}
 	
-	var templates []string
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-		
-		name := entry.Name()
-		if filepath.Ext(name) == ".tpl" {
-			templateName := name
-			if len(name) > 9 && name[len(name)-9:] == ".conf.tpl" {
-				templateName = name[:len(name)-9]
-			}
-			templates = append(templates, templateName)
+	if len(sites) == 0 {
+		if printed, err := printResult(output.NewConfigList(configDir, nil)); printed {
+			return err
 		}
+		fmt.Printf("No configuration files found in %s\n", configDir)
+		return nil
 	}
 	
-	if len(templates) == 0 {
-		fmt.Printf("No templates found in %s\n", templateDir)
-		return nil
+	var items []output.Config
+	for _, site := range sites {
+		items = append(items, configInfo(site))
 	}
 	
-	fmt.Printf("Available templates (%s):\n", templateDir)
-	fmt.Printf("%-20s %s\n", "NAME", "FILE")
-	fmt.Printf("%-20s %s\n", "----", "----")
+	if printed, err := printResult(output.NewConfigList(configDir, items)); printed {
+		return err
+	}
 	
-	for _, tmpl := range templates {
-		filename := tmpl + ".conf.tpl"
-		fmt.Printf("%-20s %s\n", tmpl, filename)
+	fmt.Printf("Nginx configurations (%s):\n", configDir)
+	if outputFormat == output.Wide {
+		fmt.Printf("%-30s %-15s %-15s %-40s %s\n", "NAME", "STATUS", "TEMPLATE", "SERVER NAMES", "PATH")
+		fmt.Printf("%-30s %-15s %-15s %-40s %s\n", "----", "------", "--------", "------------", "----")
+		for _, item := range items {
+			tmpl := item.Template
+			if tmpl == "" {
+				tmpl = "-"
+			}
+			serverNames := strings.Join(item.ServerNames, " ")
+			if serverNames == "" {
+				serverNames = "-"
+			}
+			fmt.Printf("%-30s %-15s %-15s %-40s %s\n", item.Name, item.Status, tmpl, serverNames, item.Path)
+		}
+	} else {
+		fmt.Printf("%-30s %s\n", "NAME", "STATUS")
+		fmt.Printf("%-30s %s\n", "----", "------")
+		for _, item := range items {
+			fmt.Printf("%-30s %s\n", item.Name, item.Status)
+		}
 	}
 	
-	fmt.Printf("\nTotal: %d templates\n", len(templates))
+	fmt.Printf("\nTotal: %d configurations\n", len(sites))
+	
+	broken := 0
+	for _, site := range sites {
+		if site.Status.Broken() {
+			broken++
+		}
+	}
+	if broken > 0 {
+		fmt.Printf("%d enabled wrongly (dangling, foreign-target or unmanaged-copy); run 'ngcli repair' to fix them\n", broken)
+	}
 	
 	return nil
 }
 
-func listConfigurations() error {
-	var configDir string
-	if outputDir != "" {
-		configDir = outputDir
-	} else {
-		var err error
-		configDir, err = utils.DetectNginxConfigPath()
-		if err != nil {
-			return fmt.Errorf("failed to detect nginx config directory: %w", err)
-		}
+// configInfo describes the configuration, with the template and files
+// recorded by 'ngcli generate' if it wrote it.
+func configInfo(site layout.Site) output.Config {
+	file := site.Name
+	// Strip .conf extension for display consistency
+	name := strings.TrimSuffix(file, ".conf")
+	info := output.Config{
+		Name:        name,
+		Path:        site.Path,
+		Status:      string(site.Status),
+		Link:        site.Link,
+		Target:      site.Target,
+		ServerNames: []string{},
 	}
-	
-	configs, err := filesystem.ListConfigs(configDir)
-	if err != nil {
-		return fmt.Errorf("failed to list configurations: %w", err)
+
+	if site.Status != layout.Unknown {
+		enabled := site.Status == layout.Enabled
+		info.Enabled = &enabled
 	}
-	
-	if len(configs) == 0 {
-		fmt.Printf("No configuration files found in %s\n", configDir)
-		return nil
+
+	if content, err := filesystem.ReadFile(info.Path); err == nil {
+		info.ServerNames = serverNames(content)
 	}
-	
-	var tableData [][]string
-	enabledDir, hasEnabled := utils.DetectNginxEnabledPath()
-	
-	for _, config := range configs {
-		// Strip .conf extension for display consistency
-		displayName := config
-		if len(config) > 5 && config[len(config)-5:] == ".conf" {
-			displayName = config[:len(config)-5]
-		}
 
-		status := "disabled"
-		if hasEnabled {
-			enabledPath := filepath.Join(enabledDir, config)
-			if utils.FileExists(enabledPath) {
-				status = "enabled"
+	manifest, _ := state.Load(name)
+	if manifest == nil && name != file {
+		manifest, _ = state.Load(file)
+	}
+	if manifest != nil {
+		info.Managed = true
+		info.Template = manifest.Template
+		info.TemplateVersion = manifest.TemplateVersion
+		generatedAt := manifest.GeneratedAt
+		info.GeneratedAt = &generatedAt
+		for _, f := range manifest.Files {
+			if f.Output != "" {
+				info.Files = append(info.Files, f.Path)
 			}
-		} else {
-			status = "n/a"
 		}
-		tableData = append(tableData, []string{displayName, status})
 	}
-	
-	fmt.Printf("Nginx configurations (%s):\n", configDir)
-	fmt.Printf("%-30s %s\n", "NAME", "STATUS")
-	fmt.Printf("%-30s %s\n", "----", "------")
-	
-	for _, row := range tableData {
-		fmt.Printf("%-30s %s\n", row[0], row[1])
+
+	return info
+}
+
+// serverNames returns the server_name values of a configuration, in order
+// and without duplicates.
+func serverNames(content string) []string {
+	names := []string{}
+	directives, err := nginxconf.Parse(content)
+	if err != nil {
+		return names
 	}
-	
-	fmt.Printf("\nTotal: %d configurations\n", len(configs))
-	
-	return nil
-}
\ No newline at end of file
+
+	seen := make(map[string]bool)
+	nginxconf.Walk(directives, func(d *nginxconf.Directive, parents []string) {
+		if d.Name != "server_name" {
+			return
+		}
+		for _, arg := range d.Args {
+			if arg.Value != "" && !seen[arg.Value] {
+				seen[arg.Value] = true
+				names = append(names, arg.Value)
+			}
+		}
+	})
+	return names
+}
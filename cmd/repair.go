@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/vourteen14/ngcli/errcode"
+	"github.com/vourteen14/ngcli/layout"
+	"github.com/vourteen14/ngcli/system"
+)
+
+var (
+	repairDryRun   bool
+	repairNoReload bool
+)
+
+var repairCmd = &cobra.Command{
+	Use:   "repair [config_name...]",
+	Short: "Repair configurations enabled wrongly",
+	Long: `Repair the entries of sites-enabled (the include directory of a
+symlink layout) that 'ngcli list' reports as enabled wrongly:
+
+  dangling        a link to a missing file: linked to the configuration
+                  again, or removed if there is none
+  foreign-target  a link to another file: linked to the configuration;
+                  left alone if there is none
+  unmanaged-copy  a copy instead of a link: the copy is what nginx
+                  loads, so it replaces the configuration, which is
+                  backed up if it differs, and is linked
+
+Links are also rewritten to be absolute or relative, as set with
+'symlinks' in the layout section of ~/.ngcli/config.yaml.
+
+Without names every configuration is repaired. nginx is tested and
+reloaded afterwards; use --no-reload to skip it.`,
+	ValidArgsFunction: completeConfigs(brokenConfigs),
+	RunE:              runRepair,
+}
+
+func init() {
+	rootCmd.AddCommand(repairCmd)
+
+	repairCmd.Flags().BoolVar(&repairDryRun, "dry-run", false, "show the repairs without making them")
+	repairCmd.Flags().BoolVar(&repairNoReload, "no-reload", false, "skip the nginx test and reload")
+}
+
+func runRepair(cmd *cobra.Command, args []string) error {
+	lay, err := siteLayout()
+	if err != nil {
+		return fmt.Errorf("failed to detect nginx layout: %w", err)
+	}
+	repairer, ok := lay.(layout.Repairer)
+	if !ok {
+		fmt.Printf("Nothing to repair: the %s layout does not enable configurations with symbolic links\n", lay.Name())
+		return nil
+	}
+
+	var sites []layout.Site
+	if len(args) == 0 {
+		if sites, err = lay.List(); err != nil {
+			return fmt.Errorf("failed to list configurations: %w", err)
+		}
+	}
+	for _, name := range args {
+		site, err := lay.Find(name)
+		if err != nil {
+			return err
+		}
+		sites = append(sites, site)
+	}
+
+	repaired, failed := 0, 0
+	for _, site := range sites {
+		changes, err := repairer.Repair(site, repairDryRun)
+		if err != nil {
+			fmt.Printf("Cannot repair %s (%s): %v\n", site.Name, site.Status, err)
+			failed++
+			continue
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		repaired++
+		verb := "Repaired"
+		if repairDryRun {
+			verb = "Would repair"
+		}
+		fmt.Printf("%s %s (%s)\n", verb, site.Name, site.Status)
+		for _, change := range changes {
+			fmt.Printf("  %s\n", change)
+		}
+	}
+
+	if repaired == 0 && failed == 0 {
+		fmt.Println("Nothing to repair")
+		return nil
+	}
+	if repairDryRun {
+		fmt.Println("\nDry run: nothing was changed")
+	} else if repaired > 0 && !repairNoReload {
+		if err := system.NginxTest(); err != nil {
+			if errcode.CodeOf(err) == errcode.NginxNotInstalled {
+				fmt.Println("Repaired; nginx is not installed, so nginx -t was skipped and nginx was not reloaded")
+				return err
+			}
+			fmt.Println("Repaired, but nginx -t fails; nginx was not reloaded")
+			return err
+		}
+		if err := system.NginxReload(); err != nil {
+			fmt.Printf("Warning: failed to reload nginx: %v\n", err)
+			fmt.Println("Run 'ngcli reload' manually to apply changes")
+		} else {
+			fmt.Println("Nginx configuration reloaded successfully")
+		}
+	}
+
+	if failed > 0 {
+		return errcode.Errorf(errcode.General, "%d of %d configurations could not be repaired", failed, repaired+failed)
+	}
+	return nil
+}
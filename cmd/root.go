@@ -4,14 +4,24 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/vourteen14/ngcli/errcode"
+	"github.com/vourteen14/ngcli/output"
+	"github.com/vourteen14/ngcli/prompt"
 )
 
 var (
 	templateDir string
 	outputDir   string
 	verbose     bool
+
+	outputFormat string
+	errorFormat  string
+
+	assumeYes bool
+	noInput   bool
 )
 
 var rootCmd = &cobra.Command{
@@ -23,10 +33,61 @@ Nginx configuration files using templates.
 It supports multiple environments and provides commands to enable, 
 disable, and reload configurations.`,
 	Version: "1.0.0",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if errorFormat != "text" && errorFormat != "json" {
+			return errcode.Errorf(errcode.Usage, "unsupported error format: %s (use text or json)", errorFormat)
+		}
+		prompt.Configure(assumeYes, noInput)
+		return errcode.Wrap(errcode.Usage, output.Validate(outputFormat))
+	},
+	// Errors are reported by Execute, with their code
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+// Execute runs the command line and returns the exit status: 0 on success,
+// otherwise the exit status of the error code (see errcode).
+func Execute() int {
+	usageErrors(rootCmd)
+
+	cmd, err := rootCmd.ExecuteC()
+	if err == nil {
+		return 0
+	}
+
+	// Cobra reports unknown commands and missing required flags as plain
+	// errors
+	if strings.HasPrefix(err.Error(), "unknown command") || strings.HasPrefix(err.Error(), "required flag(s)") {
+		err = errcode.Wrap(errcode.Usage, err)
+	}
+
+	report := output.NewErrorReport(err)
+	if errorFormat == "json" {
+		output.Write(os.Stderr, output.JSON, report)
+		return report.ExitCode
+	}
+
+	fmt.Fprintf(os.Stderr, "Error: %v (%s)\n", err, report.Code)
+	if report.Code == errcode.Usage {
+		fmt.Fprintf(os.Stderr, "Run '%s --help' for usage.\n", cmd.CommandPath())
+	}
+	return report.ExitCode
 }
 
-func Execute() error {
-	return rootCmd.Execute()
+// usageErrors gives flag and argument errors of cmd and its subcommands
+// the Usage code.
+func usageErrors(cmd *cobra.Command) {
+	cmd.SetFlagErrorFunc(func(c *cobra.Command, err error) error {
+		return errcode.Wrap(errcode.Usage, err)
+	})
+	if validate := cmd.Args; validate != nil {
+		cmd.Args = func(c *cobra.Command, args []string) error {
+			return errcode.Wrap(errcode.Usage, validate(c, args))
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		usageErrors(sub)
+	}
 }
 
 func init() {
@@ -35,6 +96,16 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&templateDir, "template-dir", getDefaultTemplateDir(), "directory containing templates")
 	rootCmd.PersistentFlags().StringVar(&outputDir, "output-dir", "", "override output directory")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+	// 'ngcli generate' has a deprecated --output for --out-file
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", output.Table, "output format: table, wide, json, yaml or name")
+	rootCmd.PersistentFlags().StringVar(&errorFormat, "error-format", "text", "error format: text, or json on stderr")
+	rootCmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, "answer yes to confirmations (overwrite, delete)")
+	rootCmd.PersistentFlags().BoolVar(&noInput, "no-input", false, "never prompt; fail when input is needed (also "+prompt.EnvNonInteractive+"=1)")
+
+	rootCmd.RegisterFlagCompletionFunc("template-dir", completeDirs)
+	rootCmd.RegisterFlagCompletionFunc("output-dir", completeDirs)
+	rootCmd.RegisterFlagCompletionFunc("output", completeWords(output.Formats...))
+	rootCmd.RegisterFlagCompletionFunc("error-format", completeWords("text", "json"))
 }
 
 func initConfig() {
@@ -46,6 +117,22 @@ func initConfig() {
 	}
 }
 
+// jsonErrors reports whether errors are reported as JSON on stderr.
+// Commands then leave the details the report carries, such as the problems
+// of each parameter, off stdout.
+func jsonErrors() bool {
+	return errorFormat == "json"
+}
+
+// printResult prints result if a JSON, YAML or name output was requested
+// and reports whether it did; otherwise the command prints its table.
+func printResult(result output.Result) (bool, error) {
+	if !output.Structured(outputFormat) {
+		return false, nil
+	}
+	return true, output.Write(os.Stdout, outputFormat, result)
+}
+
 func getDefaultTemplateDir() string {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -2,10 +2,11 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/vourteen14/ngcli/filesystem"
-	"github.com/vourteen14/ngcli/utils"
+	"github.com/vourteen14/ngcli/output"
 )
 
 var showCmd = &cobra.Command{
@@ -14,8 +15,9 @@ var showCmd = &cobra.Command{
 	Long: `Display the contents of a nginx configuration file.
 
 The config name should be without the .conf extension.`,
-	Args: cobra.ExactArgs(1),
-	RunE: runShow,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeConfigs(allConfigs),
+	RunE:              runShow,
 }
 
 func init() {
@@ -25,28 +27,40 @@ func init() {
 func runShow(cmd *cobra.Command, args []string) error {
 	configName := args[0]
 
-	var configDir string
-	if outputDir != "" {
-		configDir = outputDir
-	} else {
-		var err error
-		configDir, err = utils.DetectNginxConfigPath()
-		if err != nil {
-			return fmt.Errorf("failed to detect nginx config directory: %w", err)
-		}
+	lay, err := siteLayout()
+	if err != nil {
+		return fmt.Errorf("failed to detect nginx layout: %w", err)
 	}
 
-	configPath, err := utils.ResolveConfigPath(configDir, configName)
+	site, err := lay.Find(configName)
 	if err != nil {
 		return err
 	}
+	configPath := site.Path
 	
 	content, err := filesystem.ReadFile(configPath)
 	if err != nil {
 		return fmt.Errorf("failed to read configuration: %w", err)
 	}
 	
+	info := configInfo(site)
+	if printed, err := printResult(output.NewConfigDetail(info, content)); printed {
+		return err
+	}
+	
 	fmt.Printf("Configuration: %s\n", configPath)
+	if outputFormat == output.Wide {
+		fmt.Printf("Status: %s\n", info.Status)
+		if info.Managed {
+			fmt.Printf("Template: %s\n", info.Template)
+		}
+		if len(info.ServerNames) > 0 {
+			fmt.Printf("Server names: %s\n", strings.Join(info.ServerNames, " "))
+		}
+		for _, file := range info.Files {
+			fmt.Printf("Generated with: %s\n", file)
+		}
+	}
 	fmt.Println("---")
 	fmt.Print(content)
 	
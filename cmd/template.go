@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -8,7 +9,11 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/vourteen14/ngcli/errcode"
 	"github.com/vourteen14/ngcli/filesystem"
+	"github.com/vourteen14/ngcli/output"
+	"github.com/vourteen14/ngcli/prompt"
+	"github.com/vourteen14/ngcli/system"
 	"github.com/vourteen14/ngcli/template"
 	"github.com/vourteen14/ngcli/utils"
 )
@@ -17,6 +22,8 @@ var (
 	fromTemplate string
 	editorFlag   string
 	showParams   bool
+	lintStrict   bool
+	lintNginx    bool
 )
 
 var templateCmd = &cobra.Command{
@@ -51,8 +58,9 @@ var templateShowCmd = &cobra.Command{
 	Long: `Show template content and parameter information.
 
 Use --params flag to show only parameter information.`,
-	Args: cobra.ExactArgs(1),
-	RunE: runTemplateShow,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeTemplates,
+	RunE:              runTemplateShow,
 }
 
 var templateEditCmd = &cobra.Command{
@@ -65,25 +73,46 @@ Editor selection priority:
   2. $VISUAL environment variable
   3. $EDITOR environment variable
   4. System default (nano, vi, vim)`,
-	Args: cobra.ExactArgs(1),
-	RunE: runTemplateEdit,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeTemplates,
+	RunE:              runTemplateEdit,
 }
 
 var templateDeleteCmd = &cobra.Command{
 	Use:   "delete <name>",
 	Short: "Delete a custom template",
 	Long: `Delete a custom template. Built-in templates (prod, staging, dev) 
-cannot be deleted.`,
-	Args: cobra.ExactArgs(1),
-	RunE: runTemplateDelete,
+cannot be deleted; use 'ngcli template builtin update --force' to reset them.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeTemplates,
+	RunE:              runTemplateDelete,
 }
 
 var templateValidateCmd = &cobra.Command{
-	Use:   "validate <name>",
-	Short: "Validate template syntax",
-	Long:  `Validate template syntax and metadata format.`,
-	Args:  cobra.ExactArgs(1),
-	RunE:  runTemplateValidate,
+	Use:     "validate <name>",
+	Aliases: []string{"lint"},
+	Short:   "Validate and lint a template",
+	Long: `Validate template syntax and metadata format, and lint the template.
+
+Every field referenced by the template is compared with the declared
+@param list. Referenced but undeclared parameters fail validation;
+declared but unused parameters are reported as warnings, as are
+metadata-looking lines (such as a malformed "#@param") that were ignored.
+
+The linter warns about substitutions that could inject directives
+(server_name {{.domain}}; with an unconstrained string), parameters used in
+regex locations without regexQuote, parameters named like template
+keywords, and headers without Template or Version. It renders the template
+with sample values and reports configuration errors in the output, such as
+missing arguments or duplicate directives.
+
+Examples:
+  ngcli template validate api
+  ngcli template lint api --strict     # warnings fail too
+  ngcli template lint api --nginx      # also run nginx -t on the samples`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeTemplates,
+	RunE:              runTemplateValidate,
 }
 
 func init() {
@@ -99,6 +128,11 @@ func init() {
 	templateCreateCmd.Flags().StringVar(&fromTemplate, "from", "", "create template from existing template")
 	templateEditCmd.Flags().StringVar(&editorFlag, "editor", "", "text editor to use")
 	templateShowCmd.Flags().BoolVar(&showParams, "params", false, "show only parameter information")
+	templateValidateCmd.Flags().BoolVar(&lintStrict, "strict", false, "fail on lint warnings too")
+	templateValidateCmd.Flags().BoolVar(&lintNginx, "nginx", false, "also validate sample renders with an isolated nginx -t")
+
+	templateCreateCmd.ValidArgsFunction = cobra.NoFileCompletions
+	templateCreateCmd.RegisterFlagCompletionFunc("from", completeTemplateFlag)
 }
 
 func runTemplateCreate(cmd *cobra.Command, args []string) error {
@@ -129,14 +163,13 @@ func runTemplateCreate(cmd *cobra.Command, args []string) error {
 	fmt.Printf("Created template: %s\n", templateName)
 	fmt.Printf("Template file: %s\n", templatePath)
 	
-	fmt.Print("Open template in editor now? (Y/n): ")
-	var response string
-	if _, err := fmt.Scanln(&response); err != nil {
-		// Treat scan error or empty input as "yes" (default)
-		response = ""
+	// An editor needs a terminal, so without one the question is skipped
+	openEditor := false
+	if prompt.Interactive() {
+		openEditor, _ = prompt.Confirm("Open template in editor now?", true, "")
 	}
-
-	if response == "" || response == "y" || response == "Y" || response == "yes" {
+	
+	if openEditor {
 		editor := detectEditor(editorFlag)
 		
 		if verbose {
@@ -165,10 +198,39 @@ func runTemplateCreate(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// templateNames lists the templates in templateDir, printing the installed
+// packages that were skipped to stderr.
+func templateNames() ([]string, error) {
+	templates, warnings, err := template.ListTemplates(templateDir)
+	printWarnings(warnings)
+	return templates, err
+}
+
+// printWarnings prints warnings to stderr, keeping structured output on
+// stdout intact.
+func printWarnings(warnings []string) {
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+}
+
 func runTemplateList(cmd *cobra.Command, args []string) error {
-	templates, err := template.ListTemplates(templateDir)
-	if err != nil {
-		return fmt.Errorf("failed to list templates: %w", err)
+	var templates []string
+	if utils.FileExists(templateDir) {
+		var err error
+		if templates, err = templateNames(); err != nil {
+			return fmt.Errorf("failed to list templates: %w", err)
+		}
+	}
+	
+	var items []output.Template
+	for _, tmplName := range templates {
+		info, _ := templateInfo(tmplName)
+		items = append(items, info)
+	}
+	
+	if printed, err := printResult(output.NewTemplateList(templateDir, items)); printed {
+		return err
 	}
 	
 	if len(templates) == 0 {
@@ -177,45 +239,85 @@ func runTemplateList(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 	
-	var tableData [][]string
+	wide := outputFormat == output.Wide
+	updates := 0
 	
-	for _, tmplName := range templates {
-		tmpl, err := template.LoadTemplate(tmplName, templateDir)
-		if err != nil {
-			tableData = append(tableData, []string{tmplName, "error", "failed to parse"})
-			continue
+	fmt.Printf("Available templates (%s):\n", templateDir)
+	if wide {
+		fmt.Printf("%-20s %-10s %-10s %-40s %s\n", "NAME", "TYPE", "VERSION", "FILE", "DESCRIPTION")
+		fmt.Printf("%-20s %-10s %-10s %-40s %s\n", "----", "----", "-------", "----", "-----------")
+	} else {
+		fmt.Printf("%-20s %-10s %s\n", "NAME", "TYPE", "DESCRIPTION")
+		fmt.Printf("%-20s %-10s %s\n", "----", "----", "-----------")
+	}
+	
+	for _, item := range items {
+		if item.UpdateAvailable {
+			updates++
 		}
 		
-		description := tmpl.Metadata.Description
+		templateType := item.Type
+		description := item.Description
+		if item.Error != "" {
+			templateType = "error"
+			description = "failed to parse"
+		}
 		if description == "" {
 			description = "no description"
 		}
 		
-		if len(description) > 50 {
-			description = description[:47] + "..."
+		if wide {
+			version := item.Version
+			if version == "" {
+				version = "-"
+			}
+			fmt.Printf("%-20s %-10s %-10s %-40s %s\n", item.Name, templateType, version, item.File, description)
+			continue
 		}
 		
-		templateType := "custom"
-		if tmplName == "prod" || tmplName == "staging" || tmplName == "dev" {
-			templateType = "built-in"
+		if len(description) > 50 {
+			description = description[:47] + "..."
 		}
-		
-		tableData = append(tableData, []string{tmplName, templateType, description})
+		fmt.Printf("%-20s %-10s %s\n", item.Name, templateType, description)
 	}
 	
-	fmt.Printf("Available templates (%s):\n", templateDir)
-	fmt.Printf("%-20s %-10s %s\n", "NAME", "TYPE", "DESCRIPTION")
-	fmt.Printf("%-20s %-10s %s\n", "----", "----", "-----------")
+	fmt.Printf("\nTotal: %d templates\n", len(templates))
 	
-	for _, row := range tableData {
-		fmt.Printf("%-20s %-10s %s\n", row[0], row[1], row[2])
+	if updates > 0 {
+		fmt.Printf("%d built-in template(s) have upstream changes, see 'ngcli template builtin diff'\n", updates)
 	}
 	
-	fmt.Printf("\nTotal: %d templates\n", len(templates))
-	
 	return nil
 }
 
+// templateInfo describes a template of the template directory. The
+// template is returned as well unless it fails to parse.
+func templateInfo(name string) (output.Template, *template.Template) {
+	info := output.Template{Name: name, Type: "custom"}
+	
+	if strings.Contains(name, "/") {
+		info.Type = "package"
+	} else if status, err := template.GetBuiltinStatus(name, templateDir); err == nil {
+		info.Type = "built-in"
+		if status.Modified {
+			info.Type = "modified"
+		}
+		info.UpdateAvailable = status.UpdateAvailable
+	}
+	
+	tmpl, err := template.LoadTemplate(name, templateDir)
+	if err != nil {
+		info.File = filepath.Join(templateDir, name+".conf.tpl")
+		info.Error = err.Error()
+		return info, nil
+	}
+	
+	info.File = tmpl.Path
+	info.Description = tmpl.Metadata.Description
+	info.Version = tmpl.Metadata.Version
+	return info, tmpl
+}
+
 func runTemplateShow(cmd *cobra.Command, args []string) error {
 	templateName := args[0]
 	
@@ -224,6 +326,17 @@ func runTemplateShow(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load template: %w", err)
 	}
 	
+	if output.Structured(outputFormat) {
+		info, _ := templateInfo(templateName)
+		content := tmpl.Content
+		if showParams {
+			content = ""
+		}
+		detail := output.NewTemplateDetail(info, tmpl.Metadata.Author, tmpl.Metadata.ParameterDocs(), tmpl.Metadata.RuleDocs(), content)
+		_, err := printResult(detail)
+		return err
+	}
+	
 	if showParams {
 		fmt.Printf("Template: %s\n", templateName)
 		if tmpl.Metadata.Description != "" {
@@ -259,7 +372,11 @@ func runTemplateEdit(cmd *cobra.Command, args []string) error {
 	templatePath := filepath.Join(templateDir, templateName+".conf.tpl")
 	
 	if !utils.FileExists(templatePath) {
-		return fmt.Errorf("template not found: %s", templateName)
+		return errcode.Errorf(errcode.TemplateNotFound, "template not found: %s", templateName)
+	}
+	
+	if !prompt.Interactive() {
+		return prompt.Required("the editor", fmt.Sprintf("edit %s directly", templatePath))
 	}
 	
 	editor := detectEditor(editorFlag)
@@ -290,28 +407,25 @@ func runTemplateEdit(cmd *cobra.Command, args []string) error {
 func runTemplateDelete(cmd *cobra.Command, args []string) error {
 	templateName := args[0]
 	
-	builtInTemplates := []string{"prod", "staging", "dev"}
-	for _, builtin := range builtInTemplates {
-		if templateName == builtin {
-			return fmt.Errorf("cannot delete built-in template: %s", templateName)
-		}
+	if template.IsBuiltin(templateName) {
+		return fmt.Errorf("cannot delete built-in template: %s", templateName)
+	}
+	
+	if pkg, _, found := strings.Cut(templateName, "/"); found {
+		return fmt.Errorf("template %s belongs to package %s (use 'ngcli template uninstall %s')", templateName, pkg, pkg)
 	}
 	
 	templatePath := filepath.Join(templateDir, templateName+".conf.tpl")
 	
 	if !utils.FileExists(templatePath) {
-		return fmt.Errorf("template not found: %s", templateName)
+		return errcode.Errorf(errcode.TemplateNotFound, "template not found: %s", templateName)
 	}
 	
-	fmt.Printf("Are you sure you want to delete template '%s'? (y/N): ", templateName)
-	var response string
-	if _, err := fmt.Scanln(&response); err != nil {
-		// Treat scan error or empty input as cancellation
-		fmt.Println("Deletion cancelled")
-		return nil
+	confirmed, err := prompt.Confirm(fmt.Sprintf("Are you sure you want to delete template '%s'?", templateName), false, "use --yes to delete it")
+	if err != nil {
+		return err
 	}
-
-	if response != "y" && response != "Y" && response != "yes" {
+	if !confirmed {
 		fmt.Println("Deletion cancelled")
 		return nil
 	}
@@ -335,6 +449,7 @@ func runTemplateValidate(cmd *cobra.Command, args []string) error {
 	
 	fmt.Printf("Template: %s\n", templateName)
 	fmt.Printf("Syntax: valid\n")
+	fmt.Printf("Metadata: %s\n", tmpl.Metadata.Format)
 	
 	if len(tmpl.Metadata.Parameters) > 0 {
 		fmt.Printf("Parameters: %d defined\n", len(tmpl.Metadata.Parameters))
@@ -353,6 +468,53 @@ func runTemplateValidate(cmd *cobra.Command, args []string) error {
 		fmt.Printf("Parameters: none defined\n")
 	}
 	
+	if len(tmpl.Metadata.Warnings) > 0 {
+		fmt.Println("\nWarning: metadata ignored:")
+		for _, warning := range tmpl.Metadata.Warnings {
+			fmt.Printf("  %s\n", warning)
+		}
+	}
+	
+	usage := tmpl.CheckParameterUsage()
+	
+	if len(usage.Unused) > 0 {
+		fmt.Println("\nWarning: parameters declared but never used:")
+		for _, param := range usage.Unused {
+			fmt.Printf("  line %d: %s\n", param.Line, param.Name)
+		}
+	}
+	
+	if len(usage.Undeclared) > 0 {
+		fmt.Println("\nError: parameters referenced but not declared:")
+		for _, ref := range usage.Undeclared {
+			fmt.Printf("  line %d: %s\n", ref.Line, ref.Name)
+		}
+		return errcode.Errorf(errcode.TemplateInvalid, "template %s references %d undeclared parameter(s)", templateName, len(usage.Undeclared))
+	}
+	
+	opts := template.LintOptions{}
+	if lintNginx {
+		opts.NginxCheck = system.NginxTestConfig
+		opts.NginxSkipped = func(err error) bool {
+			return errors.Is(err, system.ErrNginxNotFound)
+		}
+	}
+	
+	var lintErrors, lintWarnings []template.LintIssue
+	for _, issue := range tmpl.Lint(opts) {
+		if issue.Severity == template.LintError {
+			lintErrors = append(lintErrors, issue)
+		} else {
+			lintWarnings = append(lintWarnings, issue)
+		}
+	}
+	printLintIssues("Warning: lint", lintWarnings)
+	printLintIssues("Error: lint", lintErrors)
+	
+	if len(lintErrors) > 0 || (lintStrict && len(lintWarnings) > 0) {
+		return errcode.Errorf(errcode.TemplateLintFailed, "template %s has %d lint error(s) and %d warning(s)", templateName, len(lintErrors), len(lintWarnings))
+	}
+	
 	fmt.Println("Template validation successful")
 	
 	return nil
@@ -434,4 +596,18 @@ server {
         try_files $uri $uri/ =404;
     }
 }`, templateName, os.Getenv("USER"))
-}
\ No newline at end of file
+}
+
+func printLintIssues(title string, issues []template.LintIssue) {
+	if len(issues) == 0 {
+		return
+	}
+	fmt.Printf("\n%s:\n", title)
+	for _, issue := range issues {
+		if issue.Line > 0 {
+			fmt.Printf("  line %d: %s\n", issue.Line, issue.Message)
+		} else {
+			fmt.Printf("  %s\n", issue.Message)
+		}
+	}
+}
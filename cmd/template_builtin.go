@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vourteen14/ngcli/filesystem"
+	"github.com/vourteen14/ngcli/template"
+)
+
+var (
+	builtinForce  bool
+	builtinDryRun bool
+)
+
+var templateBuiltinCmd = &cobra.Command{
+	Use:   "builtin",
+	Short: "Compare and update copies of the built-in templates",
+	Long: `Compare and update the copies of the built-in templates (prod, staging,
+dev) in the template directory with the versions shipped in this binary.
+
+'ngcli init' records the version every copy was made from, so upstream
+improvements can be merged into locally modified copies.`,
+}
+
+var templateBuiltinDiffCmd = &cobra.Command{
+	Use:   "diff [name]",
+	Short: "Show upstream changes to built-in templates",
+	Long: `Show the status of each built-in template copy and a unified diff from
+the local copy to the upstream version shipped with ngcli.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeBuiltinTemplates,
+	RunE:              runTemplateBuiltinDiff,
+}
+
+var templateBuiltinUpdateCmd = &cobra.Command{
+	Use:   "update [name]",
+	Short: "Merge upstream changes into built-in template copies",
+	Long: `Merge upstream changes into the local copies of the built-in templates
+with a three-way merge against the version each copy was made from, keeping
+local modifications. The previous copy is backed up.
+
+When local and upstream changes conflict, the copy is left untouched and
+the merge result with conflict markers is written to <name>.conf.tpl.merge.
+Use --force to replace a copy with the upstream version instead.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeBuiltinTemplates,
+	RunE:              runTemplateBuiltinUpdate,
+}
+
+func init() {
+	templateCmd.AddCommand(templateBuiltinCmd)
+
+	templateBuiltinCmd.AddCommand(templateBuiltinDiffCmd)
+	templateBuiltinCmd.AddCommand(templateBuiltinUpdateCmd)
+
+	templateBuiltinUpdateCmd.Flags().BoolVar(&builtinForce, "force", false, "replace local copies with upstream, discarding local changes")
+	templateBuiltinUpdateCmd.Flags().BoolVar(&builtinDryRun, "dry-run", false, "show the merge result without writing files")
+}
+
+func builtinArgs(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return template.BuiltinNames(), nil
+	}
+	if !template.IsBuiltin(args[0]) {
+		return nil, fmt.Errorf("not a built-in template: %s (built-in: %s)", args[0], strings.Join(template.BuiltinNames(), ", "))
+	}
+	return args, nil
+}
+
+func runTemplateBuiltinDiff(cmd *cobra.Command, args []string) error {
+	names, err := builtinArgs(args)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		status, err := template.GetBuiltinStatus(name, templateDir)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s: %s\n", name, describeBuiltinStatus(status))
+		if !status.Installed || (!status.Modified && !status.UpdateAvailable) {
+			continue
+		}
+
+		diff, err := template.DiffBuiltin(name, templateDir)
+		if err != nil {
+			return err
+		}
+		if diff != "" {
+			fmt.Println(diff)
+		}
+	}
+
+	return nil
+}
+
+func runTemplateBuiltinUpdate(cmd *cobra.Command, args []string) error {
+	names, err := builtinArgs(args)
+	if err != nil {
+		return err
+	}
+
+	conflicted := 0
+	for _, name := range names {
+		if builtinForce {
+			if builtinDryRun {
+				fmt.Printf("%s: would be replaced with upstream\n", name)
+				continue
+			}
+			if err := template.ResetBuiltin(name, templateDir); err != nil {
+				return fmt.Errorf("failed to reset %s: %w", name, err)
+			}
+			fmt.Printf("%s: replaced with upstream\n", name)
+			continue
+		}
+
+		update, err := template.MergeBuiltin(name, templateDir)
+		if err != nil {
+			fmt.Printf("%s: %v\n", name, err)
+			conflicted++
+			continue
+		}
+
+		if !update.Changed {
+			fmt.Printf("%s: up to date\n", name)
+			continue
+		}
+
+		if update.Conflicts > 0 {
+			conflicted++
+			mergePath := filepath.Join(templateDir, name+".conf.tpl.merge")
+			if builtinDryRun {
+				fmt.Printf("%s: %d conflict(s)\n", name, update.Conflicts)
+				continue
+			}
+			if err := filesystem.WriteFile(mergePath, update.Merged, true); err != nil {
+				return err
+			}
+			fmt.Printf("%s: %d conflict(s), merge result written to %s\n", name, update.Conflicts, mergePath)
+			continue
+		}
+
+		if builtinDryRun {
+			fmt.Printf("%s: would be updated\n", name)
+			if verbose {
+				fmt.Println(update.Merged)
+			}
+			continue
+		}
+
+		if err := template.ApplyBuiltinUpdate(update, templateDir); err != nil {
+			return fmt.Errorf("failed to update %s: %w", name, err)
+		}
+		fmt.Printf("%s: updated\n", name)
+	}
+
+	if conflicted > 0 {
+		return fmt.Errorf("%d built-in template(s) could not be updated automatically", conflicted)
+	}
+
+	return nil
+}
+
+func describeBuiltinStatus(status template.BuiltinStatus) string {
+	if !status.Installed {
+		return "not installed (run 'ngcli init')"
+	}
+
+	var parts []string
+	if !status.Tracked {
+		parts = append(parts, "untracked copy")
+	}
+	if status.Modified {
+		parts = append(parts, "locally modified")
+	}
+	if status.UpdateAvailable {
+		parts = append(parts, fmt.Sprintf("upstream changes available (%s -> %s)", versionLabel(status.InstalledVersion), versionLabel(status.UpstreamVersion)))
+	}
+	if len(parts) == 0 {
+		return "up to date"
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func versionLabel(version string) string {
+	if version == "" {
+		return "unversioned"
+	}
+	return version
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/vourteen14/ngcli/filesystem"
+	"github.com/vourteen14/ngcli/template"
+	"github.com/vourteen14/ngcli/utils"
+)
+
+var (
+	docsFormat string
+	docsOut    string
+)
+
+var templateDocsCmd = &cobra.Command{
+	Use:   "docs [name...]",
+	Short: "Generate documentation for the template catalog",
+	Long: `Generate a documentation page for every template (or only the named
+ones) and an index page linking them, ready to be published.
+
+Each page contains the description, author and version of the template,
+its parameter table with types, defaults, options and constraints, its
+rules, example 'ngcli generate' commands and a sample render.
+
+Formats:
+  md    Markdown pages and index.md (default)
+  html  Standalone HTML pages and index.html
+  json  One catalog.json with every template
+
+Examples:
+  ngcli template docs --out ./catalog
+  ngcli template docs --format html --out ./public
+  ngcli template docs prod --format json --out -`,
+	ValidArgsFunction: completeTemplateList,
+	RunE:              runTemplateDocs,
+}
+
+func init() {
+	templateCmd.AddCommand(templateDocsCmd)
+
+	templateDocsCmd.Flags().StringVar(&docsFormat, "format", "md", "output format: md, html or json")
+	templateDocsCmd.Flags().StringVar(&docsOut, "out", "template-docs", "output directory (\"-\" prints to stdout)")
+}
+
+func runTemplateDocs(cmd *cobra.Command, args []string) error {
+	if docsFormat != "md" && docsFormat != "html" && docsFormat != "json" {
+		return fmt.Errorf("unsupported format: %s (use md, html or json)", docsFormat)
+	}
+
+	names := args
+	if len(names) == 0 {
+		templates, err := templateNames()
+		if err != nil {
+			return fmt.Errorf("failed to list templates: %w", err)
+		}
+		names = templates
+	}
+
+	if len(names) == 0 {
+		fmt.Printf("No templates found in %s\n", templateDir)
+		return nil
+	}
+
+	var docs []template.TemplateDoc
+	for _, name := range names {
+		tmpl, err := template.LoadTemplate(name, templateDir)
+		if err != nil {
+			return err
+		}
+
+		doc := template.BuildTemplateDoc(tmpl)
+		if doc.SampleError != "" {
+			fmt.Printf("Warning: no sample output for %s: %s\n", name, doc.SampleError)
+		}
+		docs = append(docs, doc)
+	}
+
+	files, err := renderDocs(docs)
+	if err != nil {
+		return err
+	}
+
+	if docsOut == "-" {
+		for _, file := range files {
+			fmt.Print(file.content)
+		}
+		return nil
+	}
+
+	if err := utils.EnsureDir(docsOut); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	for _, file := range files {
+		path := filepath.Join(docsOut, file.name)
+		if err := filesystem.WriteFile(path, file.content, true); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		if verbose {
+			fmt.Printf("Wrote %s\n", path)
+		}
+	}
+
+	fmt.Printf("Documented %d template(s) in %s\n", len(docs), docsOut)
+	return nil
+}
+
+type docFile struct {
+	name    string
+	content string
+}
+
+func renderDocs(docs []template.TemplateDoc) ([]docFile, error) {
+	var files []docFile
+
+	switch docsFormat {
+	case "json":
+		data, err := json.MarshalIndent(docs, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode documentation: %w", err)
+		}
+		files = append(files, docFile{name: "catalog.json", content: string(data) + "\n"})
+	case "html":
+		index, err := template.HTMLIndex(docs)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, docFile{name: "index.html", content: index})
+		for _, doc := range docs {
+			page, err := template.HTMLDoc(doc)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, docFile{name: template.DocFileName(doc.Name, "html"), content: page})
+		}
+	default:
+		files = append(files, docFile{name: "index.md", content: template.MarkdownIndex(docs)})
+		for _, doc := range docs {
+			files = append(files, docFile{name: template.DocFileName(doc.Name, "md"), content: template.MarkdownDoc(doc)})
+		}
+	}
+
+	return files, nil
+}
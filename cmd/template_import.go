@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vourteen14/ngcli/filesystem"
+	"github.com/vourteen14/ngcli/template"
+	"github.com/vourteen14/ngcli/utils"
+)
+
+var (
+	importName   string
+	importForce  bool
+	importDryRun bool
+)
+
+var templateImportCmd = &cobra.Command{
+	Use:   "import <file.conf>",
+	Short: "Create a template from an existing nginx configuration",
+	Long: `Create a template from a hand-written nginx configuration.
+
+The configuration is parsed and literals that are likely to change between
+sites become parameters: server_name values, listen ports, root paths,
+ssl_certificate/ssl_certificate_key paths and proxy_pass hosts and ports.
+
+Before the template is saved it is rendered with the extracted values and
+compared with the original file; the import fails unless the output is
+byte-for-byte identical.
+
+Examples:
+  ngcli template import /etc/nginx/sites-available/shop.conf --name shop
+  ngcli template import legacy.conf --name legacy --dry-run`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTemplateImport,
+}
+
+func init() {
+	templateCmd.AddCommand(templateImportCmd)
+
+	templateImportCmd.Flags().StringVar(&importName, "name", "", "name of the template to create (required)")
+	templateImportCmd.Flags().BoolVar(&importForce, "force", false, "overwrite an existing template")
+	templateImportCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "print the template without saving it")
+	templateImportCmd.MarkFlagRequired("name")
+}
+
+func runTemplateImport(cmd *cobra.Command, args []string) error {
+	sourcePath := args[0]
+
+	original, err := filesystem.ReadFile(sourcePath)
+	if err != nil {
+		return err
+	}
+
+	templatePath := filepath.Join(templateDir, importName+".conf.tpl")
+	if utils.FileExists(templatePath) && !importForce && !importDryRun {
+		return fmt.Errorf("template already exists: %s (use --force to overwrite)", importName)
+	}
+
+	result, err := template.ImportConfig(importName, filepath.Base(sourcePath), original)
+	if err != nil {
+		return fmt.Errorf("failed to import configuration: %w", err)
+	}
+
+	tmpl, err := template.ParseTemplate(importName, templatePath, result.Content)
+	if err != nil {
+		return fmt.Errorf("generated template is invalid: %w", err)
+	}
+
+	diff, err := template.VerifyImport(tmpl, result.Values, original)
+	if err != nil {
+		return fmt.Errorf("verification failed: %w", err)
+	}
+	if diff != "" {
+		fmt.Println(diff)
+		return fmt.Errorf("verification failed: rendering the template does not reproduce %s", sourcePath)
+	}
+
+	if len(result.Parameters) == 0 {
+		fmt.Println("No parameters detected; the template reproduces the configuration as-is")
+	} else {
+		fmt.Println("Detected parameters:")
+		fmt.Printf("  %-20s %-10s %-6s %s\n", "NAME", "TYPE", "USES", "VALUE")
+		for _, param := range result.Parameters {
+			fmt.Printf("  %-20s %-10s %-6d %s\n", param.Name, param.Type, param.Occurrences, param.Value)
+		}
+	}
+	fmt.Println("\nVerification: rendered output matches the original byte for byte")
+
+	if importDryRun {
+		fmt.Println("\nTemplate preview:")
+		fmt.Println(strings.Repeat("-", 50))
+		fmt.Println(result.Content)
+		fmt.Println(strings.Repeat("-", 50))
+		return nil
+	}
+
+	if err := filesystem.WriteFile(templatePath, result.Content, importForce); err != nil {
+		return fmt.Errorf("failed to save template: %w", err)
+	}
+
+	fmt.Printf("\nCreated template: %s\n", importName)
+	fmt.Printf("Template file: %s\n", templatePath)
+
+	var sets []string
+	for _, param := range result.Parameters {
+		sets = append(sets, fmt.Sprintf("--set %s=%s", param.Name, param.Value))
+	}
+	fmt.Printf("\nReproduce the original with:\n  ngcli generate <config_name> --template %s %s\n", importName, strings.Join(sets, " "))
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vourteen14/ngcli/filesystem"
+	"github.com/vourteen14/ngcli/template"
+)
+
+var (
+	migrateTo     string
+	migrateDryRun bool
+	migrateForce  bool
+)
+
+var templateMigrateCmd = &cobra.Command{
+	Use:   "migrate <name>",
+	Short: "Convert template metadata between comments and YAML front-matter",
+	Long: `Rewrite the metadata header of a template in the other format:
+
+  yaml      YAML front-matter ({{/* --- ... --- */}} or "# ---" blocks)
+  comments  Comment syntax ("# @param name type required \"description\"")
+
+Headers wrapped in a template comment stay wrapped, plain "#" headers stay
+plain. Nested types (array items, objects) and multi-line values cannot be
+expressed as comments. The previous template is backed up.
+
+Metadata the parser ignores, such as a malformed @param line or an unknown
+attribute, cannot be carried over. Each such line is listed, and the
+template is only rewritten without it with --force.
+
+Examples:
+  ngcli template migrate prod --to yaml
+  ngcli template migrate api --to comments --dry-run`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeTemplates,
+	RunE:              runTemplateMigrate,
+}
+
+func init() {
+	templateCmd.AddCommand(templateMigrateCmd)
+
+	templateMigrateCmd.Flags().StringVar(&migrateTo, "to", template.MetadataFrontMatter, "target format: yaml or comments")
+	templateMigrateCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "print the migrated template without saving it")
+	templateMigrateCmd.Flags().BoolVar(&migrateForce, "force", false, "migrate even if ignored metadata lines would be lost")
+}
+
+func runTemplateMigrate(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	tmpl, err := template.LoadTemplate(name, templateDir)
+	if err != nil {
+		return err
+	}
+
+	migrated, err := template.MigrateMetadata(tmpl.Content, migrateTo)
+	if err != nil {
+		return fmt.Errorf("failed to migrate %s: %w", name, err)
+	}
+
+	// The migrated template must declare exactly the same parameters
+	check, err := template.ParseTemplate(name, tmpl.Path, migrated)
+	if err != nil {
+		return fmt.Errorf("migrated template is invalid: %w", err)
+	}
+	if len(check.Metadata.Parameters) != len(tmpl.Metadata.Parameters) {
+		return fmt.Errorf("migrated template declares %d parameter(s) instead of %d", len(check.Metadata.Parameters), len(tmpl.Metadata.Parameters))
+	}
+
+	// Lines the parser ignored are not in the migrated header
+	for _, warning := range tmpl.Metadata.Warnings {
+		fmt.Printf("Warning: not migrated: %s\n", warning)
+	}
+
+	if migrateDryRun {
+		fmt.Println(strings.Repeat("-", 50))
+		fmt.Println(migrated)
+		fmt.Println(strings.Repeat("-", 50))
+		return nil
+	}
+
+	if len(tmpl.Metadata.Warnings) > 0 && !migrateForce {
+		return fmt.Errorf("%d metadata line(s) of %s would be lost; fix them or use --force", len(tmpl.Metadata.Warnings), name)
+	}
+
+	if err := filesystem.BackupFile(tmpl.Path); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", filepath.Base(tmpl.Path), err)
+	}
+	if err := filesystem.WriteFile(tmpl.Path, migrated, true); err != nil {
+		return fmt.Errorf("failed to save template: %w", err)
+	}
+
+	fmt.Printf("Migrated %s to %s metadata\n", name, migrateTo)
+	return nil
+}
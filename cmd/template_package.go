@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vourteen14/ngcli/config"
+	"github.com/vourteen14/ngcli/template"
+)
+
+var (
+	installForce  bool
+	searchSources []string
+)
+
+var templateInstallCmd = &cobra.Command{
+	Use:   "install <path|archive|git-url>",
+	Short: "Install a template package",
+	Long: `Install a template package from a local directory, a .tar.gz archive
+or a git repository. A package is a directory with a package.yaml manifest:
+
+  name: acme
+  version: 1.2.0
+  description: ACME web stack templates
+  templates: [prod.conf.tpl, api.conf.tpl]
+  partials: [partials/ssl.tpl]
+  min_ngcli_version: 1.0.0
+
+Templates are installed under the package name and used as <package>/<name>,
+for example 'ngcli generate shop --template acme/prod'. Partials are available
+to the package templates through {{template "ssl" .}}.
+
+Sources are written as <location>[//subdir][#ref], e.g.
+  ngcli template install ./acme-templates
+  ngcli template install acme-1.2.0.tar.gz
+  ngcli template install https://git.example.com/ops/templates.git//acme#v1.2.0`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTemplateInstall,
+}
+
+var templateUpgradeCmd = &cobra.Command{
+	Use:   "upgrade [package...]",
+	Short: "Upgrade installed template packages",
+	Long: `Upgrade installed template packages from the source they were
+installed from. Without arguments every installed package is checked.`,
+	ValidArgsFunction: completePackages,
+	RunE:              runTemplateUpgrade,
+}
+
+var templateUninstallCmd = &cobra.Command{
+	Use:               "uninstall <package>",
+	Short:             "Uninstall a template package",
+	Long:              `Uninstall a template package and remove all of its templates.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completePackages,
+	RunE:              runTemplateUninstall,
+}
+
+var templateSearchCmd = &cobra.Command{
+	Use:   "search [query]",
+	Short: "Search template packages in the configured sources",
+	Long: `Search template packages by name, description or template name.
+
+Sources are read from the 'sources' list in ~/.ngcli/config.yaml or given
+with --source. A source is a package or a directory/repository containing
+one package per subdirectory.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runTemplateSearch,
+}
+
+func init() {
+	templateCmd.AddCommand(templateInstallCmd)
+	templateCmd.AddCommand(templateUpgradeCmd)
+	templateCmd.AddCommand(templateUninstallCmd)
+	templateCmd.AddCommand(templateSearchCmd)
+
+	templateInstallCmd.Flags().BoolVar(&installForce, "force", false, "reinstall even if the package is already installed")
+	templateSearchCmd.Flags().StringArrayVar(&searchSources, "source", []string{}, "package source to search (overrides configured sources)")
+}
+
+func runTemplateInstall(cmd *cobra.Command, args []string) error {
+	installed, err := template.InstallPackage(args[0], templateDir, rootCmd.Version, installForce)
+	if err != nil {
+		return fmt.Errorf("failed to install package: %w", err)
+	}
+
+	fmt.Printf("Installed package %s %s\n", installed.Name, installed.Version)
+	printPackageTemplates(installed)
+
+	return nil
+}
+
+func runTemplateUpgrade(cmd *cobra.Command, args []string) error {
+	names := args
+	if len(names) == 0 {
+		packages, warnings, err := template.ListInstalledPackages(templateDir)
+		if err != nil {
+			return err
+		}
+		printWarnings(warnings)
+		if len(packages) == 0 {
+			fmt.Println("No template packages installed")
+			return nil
+		}
+		for _, pkg := range packages {
+			names = append(names, pkg.Name)
+		}
+	}
+
+	for _, name := range names {
+		previous, err := template.LoadInstalledPackage(name, templateDir)
+		if err != nil {
+			return err
+		}
+
+		upgraded, err := template.UpgradePackage(name, templateDir, rootCmd.Version)
+		if err != nil {
+			return fmt.Errorf("failed to upgrade package %s: %w", name, err)
+		}
+
+		if upgraded == nil {
+			fmt.Printf("Package %s %s is up to date\n", name, previous.Version)
+			continue
+		}
+
+		fmt.Printf("Upgraded package %s %s -> %s\n", name, previous.Version, upgraded.Version)
+		if verbose {
+			printPackageTemplates(upgraded)
+		}
+	}
+
+	return nil
+}
+
+func runTemplateUninstall(cmd *cobra.Command, args []string) error {
+	if err := template.UninstallPackage(args[0], templateDir); err != nil {
+		return fmt.Errorf("failed to uninstall package: %w", err)
+	}
+
+	fmt.Printf("Uninstalled package: %s\n", args[0])
+
+	return nil
+}
+
+func runTemplateSearch(cmd *cobra.Command, args []string) error {
+	sources := searchSources
+	if len(sources) == 0 {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		sources = cfg.Sources
+	}
+
+	if len(sources) == 0 {
+		fmt.Println("No package sources configured")
+		fmt.Println("Add a 'sources' list to ~/.ngcli/config.yaml or use --source")
+		return nil
+	}
+
+	query := ""
+	if len(args) == 1 {
+		query = args[0]
+	}
+
+	packages, err := template.SearchPackages(sources, query)
+	if err != nil {
+		return fmt.Errorf("failed to search packages: %w", err)
+	}
+
+	if len(packages) == 0 {
+		fmt.Println("No matching packages found")
+		return nil
+	}
+
+	installed := make(map[string]string)
+	if records, _, err := template.ListInstalledPackages(templateDir); err == nil {
+		for _, record := range records {
+			installed[record.Name] = record.Version
+		}
+	}
+
+	fmt.Printf("%-20s %-10s %-10s %s\n", "NAME", "VERSION", "INSTALLED", "SOURCE")
+	fmt.Printf("%-20s %-10s %-10s %s\n", "----", "-------", "---------", "------")
+
+	for _, pkg := range packages {
+		current := installed[pkg.Name]
+		if current == "" {
+			current = "-"
+		}
+		fmt.Printf("%-20s %-10s %-10s %s\n", pkg.Name, pkg.Version, current, pkg.Source)
+		if verbose && pkg.Description != "" {
+			fmt.Printf("  %s\n", pkg.Description)
+		}
+	}
+
+	return nil
+}
+
+func printPackageTemplates(pkg *template.InstalledPackage) {
+	fmt.Println("Templates:")
+	for _, tpl := range pkg.Templates {
+		fmt.Printf("  %s/%s\n", pkg.Name, templateBaseName(tpl))
+	}
+}
+
+func templateBaseName(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), ".conf.tpl")
+}
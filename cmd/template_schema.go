@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/vourteen14/ngcli/errcode"
+	"github.com/vourteen14/ngcli/filesystem"
+	"github.com/vourteen14/ngcli/template"
+)
+
+var (
+	schemaOut      string
+	schemaID       string
+	valuesTemplate string
+)
+
+var templateSchemaCmd = &cobra.Command{
+	Use:   "schema <name>",
+	Short: "Export the parameters of a template as JSON Schema",
+	Long: `Export a JSON Schema (draft 2020-12) describing valid values files for
+a template, so other tools can validate parameters without ngcli.
+
+The schema contains the type, description and default of every parameter,
+enums from options, min/max, minlen/maxlen and pattern constraints, required
+parameters, and the @when, @require and @conflicts rules. It is generated
+from the same constraints 'ngcli generate' enforces.
+
+Examples:
+  ngcli template schema prod
+  ngcli template schema prod --out prod.schema.json`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeTemplates,
+	RunE:              runTemplateSchema,
+}
+
+var validateValuesCmd = &cobra.Command{
+	Use:   "validate-values <values.yaml>",
+	Short: "Validate a values file against a template",
+	Long: `Validate a YAML or JSON values file against the parameters of a
+template, with the same rules as the schema from 'ngcli template schema'.
+
+Values must have the type of their parameter: integers and booleans are
+written unquoted (port: 8080, enable_ssl: true).
+
+Examples:
+  ngcli validate-values site.yaml --template prod
+  ngcli generate mysite --template prod --values site.yaml`,
+	Args: cobra.ExactArgs(1),
+	RunE: runValidateValues,
+}
+
+func init() {
+	templateCmd.AddCommand(templateSchemaCmd)
+	rootCmd.AddCommand(validateValuesCmd)
+
+	templateSchemaCmd.Flags().StringVar(&schemaOut, "out", "", "write the schema to this file instead of stdout")
+	templateSchemaCmd.Flags().StringVar(&schemaID, "id", "", "$id of the generated schema")
+
+	validateValuesCmd.Flags().StringVarP(&valuesTemplate, "template", "t", "", "template to validate against (required)")
+	validateValuesCmd.MarkFlagRequired("template")
+	validateValuesCmd.RegisterFlagCompletionFunc("template", completeTemplateFlag)
+}
+
+func runTemplateSchema(cmd *cobra.Command, args []string) error {
+	tmpl, err := template.LoadTemplate(args[0], templateDir)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(tmpl.Metadata.JSONSchema(schemaID), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode schema: %w", err)
+	}
+
+	if schemaOut == "" {
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if err := filesystem.WriteFile(schemaOut, string(data)+"\n", true); err != nil {
+		return fmt.Errorf("failed to write schema: %w", err)
+	}
+	fmt.Printf("Wrote schema for %s to %s\n", args[0], schemaOut)
+
+	return nil
+}
+
+func runValidateValues(cmd *cobra.Command, args []string) error {
+	tmpl, err := template.LoadTemplate(valuesTemplate, templateDir)
+	if err != nil {
+		return err
+	}
+
+	doc, err := template.LoadValuesFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := validateValues(tmpl, doc); err != nil {
+		var verr *template.ValidationError
+		if errors.As(err, &verr) && !jsonErrors() {
+			fmt.Printf("%s is not valid for template %s:\n", args[0], valuesTemplate)
+			for _, pe := range verr.Errors {
+				fmt.Printf("  - %s: %s\n", pe.Parameter, pe.Message)
+			}
+			return errcode.Summarize(err, "values validation failed")
+		}
+		return err
+	}
+
+	for _, warning := range tmpl.Metadata.DeprecationWarnings(stringValues(doc)) {
+		fmt.Printf("Warning: %s\n", warning)
+	}
+	fmt.Printf("%s is valid for template %s\n", args[0], valuesTemplate)
+
+	return nil
+}
+
+// validateValues checks a decoded values file: value types first, then every
+// parameter and rule, reporting all problems together.
+func validateValues(tmpl *template.Template, doc map[string]interface{}) error {
+	params, typeErr := tmpl.Metadata.ParseValues(doc)
+
+	verr := &template.ValidationError{}
+	if typeErr != nil && !errors.As(typeErr, &verr) {
+		return typeErr
+	}
+
+	// Values with the wrong type are reported once, by ParseValues
+	invalid := make(map[string]bool)
+	for _, pe := range verr.Errors {
+		invalid[pe.Parameter] = true
+	}
+
+	if err := tmpl.Metadata.ValidateParameters(tmpl.Metadata.ApplyDefaults(params)); err != nil {
+		var rules *template.ValidationError
+		if !errors.As(err, &rules) {
+			return err
+		}
+		for _, pe := range rules.Errors {
+			if !invalid[pe.Parameter] {
+				verr.Errors = append(verr.Errors, pe)
+			}
+		}
+	}
+
+	if len(verr.Errors) > 0 {
+		return verr
+	}
+
+	return nil
+}
+
+func stringValues(doc map[string]interface{}) map[string]string {
+	values := make(map[string]string, len(doc))
+	for key, value := range doc {
+		values[key] = fmt.Sprint(value)
+	}
+	return values
+}
+
+// mergeValuesFile loads a values file for tmpl and overlays params on it.
+func mergeValuesFile(tmpl *template.Template, path string, params map[string]string) (map[string]string, error) {
+	doc, err := template.LoadValuesFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := tmpl.Metadata.ParseValues(doc)
+	if err != nil {
+		var verr *template.ValidationError
+		if errors.As(err, &verr) && !jsonErrors() {
+			fmt.Printf("Invalid values in %s:\n", path)
+			for _, pe := range verr.Errors {
+				fmt.Printf("  - %s: %s\n", pe.Parameter, pe.Message)
+			}
+			return nil, errcode.Summarize(err, "values validation failed")
+		}
+		return nil, err
+	}
+
+	for key, value := range params {
+		values[key] = value
+	}
+
+	return values, nil
+}
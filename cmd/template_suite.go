@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/vourteen14/ngcli/errcode"
+	"github.com/vourteen14/ngcli/system"
+	"github.com/vourteen14/ngcli/template"
+	"github.com/vourteen14/ngcli/utils"
+)
+
+var (
+	testUpdate bool
+	testNginx  bool
+	testJUnit  string
+)
+
+var templateTestCmd = &cobra.Command{
+	Use:   "test [name]",
+	Short: "Run template test suites",
+	Long: `Run the test suite of a template, or of every template when no name
+is given. A test suite is a <name>.tests.yaml file next to <name>.conf.tpl:
+
+  cases:
+    - name: basic
+      params:
+        domain: example.com
+      golden: testdata/prod-basic.conf
+      directives:
+        - ssl_protocols TLSv1.2 TLSv1.3
+    - name: missing domain
+      params: {}
+      expect_errors: [domain]
+
+Use --update to rewrite golden files from the current output and --nginx to
+check every rendered case with an isolated 'nginx -t'. The command exits
+non-zero when any case fails; --junit writes a JUnit XML report for CI.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeTemplates,
+	RunE:              runTemplateTest,
+}
+
+func init() {
+	templateCmd.AddCommand(templateTestCmd)
+
+	templateTestCmd.Flags().BoolVar(&testUpdate, "update", false, "update golden files with the rendered output")
+	templateTestCmd.Flags().BoolVar(&testNginx, "nginx", false, "validate every case with an isolated nginx -t")
+	templateTestCmd.Flags().StringVar(&testJUnit, "junit", "", "write a JUnit XML report to this file")
+}
+
+func runTemplateTest(cmd *cobra.Command, args []string) error {
+	var names []string
+	if len(args) == 1 {
+		names = args
+	} else {
+		templates, err := templateNames()
+		if err != nil {
+			return fmt.Errorf("failed to list templates: %w", err)
+		}
+		for _, name := range templates {
+			if utils.FileExists(template.TestSuitePath(name, templateDir)) {
+				names = append(names, name)
+			}
+		}
+	}
+
+	if len(names) == 0 {
+		fmt.Printf("No template test suites found in %s\n", templateDir)
+		return nil
+	}
+
+	opts := template.TestOptions{
+		Update:     testUpdate,
+		NginxTest:  testNginx,
+		NginxCheck: system.NginxTestConfig,
+		NginxSkipped: func(err error) bool {
+			return errors.Is(err, system.ErrNginxNotFound)
+		},
+	}
+
+	var all []template.TestResult
+	for _, name := range names {
+		tmpl, err := template.LoadTemplate(name, templateDir)
+		if err != nil {
+			all = append(all, template.TestResult{Suite: name, Case: "load", Failures: []string{err.Error()}})
+			continue
+		}
+
+		suite, err := template.LoadTestSuite(name, templateDir)
+		if err != nil {
+			all = append(all, template.TestResult{Suite: name, Case: "load", Failures: []string{err.Error()}})
+			continue
+		}
+
+		all = append(all, suite.Run(tmpl, opts)...)
+	}
+
+	failed := 0
+	for _, result := range all {
+		status := "PASS"
+		switch {
+		case !result.Passed():
+			status = "FAIL"
+			failed++
+		case result.Skipped != "":
+			status = "SKIP"
+		}
+
+		fmt.Printf("%-4s %s/%s\n", status, result.Suite, result.Case)
+		for _, failure := range result.Failures {
+			fmt.Printf("     %s\n", strings.ReplaceAll(failure, "\n", "\n     "))
+		}
+		if verbose && result.Skipped != "" {
+			fmt.Printf("     skipped nginx check: %s\n", result.Skipped)
+		}
+	}
+
+	if testUpdate {
+		fmt.Println("\nGolden files updated")
+	}
+	fmt.Printf("\n%d passed, %d failed, %d total\n", len(all)-failed, failed, len(all))
+
+	if testJUnit != "" {
+		if err := writeJUnitReport(testJUnit, all); err != nil {
+			return err
+		}
+		if verbose {
+			fmt.Printf("JUnit report written to %s\n", testJUnit)
+		}
+	}
+
+	if failed > 0 {
+		return errcode.Errorf(errcode.TemplateTestFailed, "%d of %d template tests failed", failed, len(all))
+	}
+
+	return nil
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Tests   int              `xml:"tests,attr"`
+	Failed  int              `xml:"failures,attr"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name    string          `xml:"name,attr"`
+	Tests   int             `xml:"tests,attr"`
+	Failed  int             `xml:"failures,attr"`
+	Skipped int             `xml:"skipped,attr"`
+	Time    string          `xml:"time,attr"`
+	Cases   []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+func writeJUnitReport(path string, results []template.TestResult) error {
+	report := junitTestSuites{}
+	index := make(map[string]int)
+
+	for _, result := range results {
+		i, ok := index[result.Suite]
+		if !ok {
+			i = len(report.Suites)
+			index[result.Suite] = i
+			report.Suites = append(report.Suites, junitTestSuite{Name: result.Suite})
+		}
+		suite := &report.Suites[i]
+
+		tc := junitTestCase{
+			Name:      result.Case,
+			ClassName: "ngcli.template." + result.Suite,
+			Time:      formatSeconds(result.Duration),
+		}
+		if !result.Passed() {
+			tc.Failure = &junitFailure{
+				Message: strings.SplitN(result.Failures[0], "\n", 2)[0],
+				Body:    strings.Join(result.Failures, "\n"),
+			}
+			suite.Failed++
+			report.Failed++
+		} else if result.Skipped != "" {
+			tc.Skipped = &junitSkipped{Message: result.Skipped}
+			suite.Skipped++
+		}
+
+		suite.Cases = append(suite.Cases, tc)
+		suite.Tests++
+		report.Tests++
+	}
+
+	for i := range report.Suites {
+		var total time.Duration
+		for _, result := range results {
+			if result.Suite == report.Suites[i].Name {
+				total += result.Duration
+			}
+		}
+		report.Suites[i].Time = formatSeconds(total)
+	}
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode JUnit report: %w", err)
+	}
+
+	if err := os.WriteFile(path, append([]byte(xml.Header), append(data, '\n')...), 0644); err != nil {
+		return fmt.Errorf("failed to write JUnit report %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func formatSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
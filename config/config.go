@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/vourteen14/ngcli/layout"
 	"gopkg.in/yaml.v2"
 )
 
@@ -13,6 +14,11 @@ type Config struct {
 	OutputDir   string            `yaml:"output_dir"`
 	Verbose     bool              `yaml:"verbose"`
 	Defaults    map[string]string `yaml:"defaults"`
+	Sources     []string          `yaml:"sources"`
+	SecretStore string            `yaml:"secret_store"`
+	AgeIdentity string            `yaml:"age_identity"`
+	// Layout selects how configurations are enabled; see package layout
+	Layout layout.Options `yaml:"layout,omitempty"`
 }
 
 func DefaultConfig() *Config {
@@ -0,0 +1,168 @@
+// Package errcode gives ngcli errors stable codes and exit statuses, so
+// that scripts can tell a failed nginx test from a missing template or a
+// permission problem.
+//
+// Codes never change meaning; new failures get new codes.
+package errcode
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// Code identifies a kind of failure, e.g. "NGCLI-E012".
+type Code string
+
+// Error codes, grouped by exit status.
+const (
+	General Code = "NGCLI-E001"
+	Usage   Code = "NGCLI-E002"
+	// InputRequired is a question that cannot be asked, e.g. in CI
+	InputRequired Code = "NGCLI-E003"
+
+	NginxNotInstalled Code = "NGCLI-E010"
+	NginxReloadFailed Code = "NGCLI-E011"
+	NginxTestFailed   Code = "NGCLI-E012"
+
+	TemplateNotFound   Code = "NGCLI-E020"
+	TemplateInvalid    Code = "NGCLI-E021"
+	TemplateLintFailed Code = "NGCLI-E022"
+	TemplateTestFailed Code = "NGCLI-E023"
+
+	MissingParameters Code = "NGCLI-E030"
+	InvalidParameters Code = "NGCLI-E031"
+	SecretUnresolved  Code = "NGCLI-E032"
+	InvalidValues     Code = "NGCLI-E033"
+
+	ConfigNotFound   Code = "NGCLI-E040"
+	ConfigNotEnabled Code = "NGCLI-E041"
+	Unsupported      Code = "NGCLI-E042"
+
+	PermissionDenied Code = "NGCLI-E050"
+
+	// ChecksFailed is a failed check of 'ngcli doctor'
+	ChecksFailed Code = "NGCLI-E060"
+)
+
+// exitCodes maps codes to the exit status of ngcli.
+var exitCodes = map[Code]int{
+	General:            1,
+	ChecksFailed:       1,
+	Usage:              2,
+	InputRequired:      2,
+	MissingParameters:  3,
+	InvalidParameters:  3,
+	SecretUnresolved:   3,
+	InvalidValues:      3,
+	TemplateNotFound:   4,
+	ConfigNotFound:     4,
+	ConfigNotEnabled:   4,
+	TemplateInvalid:    5,
+	TemplateLintFailed: 5,
+	TemplateTestFailed: 5,
+	NginxTestFailed:    6,
+	NginxNotInstalled:  7,
+	NginxReloadFailed:  7,
+	Unsupported:        7,
+	PermissionDenied:   8,
+}
+
+// ExitCode returns the exit status for code.
+func (c Code) ExitCode() int {
+	if status, ok := exitCodes[c]; ok {
+		return status
+	}
+	return 1
+}
+
+// Field is a problem with a single parameter or field.
+type Field struct {
+	Name    string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is an error with a code. Fields lists problems with individual
+// parameters, if any.
+type Error struct {
+	Code   Code
+	Err    error
+	Fields []Field
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New returns an error with code and a fixed message.
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Err: errors.New(message)}
+}
+
+// Errorf returns an error with code, formatted as by fmt.Errorf.
+func Errorf(code Code, format string, args ...interface{}) error {
+	return &Error{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+// Wrap gives err a code; it returns nil for a nil err.
+func Wrap(code Code, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// Summarize replaces the message of err, whose details have already been
+// printed, keeping its code and field problems.
+func Summarize(err error, message string) error {
+	return &Error{Code: CodeOf(err), Err: errors.New(message), Fields: FieldsOf(err)}
+}
+
+// Coder is implemented by error types that know their code, such as
+// template.ValidationError.
+type Coder interface {
+	ErrorCode() Code
+}
+
+// FieldErrors is implemented by error types that describe problems with
+// individual fields.
+type FieldErrors interface {
+	FieldErrors() []Field
+}
+
+// CodeOf returns the code of the first coded error in the chain of err.
+// Permission errors without a code are PermissionDenied, anything else
+// General.
+func CodeOf(err error) Code {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		switch coded := e.(type) {
+		case *Error:
+			return coded.Code
+		case Coder:
+			return coded.ErrorCode()
+		}
+	}
+	if errors.Is(err, fs.ErrPermission) {
+		return PermissionDenied
+	}
+	return General
+}
+
+// FieldsOf returns the field problems recorded in the chain of err.
+func FieldsOf(err error) []Field {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		switch fielded := e.(type) {
+		case *Error:
+			if len(fielded.Fields) > 0 {
+				return fielded.Fields
+			}
+		case FieldErrors:
+			return fielded.FieldErrors()
+		}
+	}
+	return nil
+}
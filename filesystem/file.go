@@ -1,9 +1,13 @@
 package filesystem
 
 import (
+	"archive/tar"
+	"compress/gzip"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 )
@@ -25,6 +29,36 @@ func WriteFile(path, content string, force bool) error {
 	return nil
 }
 
+// WriteFileMode writes content with the given mode. The mode is also
+// applied to an existing file before its content is replaced, so secrets
+// are never written to a file others can read.
+func WriteFileMode(path, content string, mode os.FileMode, force bool) error {
+	if _, err := os.Stat(path); err == nil {
+		if !force {
+			return fmt.Errorf("file already exists: %s (use --force to overwrite)", path)
+		}
+		if err := os.Chmod(path, mode); err != nil {
+			return fmt.Errorf("failed to set mode of %s: %w", path, err)
+		}
+	}
+	
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", path, err)
+	}
+	
+	// The umask may have narrowed the mode of a new file
+	if err := os.Chmod(path, mode); err != nil {
+		return fmt.Errorf("failed to set mode of %s: %w", path, err)
+	}
+	
+	return nil
+}
+
 func BackupFile(path string) error {
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		return nil
@@ -33,18 +67,45 @@ func BackupFile(path string) error {
 	timestamp := time.Now().Format("20060102-150405")
 	backupPath := fmt.Sprintf("%s.backup-%s", path, timestamp)
 	
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat original file %s: %w", path, err)
+	}
+	
 	content, err := os.ReadFile(path)
 	if err != nil {
 		return fmt.Errorf("failed to read original file %s: %w", path, err)
 	}
 	
-	if err := os.WriteFile(backupPath, content, 0644); err != nil {
+	// Keep the permissions of the original, which may hold secrets
+	if err := os.WriteFile(backupPath, content, info.Mode().Perm()); err != nil {
 		return fmt.Errorf("failed to create backup %s: %w", backupPath, err)
 	}
 	
 	return nil
 }
 
+// RestoreLatestBackup puts back the newest backup BackupFile made of path.
+func RestoreLatestBackup(path string) error {
+	backups, err := filepath.Glob(path + ".backup-*")
+	if err != nil || len(backups) == 0 {
+		return fmt.Errorf("no backup of %s found", path)
+	}
+	// Timestamps sort in time order
+	sort.Strings(backups)
+	latest := backups[len(backups)-1]
+	
+	info, err := os.Stat(latest)
+	if err != nil {
+		return fmt.Errorf("failed to stat backup %s: %w", latest, err)
+	}
+	content, err := os.ReadFile(latest)
+	if err != nil {
+		return fmt.Errorf("failed to read backup %s: %w", latest, err)
+	}
+	return WriteFileMode(path, string(content), info.Mode().Perm(), true)
+}
+
 func ReadFile(path string) (string, error) {
 	content, err := os.ReadFile(path)
 	if err != nil {
@@ -80,24 +141,54 @@ func ListConfigs(dir string) ([]string, error) {
 	return configs, nil
 }
 
-func CreateSymlink(src, dst string) error {
+// CreateSymlink links dst to src, replacing a symbolic link already at
+// dst. With relative the link holds the path of src relative to the
+// directory of dst, so it survives moving both directories together.
+func CreateSymlink(src, dst string, relative bool) error {
 	if _, err := os.Stat(src); os.IsNotExist(err) {
 		return fmt.Errorf("source file does not exist: %s", src)
 	}
 	
-	if _, err := os.Lstat(dst); err == nil {
+	target := src
+	if relative {
+		var err error
+		if target, err = relativeTarget(src, dst); err != nil {
+			return err
+		}
+	}
+	
+	if info, err := os.Lstat(dst); err == nil {
+		if info.Mode()&os.ModeSymlink == 0 {
+			return fmt.Errorf("%s exists and is not a symbolic link", dst)
+		}
 		if err := os.Remove(dst); err != nil {
 			return fmt.Errorf("failed to remove existing symlink %s: %w", dst, err)
 		}
 	}
 	
-	if err := os.Symlink(src, dst); err != nil {
+	if err := os.Symlink(target, dst); err != nil {
 		return fmt.Errorf("failed to create symlink %s -> %s: %w", dst, src, err)
 	}
 	
 	return nil
 }
 
+func relativeTarget(src, dst string) (string, error) {
+	absSrc, err := filepath.Abs(src)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", src, err)
+	}
+	absDst, err := filepath.Abs(dst)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", dst, err)
+	}
+	target, err := filepath.Rel(filepath.Dir(absDst), absSrc)
+	if err != nil {
+		return "", fmt.Errorf("failed to make %s relative to %s: %w", src, filepath.Dir(dst), err)
+	}
+	return target, nil
+}
+
 func RemoveSymlink(path string) error {
 	info, err := os.Lstat(path)
 	if os.IsNotExist(err) {
@@ -140,4 +231,76 @@ func CheckWritePermission(dir string) error {
 	os.Remove(testFile)
 	
 	return nil
-}
\ No newline at end of file
+}
+
+// CopyFile copies src to dst, creating the directory of dst if needed.
+func CopyFile(src, dst string) error {
+	content, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", src, err)
+	}
+	
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(dst), err)
+	}
+	
+	if err := os.WriteFile(dst, content, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", dst, err)
+	}
+	
+	return nil
+}
+
+// ExtractTarGz extracts a .tar.gz archive into dest. Entries that would
+// escape dest are rejected.
+func ExtractTarGz(archive, dest string) error {
+	file, err := os.Open(archive)
+	if err != nil {
+		return fmt.Errorf("failed to open archive %s: %w", archive, err)
+	}
+	defer file.Close()
+	
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("failed to read archive %s: %w", archive, err)
+	}
+	defer gz.Close()
+	
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive %s: %w", archive, err)
+		}
+		
+		target := filepath.Join(dest, header.Name)
+		if target != filepath.Clean(dest) && !strings.HasPrefix(target, filepath.Clean(dest)+string(os.PathSeparator)) {
+			return fmt.Errorf("archive entry escapes destination: %s", header.Name)
+		}
+		
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", target, err)
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(target), err)
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			if err != nil {
+				return fmt.Errorf("failed to create %s: %w", target, err)
+			}
+			if _, err := io.Copy(out, tr); err != nil {
+				out.Close()
+				return fmt.Errorf("failed to extract %s: %w", target, err)
+			}
+			out.Close()
+		}
+	}
+	
+	return nil
+}
@@ -0,0 +1,77 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func assertMode(t *testing.T, path string, want os.FileMode) {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != want {
+		t.Errorf("mode of %s = %04o, want %04o", filepath.Base(path), info.Mode().Perm(), want)
+	}
+}
+
+func TestWriteFileModeNarrowsExistingFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "site.htpasswd")
+	if err := WriteFileMode(path, "admin:old", 0644, false); err != nil {
+		t.Fatalf("WriteFileMode: %v", err)
+	}
+	assertMode(t, path, 0644)
+
+	// A file that now holds secrets must not stay world-readable
+	if err := WriteFileMode(path, "admin:s3cret", 0600, true); err != nil {
+		t.Fatalf("WriteFileMode: %v", err)
+	}
+	assertMode(t, path, 0600)
+
+	if err := WriteFileMode(path, "admin:s3cret", 0600, false); err == nil {
+		t.Errorf("overwriting without force succeeded")
+	}
+}
+
+func TestBackupFileKeepsMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "site.htpasswd")
+	if err := WriteFileMode(path, "admin:s3cret", 0600, false); err != nil {
+		t.Fatalf("WriteFileMode: %v", err)
+	}
+	if err := BackupFile(path); err != nil {
+		t.Fatalf("BackupFile: %v", err)
+	}
+
+	backups, _ := filepath.Glob(path + ".backup-*")
+	if len(backups) != 1 {
+		t.Fatalf("backups = %v, want one", backups)
+	}
+	assertMode(t, backups[0], 0600)
+}
+
+func TestRestoreLatestBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "site.conf")
+	if err := RestoreLatestBackup(path); err == nil {
+		t.Errorf("RestoreLatestBackup without a backup succeeded")
+	}
+
+	if err := os.WriteFile(path+".backup-20260101-000000", []byte("oldest"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path+".backup-20260102-000000", []byte("newest"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileMode(path, "broken", 0644, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RestoreLatestBackup(path); err != nil {
+		t.Fatalf("RestoreLatestBackup: %v", err)
+	}
+	if content, _ := os.ReadFile(path); string(content) != "newest" {
+		t.Errorf("restored %q, want the newest backup", content)
+	}
+	assertMode(t, path, 0600)
+}
@@ -3,11 +3,12 @@ module github.com/vourteen14/ngcli
 go 1.21
 
 require (
+	github.com/santhosh-tekuri/jsonschema/v5 v5.3.1
 	github.com/spf13/cobra v1.8.0
 	gopkg.in/yaml.v2 v2.4.0
 )
 
 require (
-	github.com/inconshreveable/mousetrap v1.1.0
-	github.com/spf13/pflag v1.0.5
+	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	github.com/spf13/pflag v1.0.5 // indirect
 )
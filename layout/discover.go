@@ -0,0 +1,246 @@
+package layout
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vourteen14/ngcli/nginxconf"
+	"github.com/vourteen14/ngcli/system"
+	"gopkg.in/yaml.v2"
+)
+
+// Discovery is what nginx itself says about where site configurations
+// are loaded from: the build found with 'nginx -V' and the include
+// directives of its main configuration file.
+type Discovery struct {
+	Binary   string `yaml:"binary"`
+	Version  string `yaml:"version"`
+	Prefix   string `yaml:"prefix"`
+	ConfPath string `yaml:"conf_path"`
+	// Source is how the configuration was read: files, or nginx -T where
+	// the files are not readable
+	Source   string    `yaml:"source"`
+	Includes []Include `yaml:"includes"`
+	// Layout is the layout the includes describe; its Name is empty if
+	// none of them loads site configurations
+	Layout Options `yaml:"layout"`
+	// Problems are files that could not be read or parsed
+	Problems     []string  `yaml:"problems,omitempty"`
+	DiscoveredAt time.Time `yaml:"discovered_at"`
+	// Files are the binary and configuration files read, with their
+	// modification times; the cached discovery is stale once one changes
+	Files []FileStamp `yaml:"files"`
+}
+
+// Include is an include directive, with its pattern made absolute.
+type Include struct {
+	Pattern string `yaml:"pattern"`
+	File    string `yaml:"file"`
+	Line    int    `yaml:"line"`
+	// Context is the enclosing blocks, e.g. "http", or "main" at the top
+	// level
+	Context string `yaml:"context"`
+}
+
+// FileStamp is a file and its modification time.
+type FileStamp struct {
+	Path    string    `yaml:"path"`
+	ModTime time.Time `yaml:"mod_time"`
+}
+
+// CachePath returns the file the discovery is cached in.
+func CachePath() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".ngcli", "cache", "nginx.yaml")
+}
+
+// Discover returns the cached discovery while nginx and its configuration
+// files are unchanged, and discovers them again otherwise or if refresh is
+// set. It returns system.ErrNginxNotFound without nginx.
+func Discover(refresh bool) (*Discovery, error) {
+	if !refresh {
+		if cached := loadCache(); cached != nil && cached.fresh() {
+			return cached, nil
+		}
+	}
+
+	d, err := discover()
+	if err != nil {
+		return nil, err
+	}
+	// The cache only saves work
+	d.save()
+	return d, nil
+}
+
+func loadCache() *Discovery {
+	data, err := os.ReadFile(CachePath())
+	if err != nil {
+		return nil
+	}
+	var d Discovery
+	if err := yaml.Unmarshal(data, &d); err != nil {
+		return nil
+	}
+	return &d
+}
+
+func (d *Discovery) save() error {
+	data, err := yaml.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("failed to encode nginx discovery: %w", err)
+	}
+	path := CachePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// fresh reports whether the nginx on PATH and the files read are those
+// of the discovery.
+func (d *Discovery) fresh() bool {
+	if binary, err := system.NginxPath(); err != nil || binary != d.Binary {
+		return false
+	}
+	for _, file := range d.Files {
+		if !stamp(file.Path).ModTime.Equal(file.ModTime) {
+			return false
+		}
+	}
+	return true
+}
+
+// stamp returns the modification time of path, zero if it does not
+// exist.
+func stamp(path string) FileStamp {
+	s := FileStamp{Path: path}
+	if info, err := os.Stat(path); err == nil {
+		s.ModTime = info.ModTime().UTC()
+	}
+	return s
+}
+
+func discover() (*Discovery, error) {
+	build, err := system.NginxBuildInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Discovery{
+		Binary:       build.Binary,
+		Version:      build.Version,
+		Prefix:       build.Prefix,
+		ConfPath:     build.ConfPath,
+		Source:       "files",
+		DiscoveredAt: time.Now().UTC(),
+		Files:        []FileStamp{stamp(build.Binary), stamp(build.ConfPath)},
+	}
+
+	read := func(path string) (string, error) {
+		data, err := os.ReadFile(path)
+		return string(data), err
+	}
+	if _, err := os.ReadFile(build.ConfPath); err != nil {
+		dump, dumpErr := system.NginxDumpConfig()
+		if dumpErr != nil {
+			d.Problems = append(d.Problems, fmt.Sprintf("cannot read %s: %v; %v", build.ConfPath, err, dumpErr))
+			return d, nil
+		}
+		d.Source = "nginx -T"
+		read = func(path string) (string, error) {
+			if content, ok := dump[path]; ok {
+				return content, nil
+			}
+			return "", fmt.Errorf("not in the output of nginx -T")
+		}
+	}
+
+	d.readIncludes(build.ConfPath, nil, read, map[string]bool{})
+	d.Layout = includeOptions(d.Includes)
+	return d, nil
+}
+
+// readIncludes records the include directives of file, whose directives
+// are inside the parents blocks, and follows includes of single files.
+// Wildcard includes load many files, such as the site configurations,
+// and are not followed.
+func (d *Discovery) readIncludes(file string, parents []string, read func(string) (string, error), seen map[string]bool) {
+	if seen[file] {
+		return
+	}
+	seen[file] = true
+
+	content, err := read(file)
+	if err != nil {
+		d.Problems = append(d.Problems, fmt.Sprintf("cannot read %s: %v", file, err))
+		return
+	}
+	directives, err := nginxconf.Parse(content)
+	if err != nil {
+		d.Problems = append(d.Problems, fmt.Sprintf("cannot parse %s: %v", file, err))
+		return
+	}
+
+	confDir := filepath.Dir(d.ConfPath)
+	nginxconf.Walk(directives, func(dir *nginxconf.Directive, blocks []string) {
+		if dir.Name != "include" || len(dir.Args) != 1 {
+			return
+		}
+		enclosing := append(append([]string{}, parents...), blocks...)
+		context := strings.Join(enclosing, " ")
+		if context == "" {
+			context = "main"
+		}
+		pattern := dir.Args[0].Value
+		// Relative includes are relative to the directory of nginx.conf
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(confDir, pattern)
+		}
+		d.Includes = append(d.Includes, Include{Pattern: pattern, File: file, Line: dir.Line, Context: context})
+
+		if !strings.ContainsAny(pattern, "*?[") {
+			d.Files = append(d.Files, stamp(pattern))
+			d.readIncludes(pattern, enclosing, read, seen)
+		}
+	})
+}
+
+// includeOptions returns the layout of the wildcard includes of the http
+// block. A directory named like sites-enabled next to one named like
+// sites-available is a debian layout; otherwise the first wildcard
+// include is a conf.d layout, which moves disabled configurations away
+// unless its pattern only matches *.conf.
+func includeOptions(includes []Include) Options {
+	var sites []Include
+	for _, include := range includes {
+		if include.Context == "http" && strings.ContainsAny(filepath.Base(include.Pattern), "*?[") {
+			sites = append(sites, include)
+		}
+	}
+
+	for _, include := range sites {
+		dir := filepath.Dir(include.Pattern)
+		if base, ok := strings.CutSuffix(filepath.Base(dir), "-enabled"); ok {
+			available := filepath.Join(filepath.Dir(dir), base+"-available")
+			if isDir(available) {
+				return Options{Name: "debian", ConfigDir: available, IncludeDir: dir}
+			}
+		}
+	}
+
+	if len(sites) == 0 {
+		return Options{}
+	}
+	opts := Options{Name: "conf.d", ConfigDir: filepath.Dir(sites[0].Pattern)}
+	if filepath.Base(sites[0].Pattern) != "*.conf" {
+		opts.Disable = "move"
+	}
+	return opts
+}
@@ -0,0 +1,113 @@
+package layout
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiscoverIncludes(t *testing.T) {
+	// The debian layout needs its sites-available directory to exist
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "sites-available"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		confPath string
+		// files are the configuration files, as 'nginx -T' prints them
+		files        map[string]string
+		wantIncludes []string
+		wantLayout   Options
+		wantProblems []string
+	}{
+		{
+			name:     "custom prefix",
+			confPath: "/opt/nginx/etc/nginx.conf",
+			files: map[string]string{
+				"/opt/nginx/etc/nginx.conf": "events {}\nhttp {\n    include mime.types;\n    include http.d/common.conf;\n}\n",
+				"/opt/nginx/etc/mime.types": "types { text/html html; }\n",
+				// A single file included from http is read in the http
+				// context
+				"/opt/nginx/etc/http.d/common.conf": "include /opt/nginx/sites/*.site;\n",
+			},
+			wantIncludes: []string{
+				"/opt/nginx/etc/mime.types http /opt/nginx/etc/nginx.conf:3",
+				"/opt/nginx/etc/http.d/common.conf http /opt/nginx/etc/nginx.conf:4",
+				"/opt/nginx/sites/*.site http /opt/nginx/etc/http.d/common.conf:1",
+			},
+			wantLayout: Options{Name: "conf.d", ConfigDir: "/opt/nginx/sites", Disable: "move"},
+		},
+		{
+			name:     "OpenResty",
+			confPath: "/usr/local/openresty/nginx/conf/nginx.conf",
+			files: map[string]string{
+				"/usr/local/openresty/nginx/conf/nginx.conf": "include modules/*.conf;\nhttp {\n    lua_package_path \"/usr/local/openresty/lualib/?.lua;;\";\n    include conf.d/*.conf;\n}\nstream { include stream.d/*.conf; }\n",
+			},
+			wantIncludes: []string{
+				"/usr/local/openresty/nginx/conf/modules/*.conf main /usr/local/openresty/nginx/conf/nginx.conf:1",
+				"/usr/local/openresty/nginx/conf/conf.d/*.conf http /usr/local/openresty/nginx/conf/nginx.conf:4",
+				"/usr/local/openresty/nginx/conf/stream.d/*.conf stream /usr/local/openresty/nginx/conf/nginx.conf:6",
+			},
+			wantLayout: Options{Name: "conf.d", ConfigDir: "/usr/local/openresty/nginx/conf/conf.d"},
+		},
+		{
+			name:     "debian",
+			confPath: filepath.Join(root, "nginx.conf"),
+			files: map[string]string{
+				filepath.Join(root, "nginx.conf"): "http {\n    include conf.d/*.conf;\n    include sites-enabled/*;\n}\n",
+			},
+			wantIncludes: []string{
+				filepath.Join(root, "conf.d/*.conf") + " http " + filepath.Join(root, "nginx.conf") + ":2",
+				filepath.Join(root, "sites-enabled/*") + " http " + filepath.Join(root, "nginx.conf") + ":3",
+			},
+			wantLayout: Options{Name: "debian", ConfigDir: filepath.Join(root, "sites-available"), IncludeDir: filepath.Join(root, "sites-enabled")},
+		},
+		{
+			name:     "missing and recursive includes",
+			confPath: "/etc/nginx/nginx.conf",
+			files: map[string]string{
+				"/etc/nginx/nginx.conf": "http { include a.conf; include missing.conf; }\n",
+				"/etc/nginx/a.conf":     "include /etc/nginx/nginx.conf;\n",
+			},
+			wantIncludes: []string{
+				"/etc/nginx/a.conf http /etc/nginx/nginx.conf:1",
+				"/etc/nginx/nginx.conf http /etc/nginx/a.conf:1",
+				"/etc/nginx/missing.conf http /etc/nginx/nginx.conf:1",
+			},
+			wantProblems: []string{"cannot read /etc/nginx/missing.conf: not in the output of nginx -T"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			read := func(path string) (string, error) {
+				if content, ok := tt.files[path]; ok {
+					return content, nil
+				}
+				return "", fmt.Errorf("not in the output of nginx -T")
+			}
+			d := &Discovery{ConfPath: tt.confPath}
+			d.readIncludes(tt.confPath, nil, read, map[string]bool{})
+			d.Layout = includeOptions(d.Includes)
+
+			var includes []string
+			for _, include := range d.Includes {
+				includes = append(includes, fmt.Sprintf("%s %s %s:%d", include.Pattern, include.Context, include.File, include.Line))
+			}
+			if !reflect.DeepEqual(includes, tt.wantIncludes) {
+				t.Errorf("includes:\n%s\nwant:\n%s", strings.Join(includes, "\n"), strings.Join(tt.wantIncludes, "\n"))
+			}
+			if d.Layout != tt.wantLayout {
+				t.Errorf("layout = %+v, want %+v", d.Layout, tt.wantLayout)
+			}
+			if !reflect.DeepEqual(d.Problems, tt.wantProblems) {
+				t.Errorf("problems = %q, want %q", d.Problems, tt.wantProblems)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package layout
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/vourteen14/ngcli/errcode"
+	"github.com/vourteen14/ngcli/filesystem"
+	"github.com/vourteen14/ngcli/utils"
+)
+
+// disabledSuffix is added to the configurations disabled by renaming;
+// nginx only includes *.conf.
+const disabledSuffix = ".disabled"
+
+// includeLayout writes configurations straight into the directory nginx
+// includes, so every *.conf file there is enabled. Disabling renames a
+// configuration to *.conf.disabled or moves it to disabledDir.
+type includeLayout struct {
+	name        string
+	dir         string
+	move        bool
+	disabledDir string
+}
+
+func newConfD(opts Options) (Layout, error) {
+	l := &includeLayout{name: "conf.d", dir: opts.ConfigDir, disabledDir: opts.DisabledDir}
+	if l.dir == "" {
+		l.dir = opts.IncludeDir
+	}
+	if l.dir == "" {
+		l.dir = confD
+	}
+	if l.disabledDir == "" {
+		l.disabledDir = filepath.Join(filepath.Dir(l.dir), "conf.available")
+	}
+
+	switch opts.Disable {
+	case "", "rename":
+	case "move":
+		l.move = true
+	default:
+		return nil, fmt.Errorf("unknown disable method: %s (use rename or move)", opts.Disable)
+	}
+	return l, nil
+}
+
+func (l *includeLayout) Name() string       { return l.name }
+func (l *includeLayout) ConfigDir() string  { return l.dir }
+func (l *includeLayout) IncludeDir() string { return l.dir }
+
+// List returns the *.conf files of the directory, then the disabled ones,
+// renamed or moved. A disabled copy of an enabled configuration is left
+// out.
+func (l *includeLayout) List() ([]Site, error) {
+	if _, err := os.Stat(l.dir); os.IsNotExist(err) {
+		return nil, fmt.Errorf("directory does not exist: %s", l.dir)
+	}
+
+	sites := make([]Site, 0)
+	seen := make(map[string]bool)
+	add := func(dir, suffix string, status Status) error {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to read directory %s: %w", dir, err)
+		}
+		for _, entry := range entries {
+			name := strings.TrimSuffix(entry.Name(), suffix)
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), suffix) || !strings.HasSuffix(name, ".conf") || seen[name] {
+				continue
+			}
+			seen[name] = true
+			sites = append(sites, Site{Name: name, Path: filepath.Join(dir, entry.Name()), Status: status})
+		}
+		return nil
+	}
+
+	if err := add(l.dir, "", Enabled); err != nil {
+		return nil, err
+	}
+	if err := add(l.dir, disabledSuffix, Disabled); err != nil {
+		return nil, err
+	}
+	if err := add(l.disabledDir, "", Disabled); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(sites, func(i, j int) bool { return sites[i].Name < sites[j].Name })
+	return sites, nil
+}
+
+func (l *includeLayout) Find(name string) (Site, error) {
+	file := strings.TrimSuffix(name, disabledSuffix)
+	if !strings.HasSuffix(file, ".conf") {
+		file += ".conf"
+	}
+	for _, site := range l.copies(file) {
+		if utils.FileExists(site.Path) {
+			return site, nil
+		}
+	}
+	return Site{}, errcode.Errorf(errcode.ConfigNotFound, "configuration file not found: %s", name)
+}
+
+// copies returns the places a configuration can be, enabled first.
+func (l *includeLayout) copies(file string) []Site {
+	return []Site{
+		{Name: file, Path: filepath.Join(l.dir, file), Status: Enabled},
+		{Name: file, Path: filepath.Join(l.dir, file+disabledSuffix), Status: Disabled},
+		{Name: file, Path: filepath.Join(l.disabledDir, file), Status: Disabled},
+	}
+}
+
+// Enable moves the configuration back into the directory, and removes
+// the disabled copies left by regenerating a disabled configuration; a
+// backup of each is kept.
+func (l *includeLayout) Enable(site Site) ([]string, error) {
+	var changes []string
+	target := filepath.Join(l.dir, site.Name)
+	if site.Status != Enabled {
+		if err := os.Rename(site.Path, target); err != nil {
+			return nil, fmt.Errorf("failed to move %s to %s: %w", site.Path, target, err)
+		}
+		changes = append(changes, fmt.Sprintf("Renamed: %s -> %s", site.Path, target))
+	}
+
+	for _, stale := range l.copies(site.Name)[1:] {
+		if !utils.FileExists(stale.Path) {
+			continue
+		}
+		if err := filesystem.BackupFile(stale.Path); err != nil {
+			return changes, err
+		}
+		if err := filesystem.DeleteFile(stale.Path); err != nil {
+			return changes, err
+		}
+		changes = append(changes, "Removed disabled copy: "+stale.Path)
+	}
+	return changes, nil
+}
+
+// Disable renames the configuration to *.conf.disabled, or moves it to
+// the disabled directory. A disabled copy already there is backed up and
+// replaced.
+func (l *includeLayout) Disable(site Site) ([]string, error) {
+	if site.Status != Enabled {
+		return nil, errcode.Errorf(errcode.ConfigNotEnabled, "configuration not enabled: %s", site.Name)
+	}
+
+	target := site.Path + disabledSuffix
+	if l.move {
+		if err := utils.EnsureDir(l.disabledDir); err != nil {
+			return nil, fmt.Errorf("failed to create directory %s: %w", l.disabledDir, err)
+		}
+		target = filepath.Join(l.disabledDir, site.Name)
+	}
+	if err := filesystem.BackupFile(target); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(site.Path, target); err != nil {
+		return nil, fmt.Errorf("failed to move %s to %s: %w", site.Path, target, err)
+	}
+	return []string{fmt.Sprintf("Renamed: %s -> %s", site.Path, target)}, nil
+}
+
+func (l *includeLayout) Remove(site Site) ([]string, error) {
+	return removeFile(site.Path)
+}
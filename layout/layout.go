@@ -0,0 +1,294 @@
+// Package layout describes how an nginx installation arranges its site
+// configurations: the directory ngcli writes them to and how one is
+// enabled or disabled without deleting it.
+//
+// Debian and Ubuntu keep configurations in sites-available and enable
+// them with symbolic links in sites-enabled. RHEL, CentOS and Alpine
+// include every *.conf file of conf.d, so a configuration there is
+// disabled by renaming it to *.conf.disabled or by moving it to a
+// conf.available directory next to conf.d. Other arrangements are
+// described by the custom layout, or registered with Register.
+package layout
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/vourteen14/ngcli/errcode"
+	"github.com/vourteen14/ngcli/filesystem"
+	"github.com/vourteen14/ngcli/utils"
+)
+
+// Status is whether nginx loads a configuration.
+type Status string
+
+const (
+	Enabled  Status = "enabled"
+	Disabled Status = "disabled"
+	// Unknown is the status of every configuration of a layout that cannot
+	// enable or disable them
+	Unknown Status = "n/a"
+
+	// The entry of a configuration in the include directory of a symlink
+	// layout can also be wrong; see Repairer.
+
+	// Dangling is a symbolic link to a missing file
+	Dangling Status = "dangling"
+	// ForeignTarget is a symbolic link to a file other than the
+	// configuration
+	ForeignTarget Status = "foreign-target"
+	// UnmanagedCopy is a regular file where a symbolic link belongs
+	UnmanagedCopy Status = "unmanaged-copy"
+)
+
+// Broken reports whether the configuration is enabled wrongly.
+func (s Status) Broken() bool {
+	return s == Dangling || s == ForeignTarget || s == UnmanagedCopy
+}
+
+// Site is a configuration file.
+type Site struct {
+	// Name is the file name of the configuration when enabled, e.g.
+	// "example.conf", whatever it is called while disabled
+	Name   string
+	Path   string
+	Status Status
+	// Link is the entry in the include directory of a symlink layout, ""
+	// if there is none, and Target what it points to as written
+	Link   string
+	Target string
+}
+
+// Layout finds, enables and disables configurations. Enable, Disable and
+// Remove return the changes made, one sentence each, for verbose output.
+type Layout interface {
+	// Name is the name the layout is registered under
+	Name() string
+	// ConfigDir is the directory new configurations are written to
+	ConfigDir() string
+	// IncludeDir is the directory nginx loads configurations from, "" if
+	// unknown
+	IncludeDir() string
+	// List returns every configuration, enabled or not
+	List() ([]Site, error)
+	// Find returns the configuration called name, with or without its
+	// .conf extension
+	Find(name string) (Site, error)
+	Enable(site Site) ([]string, error)
+	Disable(site Site) ([]string, error)
+	// Remove deletes the configuration together with anything that
+	// enables it
+	Remove(site Site) ([]string, error)
+}
+
+// Repairer is implemented by layouts whose configurations can be enabled
+// wrongly, e.g. by a symbolic link to a missing file.
+type Repairer interface {
+	// Repair enables the configuration the way the layout does, or
+	// removes what enables it wrongly, and returns the changes made; none
+	// if there is nothing to repair. With dryRun it only returns them.
+	Repair(site Site, dryRun bool) ([]string, error)
+}
+
+// Options configures a layout; it is the layout section of
+// ~/.ngcli/config.yaml. Empty fields take the defaults of the layout.
+type Options struct {
+	// Name is a registered layout, e.g. debian, conf.d or custom; empty
+	// detects it
+	Name string `yaml:"name,omitempty"`
+	// ConfigDir is where configurations are written, e.g. sites-available
+	ConfigDir string `yaml:"config_dir,omitempty"`
+	// IncludeDir is the directory nginx includes, e.g. sites-enabled
+	IncludeDir string `yaml:"include_dir,omitempty"`
+	// Disable is how conf.d configurations are disabled: rename (the
+	// default) or move
+	Disable string `yaml:"disable,omitempty"`
+	// DisabledDir is where move puts disabled configurations
+	DisabledDir string `yaml:"disabled_dir,omitempty"`
+	// Symlinks is how symbolic links point to configurations: absolute
+	// (the default) or relative to the include directory
+	Symlinks string `yaml:"symlinks,omitempty"`
+}
+
+// Factory creates a layout from its options.
+type Factory func(opts Options) (Layout, error)
+
+var factories = map[string]Factory{}
+
+// Register makes a layout available under name.
+func Register(name string, factory Factory) {
+	factories[name] = factory
+}
+
+// Names returns the registered layouts, sorted.
+func Names() []string {
+	var names []string
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Default locations of the built-in layouts.
+const (
+	sitesAvailable = "/etc/nginx/sites-available"
+	sitesEnabled   = "/etc/nginx/sites-enabled"
+	confD          = "/etc/nginx/conf.d"
+)
+
+func init() {
+	Register("debian", newDebian)
+	Register("conf.d", newConfD)
+	Register("custom", newCustom)
+}
+
+// Where the layout came from, as returned by Resolve.
+const (
+	SourceConfig   = "config"
+	SourceNginx    = "nginx"
+	SourceDetected = "detected"
+	SourceNone     = "none"
+)
+
+// New returns the layout named by opts, or else the one found by Resolve.
+func New(opts Options) (Layout, error) {
+	opts, _ = Resolve(opts)
+	if opts.Name == "" {
+		return newUnknown(opts)
+	}
+
+	factory, ok := factories[opts.Name]
+	if !ok {
+		return nil, fmt.Errorf("unknown layout: %s (available: %s)", opts.Name, strings.Join(Names(), ", "))
+	}
+	return factory(opts)
+}
+
+// Resolve completes opts when they name no layout: with the layout of the
+// include directives of nginx (see Discover), or else with the one
+// detected from the directories that exist. The options set in opts take
+// precedence over discovered ones. It also returns where the layout came
+// from.
+func Resolve(opts Options) (Options, string) {
+	if opts.Name != "" {
+		return opts, SourceConfig
+	}
+
+	if d, err := Discover(false); err == nil && d.Layout.Name != "" {
+		discovered := d.Layout
+		for _, field := range []struct{ from, to *string }{
+			{&opts.ConfigDir, &discovered.ConfigDir},
+			{&opts.IncludeDir, &discovered.IncludeDir},
+			{&opts.Disable, &discovered.Disable},
+			{&opts.DisabledDir, &discovered.DisabledDir},
+			{&opts.Symlinks, &discovered.Symlinks},
+		} {
+			if *field.from != "" {
+				*field.to = *field.from
+			}
+		}
+		return discovered, SourceNginx
+	}
+
+	if opts.Name = Detect(); opts.Name != "" {
+		return opts, SourceDetected
+	}
+	return opts, SourceNone
+}
+
+// Detect returns the layout of this system from the directories that
+// exist: debian where sites-available and sites-enabled exist, otherwise
+// conf.d where that exists, otherwise "".
+func Detect() string {
+	if isDir(sitesAvailable) && isDir(sitesEnabled) {
+		return "debian"
+	}
+	if isDir(confD) {
+		return "conf.d"
+	}
+	return ""
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// unknownLayout is used where no layout is configured or detected:
+// configurations are listed and written, but cannot be enabled or
+// disabled.
+type unknownLayout struct {
+	dir string
+}
+
+func newUnknown(opts Options) (Layout, error) {
+	dir := opts.ConfigDir
+	if dir == "" {
+		var err error
+		if dir, err = utils.DetectNginxConfigPath(); err != nil {
+			return nil, err
+		}
+	}
+	return &unknownLayout{dir: dir}, nil
+}
+
+func (l *unknownLayout) Name() string       { return "unknown" }
+func (l *unknownLayout) ConfigDir() string  { return l.dir }
+func (l *unknownLayout) IncludeDir() string { return "" }
+
+func (l *unknownLayout) List() ([]Site, error) {
+	return listDir(l.dir, Unknown)
+}
+
+func (l *unknownLayout) Find(name string) (Site, error) {
+	return findIn(l.dir, name, Unknown)
+}
+
+func (l *unknownLayout) Enable(site Site) ([]string, error) {
+	return nil, errUnsupported()
+}
+
+func (l *unknownLayout) Disable(site Site) ([]string, error) {
+	return nil, errUnsupported()
+}
+
+func (l *unknownLayout) Remove(site Site) ([]string, error) {
+	return removeFile(site.Path)
+}
+
+func errUnsupported() error {
+	return errcode.New(errcode.Unsupported, "no nginx layout detected (this system may not support enable/disable); set layout in ~/.ngcli/config.yaml")
+}
+
+// listDir returns the configurations in dir, all with status.
+func listDir(dir string, status Status) ([]Site, error) {
+	files, err := filesystem.ListConfigs(dir)
+	if err != nil {
+		return nil, err
+	}
+	sites := make([]Site, 0, len(files))
+	for _, file := range files {
+		sites = append(sites, Site{Name: file, Path: filepath.Join(dir, file), Status: status})
+	}
+	return sites, nil
+}
+
+// findIn returns the configuration called name in dir, with status.
+func findIn(dir, name string, status Status) (Site, error) {
+	path, err := utils.ResolveConfigPath(dir, name)
+	if err != nil {
+		return Site{}, err
+	}
+	return Site{Name: filepath.Base(path), Path: path, Status: status}, nil
+}
+
+func removeFile(path string) ([]string, error) {
+	if err := filesystem.DeleteFile(path); err != nil {
+		return nil, err
+	}
+	return []string{"Deleted file: " + path}, nil
+}
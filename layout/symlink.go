@@ -0,0 +1,326 @@
+package layout
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/vourteen14/ngcli/errcode"
+	"github.com/vourteen14/ngcli/filesystem"
+)
+
+// symlinkLayout keeps configurations in one directory and enables them
+// with symbolic links in the directory nginx includes.
+type symlinkLayout struct {
+	name      string
+	available string
+	enabled   string
+	relative  bool
+}
+
+func newDebian(opts Options) (Layout, error) {
+	l := &symlinkLayout{name: "debian", available: opts.ConfigDir, enabled: opts.IncludeDir}
+	if l.available == "" {
+		l.available = sitesAvailable
+	}
+	if l.enabled == "" {
+		l.enabled = sitesEnabled
+	}
+	return l, l.setSymlinks(opts.Symlinks)
+}
+
+// newCustom links configurations from config_dir into include_dir, or
+// renames them in include_dir as conf.d does when there is no separate
+// config_dir.
+func newCustom(opts Options) (Layout, error) {
+	if opts.IncludeDir == "" {
+		return nil, fmt.Errorf("the custom layout needs include_dir, the directory nginx includes")
+	}
+	if opts.ConfigDir == "" || filepath.Clean(opts.ConfigDir) == filepath.Clean(opts.IncludeDir) {
+		opts.ConfigDir = ""
+		l, err := newConfD(opts)
+		if err != nil {
+			return nil, err
+		}
+		l.(*includeLayout).name = "custom"
+		return l, nil
+	}
+	l := &symlinkLayout{name: "custom", available: opts.ConfigDir, enabled: opts.IncludeDir}
+	return l, l.setSymlinks(opts.Symlinks)
+}
+
+func (l *symlinkLayout) setSymlinks(symlinks string) error {
+	switch symlinks {
+	case "", "absolute":
+	case "relative":
+		l.relative = true
+	default:
+		return fmt.Errorf("unknown symlinks setting: %s (use absolute or relative)", symlinks)
+	}
+	return nil
+}
+
+func (l *symlinkLayout) Name() string       { return l.name }
+func (l *symlinkLayout) ConfigDir() string  { return l.available }
+func (l *symlinkLayout) IncludeDir() string { return l.enabled }
+
+// List returns the configurations, and the entries of the include
+// directory left without one, such as links to deleted configurations.
+func (l *symlinkLayout) List() ([]Site, error) {
+	sites, err := listDir(l.available, Disabled)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, site := range sites {
+		seen[site.Name] = true
+	}
+
+	if isDir(l.enabled) {
+		entries, err := filesystem.ListConfigs(l.enabled)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range entries {
+			if !seen[name] {
+				sites = append(sites, Site{Name: name, Path: filepath.Join(l.enabled, name)})
+			}
+		}
+	}
+
+	for i := range sites {
+		l.inspect(&sites[i])
+	}
+	sort.SliceStable(sites, func(i, j int) bool { return sites[i].Name < sites[j].Name })
+	return sites, nil
+}
+
+func (l *symlinkLayout) Find(name string) (Site, error) {
+	site, err := findIn(l.available, name, Disabled)
+	if err != nil {
+		// A link or copy can be left without a configuration
+		found := false
+		for _, file := range []string{name, name + ".conf"} {
+			path := filepath.Join(l.enabled, file)
+			if _, lerr := os.Lstat(path); lerr == nil {
+				site, found = Site{Name: file, Path: path}, true
+				break
+			}
+		}
+		if !found {
+			return Site{}, err
+		}
+	}
+	l.inspect(&site)
+	return site, nil
+}
+
+// inspect sets the status of the configuration from its entry in the
+// include directory: enabled if that is a symbolic link to it.
+func (l *symlinkLayout) inspect(site *Site) {
+	site.Link, site.Target = "", ""
+	link := filepath.Join(l.enabled, site.Name)
+	info, err := os.Lstat(link)
+	if err != nil {
+		site.Status = Disabled
+		return
+	}
+	site.Link = link
+	if info.Mode()&os.ModeSymlink == 0 {
+		site.Status = UnmanagedCopy
+		return
+	}
+
+	site.Target, _ = os.Readlink(link)
+	target, err := os.Stat(link)
+	if err != nil {
+		site.Status = Dangling
+		return
+	}
+	available, err := os.Stat(l.path(site.Name))
+	if err == nil && os.SameFile(target, available) {
+		site.Status = Enabled
+	} else {
+		site.Status = ForeignTarget
+	}
+}
+
+// path returns where the configuration file named file belongs.
+func (l *symlinkLayout) path(file string) string {
+	return filepath.Join(l.available, file)
+}
+
+func (l *symlinkLayout) Enable(site Site) ([]string, error) {
+	l.inspect(&site)
+	if site.Status == UnmanagedCopy {
+		return nil, errUnmanagedCopy(site)
+	}
+	if site.Path != l.path(site.Name) {
+		return nil, errcode.Errorf(errcode.ConfigNotFound, "configuration file not found: %s", l.path(site.Name))
+	}
+	return l.link(site)
+}
+
+func (l *symlinkLayout) link(site Site) ([]string, error) {
+	link := filepath.Join(l.enabled, site.Name)
+	if err := filesystem.CreateSymlink(site.Path, link, l.relative); err != nil {
+		return nil, err
+	}
+	target, _ := os.Readlink(link)
+	return []string{fmt.Sprintf("Created symlink: %s -> %s", link, target)}, nil
+}
+
+func (l *symlinkLayout) Disable(site Site) ([]string, error) {
+	l.inspect(&site)
+	switch site.Status {
+	case Disabled:
+		return nil, errcode.Errorf(errcode.ConfigNotEnabled, "configuration not enabled: %s", site.Name)
+	case UnmanagedCopy:
+		return nil, errUnmanagedCopy(site)
+	}
+	if err := filesystem.RemoveSymlink(site.Link); err != nil {
+		return nil, err
+	}
+	return []string{"Removed symlink: " + site.Link}, nil
+}
+
+func (l *symlinkLayout) Remove(site Site) ([]string, error) {
+	var changes []string
+	l.inspect(&site)
+	switch site.Status {
+	case Disabled:
+	case UnmanagedCopy:
+		removed, err := removeFile(site.Link)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, removed...)
+	default:
+		if err := filesystem.RemoveSymlink(site.Link); err != nil {
+			return nil, err
+		}
+		changes = append(changes, "Removed symlink: "+site.Link)
+	}
+	if site.Path == site.Link {
+		return changes, nil
+	}
+	removed, err := removeFile(site.Path)
+	return append(changes, removed...), err
+}
+
+// Repair makes the entry in the include directory a symbolic link to the
+// configuration, written as configured:
+//
+//   - a dangling link is linked to the configuration again, or removed
+//     if there is none
+//   - a link to another file is linked to the configuration; without one
+//     it is left alone, as it may be deliberate
+//   - a copy is what nginx loads, so it replaces the configuration, which
+//     is backed up if it differs, and is linked
+//   - a correct link is rewritten if it is absolute and links should be
+//     relative, or the other way round
+func (l *symlinkLayout) Repair(site Site, dryRun bool) ([]string, error) {
+	l.inspect(&site)
+	path := l.path(site.Name)
+	hasConfig := site.Path == path
+
+	switch {
+	case site.Status == Dangling && !hasConfig:
+		if dryRun {
+			return []string{"Would remove symlink: " + site.Link}, nil
+		}
+		if err := filesystem.RemoveSymlink(site.Link); err != nil {
+			return nil, err
+		}
+		return []string{"Removed symlink: " + site.Link}, nil
+
+	case site.Status == ForeignTarget && !hasConfig:
+		return nil, fmt.Errorf("%s links to %s, not to a configuration in %s; remove the link or add %s", site.Link, site.Target, l.available, path)
+
+	case site.Status == UnmanagedCopy:
+		return l.adopt(site, path, dryRun)
+
+	case site.Status == Dangling, site.Status == ForeignTarget,
+		site.Status == Enabled && filepath.IsAbs(site.Target) == l.relative:
+		if dryRun {
+			return []string{fmt.Sprintf("Would link %s to %s", site.Link, path)}, nil
+		}
+		if err := filesystem.RemoveSymlink(site.Link); err != nil {
+			return nil, err
+		}
+		return l.link(Site{Name: site.Name, Path: path})
+	}
+	return nil, nil
+}
+
+// adopt moves a copy in the include directory to path, where the
+// configuration belongs, and links it.
+func (l *symlinkLayout) adopt(site Site, path string, dryRun bool) ([]string, error) {
+	copied, err := os.ReadFile(site.Link)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", site.Link, err)
+	}
+	existing, err := os.ReadFile(path)
+	same := err == nil && bytes.Equal(existing, copied)
+
+	var changes []string
+	if dryRun {
+		if !same {
+			changes = append(changes, fmt.Sprintf("Would move %s to %s", site.Link, path))
+		}
+		return append(changes, fmt.Sprintf("Would link %s to %s", site.Link, path)), nil
+	}
+
+	if same {
+		if err := filesystem.DeleteFile(site.Link); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := filesystem.BackupFile(path); err != nil {
+			return nil, err
+		}
+		if err := moveFile(site.Link, path); err != nil {
+			return nil, err
+		}
+		changes = append(changes, fmt.Sprintf("Moved: %s -> %s", site.Link, path))
+	}
+	linked, err := l.link(Site{Name: site.Name, Path: path})
+	return append(changes, linked...), err
+}
+
+// moveFile renames src to dst. Across filesystems it copies src, keeping
+// its mode, as the copy may hold secrets, and removes it.
+func moveFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(dst), err)
+	}
+	err := os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, syscall.EXDEV) {
+		return fmt.Errorf("failed to move %s to %s: %w", src, dst, err)
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", src, err)
+	}
+	content, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", src, err)
+	}
+	if err := filesystem.WriteFileMode(dst, string(content), info.Mode().Perm(), true); err != nil {
+		return err
+	}
+	return filesystem.DeleteFile(src)
+}
+
+func errUnmanagedCopy(site Site) error {
+	return fmt.Errorf("%s is a copy, not a symbolic link; run 'ngcli repair %s' to replace it with one", site.Link, strings.TrimSuffix(site.Name, ".conf"))
+}
@@ -0,0 +1,235 @@
+package layout
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// entry is a file or symbolic link in a test tree.
+type entry struct {
+	content string
+	mode    os.FileMode
+	target  string
+}
+
+// snapshot returns the files and links below root by relative path.
+func snapshot(t *testing.T, root string) map[string]entry {
+	t.Helper()
+	tree := make(map[string]entry)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(path)
+			tree[rel] = entry{target: target}
+			return err
+		}
+		content, err := os.ReadFile(path)
+		tree[rel] = entry{content: string(content), mode: info.Mode().Perm()}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+func writeFile(t *testing.T, path, content string, mode os.FileMode) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		t.Fatal(err)
+	}
+	// WriteFile is subject to the umask
+	if err := os.Chmod(path, mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func symlink(t *testing.T, target, link string) {
+	t.Helper()
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSymlinkRepair(t *testing.T) {
+	tests := []struct {
+		name   string
+		setup  func(t *testing.T, available, enabled, root string)
+		status Status
+		// check inspects the tree after the repair
+		check   func(t *testing.T, available, enabled string)
+		wantErr string
+	}{
+		{
+			name: "dangling link with a configuration",
+			setup: func(t *testing.T, available, enabled, root string) {
+				writeFile(t, filepath.Join(available, "site.conf"), "server {}\n", 0644)
+				symlink(t, filepath.Join(root, "old", "site.conf"), filepath.Join(enabled, "site.conf"))
+			},
+			status: Dangling,
+			check: func(t *testing.T, available, enabled string) {
+				assertLink(t, filepath.Join(enabled, "site.conf"), filepath.Join(available, "site.conf"))
+			},
+		},
+		{
+			name: "dangling link without a configuration",
+			setup: func(t *testing.T, available, enabled, root string) {
+				symlink(t, filepath.Join(root, "old", "site.conf"), filepath.Join(enabled, "site.conf"))
+			},
+			status: Dangling,
+			check: func(t *testing.T, available, enabled string) {
+				if _, err := os.Lstat(filepath.Join(enabled, "site.conf")); !os.IsNotExist(err) {
+					t.Errorf("dangling link not removed: %v", err)
+				}
+			},
+		},
+		{
+			name: "link to another file with a configuration",
+			setup: func(t *testing.T, available, enabled, root string) {
+				writeFile(t, filepath.Join(available, "site.conf"), "server {}\n", 0644)
+				writeFile(t, filepath.Join(root, "other.conf"), "server { listen 81; }\n", 0644)
+				symlink(t, filepath.Join(root, "other.conf"), filepath.Join(enabled, "site.conf"))
+			},
+			status: ForeignTarget,
+			check: func(t *testing.T, available, enabled string) {
+				assertLink(t, filepath.Join(enabled, "site.conf"), filepath.Join(available, "site.conf"))
+			},
+		},
+		{
+			name: "link to another file without a configuration",
+			setup: func(t *testing.T, available, enabled, root string) {
+				writeFile(t, filepath.Join(root, "other.conf"), "server { listen 81; }\n", 0644)
+				symlink(t, filepath.Join(root, "other.conf"), filepath.Join(enabled, "site.conf"))
+			},
+			status:  ForeignTarget,
+			wantErr: "not to a configuration",
+		},
+		{
+			name: "copy without a configuration",
+			setup: func(t *testing.T, available, enabled, root string) {
+				writeFile(t, filepath.Join(enabled, "site.conf"), "server { listen 80; }\n", 0640)
+			},
+			status: UnmanagedCopy,
+			check: func(t *testing.T, available, enabled string) {
+				assertFile(t, filepath.Join(available, "site.conf"), "server { listen 80; }\n", 0640)
+				assertLink(t, filepath.Join(enabled, "site.conf"), filepath.Join(available, "site.conf"))
+			},
+		},
+		{
+			name: "copy differing from the configuration",
+			setup: func(t *testing.T, available, enabled, root string) {
+				writeFile(t, filepath.Join(available, "site.conf"), "server { listen 80; }\n", 0644)
+				writeFile(t, filepath.Join(enabled, "site.conf"), "server { listen 8080; }\n", 0600)
+			},
+			status: UnmanagedCopy,
+			check: func(t *testing.T, available, enabled string) {
+				assertFile(t, filepath.Join(available, "site.conf"), "server { listen 8080; }\n", 0600)
+				assertLink(t, filepath.Join(enabled, "site.conf"), filepath.Join(available, "site.conf"))
+				backups, _ := filepath.Glob(filepath.Join(available, "site.conf.backup-*"))
+				if len(backups) != 1 {
+					t.Fatalf("backups = %v, want one", backups)
+				}
+				assertFile(t, backups[0], "server { listen 80; }\n", 0644)
+			},
+		},
+		{
+			name: "copy equal to the configuration",
+			setup: func(t *testing.T, available, enabled, root string) {
+				writeFile(t, filepath.Join(available, "site.conf"), "server {}\n", 0644)
+				writeFile(t, filepath.Join(enabled, "site.conf"), "server {}\n", 0644)
+			},
+			status: UnmanagedCopy,
+			check: func(t *testing.T, available, enabled string) {
+				assertFile(t, filepath.Join(available, "site.conf"), "server {}\n", 0644)
+				assertLink(t, filepath.Join(enabled, "site.conf"), filepath.Join(available, "site.conf"))
+				if backups, _ := filepath.Glob(filepath.Join(available, "site.conf.backup-*")); len(backups) != 0 {
+					t.Errorf("backups = %v, want none", backups)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, dryRun := range []bool{true, false} {
+			name := tt.name
+			if dryRun {
+				name += " (dry run)"
+			}
+			t.Run(name, func(t *testing.T) {
+				root := t.TempDir()
+				available := filepath.Join(root, "sites-available")
+				enabled := filepath.Join(root, "sites-enabled")
+				for _, dir := range []string{available, enabled} {
+					if err := os.Mkdir(dir, 0755); err != nil {
+						t.Fatal(err)
+					}
+				}
+				tt.setup(t, available, enabled, root)
+
+				l, err := newDebian(Options{ConfigDir: available, IncludeDir: enabled})
+				if err != nil {
+					t.Fatal(err)
+				}
+				site, err := l.Find("site")
+				if err != nil {
+					t.Fatalf("Find: %v", err)
+				}
+				if site.Status != tt.status {
+					t.Fatalf("status = %s, want %s", site.Status, tt.status)
+				}
+
+				before := snapshot(t, root)
+				changes, err := l.(Repairer).Repair(site, dryRun)
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Fatalf("Repair = %v, want an error containing %q", err, tt.wantErr)
+					}
+				} else if err != nil || len(changes) == 0 {
+					t.Fatalf("Repair = %q, %v; want changes", changes, err)
+				}
+
+				if dryRun || tt.wantErr != "" {
+					if after := snapshot(t, root); !reflect.DeepEqual(before, after) {
+						t.Errorf("tree changed:\nbefore %v\nafter  %v", before, after)
+					}
+					return
+				}
+				tt.check(t, available, enabled)
+			})
+		}
+	}
+}
+
+func assertLink(t *testing.T, link, want string) {
+	t.Helper()
+	target, err := os.Readlink(link)
+	if err != nil {
+		t.Fatalf("%s is not a link: %v", link, err)
+	}
+	if target != want {
+		t.Errorf("%s -> %s, want %s", link, target, want)
+	}
+}
+
+func assertFile(t *testing.T, path, content string, mode os.FileMode) {
+	t.Helper()
+	info, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Mode().IsRegular() {
+		t.Fatalf("%s is not a regular file", path)
+	}
+	if info.Mode().Perm() != mode {
+		t.Errorf("mode of %s = %o, want %o", path, info.Mode().Perm(), mode)
+	}
+	if data, _ := os.ReadFile(path); string(data) != content {
+		t.Errorf("%s = %q, want %q", path, data, content)
+	}
+}
@@ -0,0 +1,103 @@
+package nginxconf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Problem is a mistake found by Lint.
+type Problem struct {
+	Line    int
+	Message string
+}
+
+// minArgs is the number of arguments common directives need at least.
+var minArgs = map[string]int{
+	"server_name":         1,
+	"listen":              1,
+	"root":                1,
+	"alias":               1,
+	"index":               1,
+	"include":             1,
+	"proxy_pass":          1,
+	"grpc_pass":           1,
+	"fastcgi_pass":        1,
+	"ssl_certificate":     1,
+	"ssl_certificate_key": 1,
+	"access_log":          1,
+	"error_log":           1,
+	"return":              1,
+	"try_files":           2,
+	"rewrite":             2,
+	"add_header":          2,
+	"proxy_set_header":    2,
+	"location":            1,
+	"upstream":            1,
+}
+
+// single are directives nginx rejects as "duplicate" within one block.
+var single = map[string]bool{
+	"root":                 true,
+	"alias":                true,
+	"proxy_pass":           true,
+	"grpc_pass":            true,
+	"fastcgi_pass":         true,
+	"ssl_certificate_key":  true,
+	"client_max_body_size": true,
+	"try_files":            true,
+}
+
+// Lint checks configuration text for mistakes nginx would reject or that
+// usually come from a template rendered with missing values: syntax errors,
+// "<no value>", directives with too few arguments or empty arguments, and
+// duplicate directives.
+func Lint(content string) []Problem {
+	var problems []Problem
+
+	for i, line := range strings.Split(content, "\n") {
+		if strings.Contains(line, "<no value>") && !strings.HasPrefix(strings.TrimSpace(line), "#") {
+			problems = append(problems, Problem{Line: i + 1, Message: `"<no value>" rendered from a missing parameter`})
+		}
+	}
+
+	directives, err := Parse(content)
+	if err != nil {
+		return append(problems, Problem{Message: err.Error()})
+	}
+
+	Walk(directives, func(d *Directive, parents []string) {
+		if need, known := minArgs[d.Name]; known && len(d.Args) < need {
+			problems = append(problems, Problem{Line: d.Line, Message: fmt.Sprintf("%s needs at least %d argument(s), has %d", d.Name, need, len(d.Args))})
+		}
+		// Map and geo blocks use '' as a key, and headers may be set empty
+		for _, arg := range d.Args {
+			if _, known := minArgs[d.Name]; known && arg.Quoted && arg.Value == "" && d.Name != "add_header" && d.Name != "proxy_set_header" {
+				problems = append(problems, Problem{Line: arg.Line, Message: fmt.Sprintf("%s has an empty argument", d.Name)})
+			}
+		}
+		if d.HasBlock {
+			problems = append(problems, duplicates(d.Block)...)
+		}
+	})
+	problems = append(problems, duplicates(directives)...)
+
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Line < problems[j].Line })
+	return problems
+}
+
+func duplicates(block []*Directive) []Problem {
+	var problems []Problem
+	seen := make(map[string]int)
+	for _, d := range block {
+		if !single[d.Name] {
+			continue
+		}
+		if first, exists := seen[d.Name]; exists {
+			problems = append(problems, Problem{Line: d.Line, Message: fmt.Sprintf("%s is duplicate (first on line %d)", d.Name, first)})
+			continue
+		}
+		seen[d.Name] = d.Line
+	}
+	return problems
+}
@@ -0,0 +1,217 @@
+package nginxconf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Token is a directive name or argument. Start and End are byte offsets of
+// the value in the source; for quoted arguments they exclude the quotes.
+type Token struct {
+	Value  string
+	Start  int
+	End    int
+	Line   int
+	Quoted bool
+}
+
+// Directive is a simple ("listen 80;") or block ("server { ... }") directive.
+type Directive struct {
+	Name     string
+	Args     []Token
+	Block    []*Directive
+	HasBlock bool
+	Line     int
+	Start    int
+}
+
+// Parse parses nginx configuration text into a directive tree. Comments are
+// skipped; byte offsets are kept so callers can rewrite the source exactly.
+func Parse(content string) ([]*Directive, error) {
+	p := &parser{lex: &lexer{input: content, line: 1}}
+
+	directives, err := p.parseBlock(false)
+	if err != nil {
+		return nil, err
+	}
+
+	return directives, nil
+}
+
+// Walk calls fn for every directive in depth-first order, together with the
+// names of the enclosing blocks (outermost first).
+func Walk(directives []*Directive, fn func(d *Directive, parents []string)) {
+	walk(directives, nil, fn)
+}
+
+func walk(directives []*Directive, parents []string, fn func(d *Directive, parents []string)) {
+	for _, d := range directives {
+		fn(d, parents)
+		if d.HasBlock {
+			walk(d.Block, append(parents, d.Name), fn)
+		}
+	}
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenSemicolon
+	tokenOpen
+	tokenClose
+	tokenEOF
+)
+
+type lexer struct {
+	input string
+	pos   int
+	line  int
+}
+
+func (l *lexer) next() (tokenKind, Token, error) {
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		switch {
+		case c == '\n':
+			l.line++
+			l.pos++
+		case c == ' ' || c == '\t' || c == '\r':
+			l.pos++
+		case c == '#':
+			for l.pos < len(l.input) && l.input[l.pos] != '\n' {
+				l.pos++
+			}
+		default:
+			return l.token()
+		}
+	}
+
+	return tokenEOF, Token{Start: l.pos, End: l.pos, Line: l.line}, nil
+}
+
+func (l *lexer) token() (tokenKind, Token, error) {
+	start := l.pos
+	c := l.input[l.pos]
+
+	switch c {
+	case ';':
+		l.pos++
+		return tokenSemicolon, Token{Value: ";", Start: start, End: l.pos, Line: l.line}, nil
+	case '{':
+		l.pos++
+		return tokenOpen, Token{Value: "{", Start: start, End: l.pos, Line: l.line}, nil
+	case '}':
+		l.pos++
+		return tokenClose, Token{Value: "}", Start: start, End: l.pos, Line: l.line}, nil
+	case '"', '\'':
+		line := l.line
+		l.pos++
+		var value strings.Builder
+		for l.pos < len(l.input) {
+			ch := l.input[l.pos]
+			if ch == '\\' && l.pos+1 < len(l.input) {
+				value.WriteByte(l.input[l.pos+1])
+				l.pos += 2
+				continue
+			}
+			if ch == c {
+				tok := Token{Value: value.String(), Start: start + 1, End: l.pos, Line: line, Quoted: true}
+				l.pos++
+				return tokenWord, tok, nil
+			}
+			if ch == '\n' {
+				l.line++
+			}
+			value.WriteByte(ch)
+			l.pos++
+		}
+		return tokenEOF, Token{}, fmt.Errorf("line %d: unterminated quoted string", line)
+	}
+
+	for l.pos < len(l.input) {
+		ch := l.input[l.pos]
+		if ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n' || ch == ';' || ch == '}' {
+			break
+		}
+		if ch == '{' {
+			// "${var}" is part of a word, a bare "{" opens a block
+			if l.pos > start && l.input[l.pos-1] == '$' {
+				end := strings.IndexByte(l.input[l.pos:], '}')
+				if end < 0 {
+					return tokenEOF, Token{}, fmt.Errorf("line %d: unterminated variable", l.line)
+				}
+				l.pos += end + 1
+				continue
+			}
+			break
+		}
+		if ch == '\\' && l.pos+1 < len(l.input) {
+			l.pos += 2
+			continue
+		}
+		l.pos++
+	}
+
+	return tokenWord, Token{Value: l.input[start:l.pos], Start: start, End: l.pos, Line: l.line}, nil
+}
+
+type parser struct {
+	lex *lexer
+}
+
+func (p *parser) parseBlock(nested bool) ([]*Directive, error) {
+	var directives []*Directive
+
+	for {
+		kind, tok, err := p.lex.next()
+		if err != nil {
+			return nil, err
+		}
+
+		switch kind {
+		case tokenEOF:
+			if nested {
+				return nil, fmt.Errorf("line %d: unexpected end of file, expecting \"}\"", tok.Line)
+			}
+			return directives, nil
+		case tokenClose:
+			if !nested {
+				return nil, fmt.Errorf("line %d: unexpected \"}\"", tok.Line)
+			}
+			return directives, nil
+		case tokenSemicolon, tokenOpen:
+			return nil, fmt.Errorf("line %d: unexpected %q", tok.Line, tok.Value)
+		}
+
+		d := &Directive{Name: tok.Value, Line: tok.Line, Start: tok.Start}
+
+	args:
+		for {
+			kind, arg, err := p.lex.next()
+			if err != nil {
+				return nil, err
+			}
+
+			switch kind {
+			case tokenWord:
+				d.Args = append(d.Args, arg)
+			case tokenSemicolon:
+				break args
+			case tokenOpen:
+				d.HasBlock = true
+				d.Block, err = p.parseBlock(true)
+				if err != nil {
+					return nil, err
+				}
+				break args
+			case tokenClose:
+				return nil, fmt.Errorf("line %d: unexpected \"}\", directive %q is not terminated by \";\"", arg.Line, d.Name)
+			case tokenEOF:
+				return nil, fmt.Errorf("line %d: unexpected end of file, directive %q is not terminated by \";\"", d.Line, d.Name)
+			}
+		}
+
+		directives = append(directives, d)
+	}
+}
@@ -0,0 +1,104 @@
+// Package output prints the results of read commands in the format chosen
+// with the global -o/--output flag.
+//
+// JSON and YAML output is versioned: every document starts with apiVersion
+// and kind, and fields are only added within an apiVersion. Removing or
+// changing a field means a new apiVersion.
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v2"
+)
+
+// APIVersion is the version of the JSON and YAML output schema.
+const APIVersion = "ngcli/v1"
+
+// Output formats.
+const (
+	Table = "table"
+	Wide  = "wide"
+	JSON  = "json"
+	YAML  = "yaml"
+	Name  = "name"
+)
+
+// Formats lists the supported output formats.
+var Formats = []string{Table, Wide, JSON, YAML, Name}
+
+// TypeMeta starts every JSON and YAML document.
+type TypeMeta struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+}
+
+func typeMeta(kind string) TypeMeta {
+	return TypeMeta{APIVersion: APIVersion, Kind: kind}
+}
+
+// Result is the typed result of a read command.
+type Result interface {
+	// Names returns what -o name prints, one per line.
+	Names() []string
+}
+
+// Validate checks that format is a supported output format.
+func Validate(format string) error {
+	for _, supported := range Formats {
+		if format == supported {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported output format: %s (use table, wide, json, yaml or name)", format)
+}
+
+// Structured reports whether format is printed by Write rather than as
+// the human-readable table of a command.
+func Structured(format string) bool {
+	return format == JSON || format == YAML || format == Name
+}
+
+// Write prints result as JSON, YAML or names.
+func Write(w io.Writer, format string, result Result) error {
+	switch format {
+	case JSON:
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode output: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case YAML:
+		data, err := toYAML(result)
+		if err != nil {
+			return fmt.Errorf("failed to encode output: %w", err)
+		}
+		_, err = w.Write(data)
+		return err
+	case Name:
+		for _, name := range result.Names() {
+			if _, err := fmt.Fprintln(w, name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return Validate(format)
+}
+
+// toYAML encodes result through its JSON form, so that YAML output has
+// exactly the fields, names and order of the JSON output.
+func toYAML(result Result) ([]byte, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(doc)
+}
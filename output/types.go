@@ -0,0 +1,277 @@
+package output
+
+import (
+	"time"
+
+	"github.com/vourteen14/ngcli/errcode"
+	"github.com/vourteen14/ngcli/template"
+)
+
+// Config is an nginx configuration in the output directory.
+type Config struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	// Status is enabled, disabled, or n/a where configurations cannot be
+	// enabled separately. Enabled is null in the last case. Symlink
+	// layouts also report the entries of the include directory that
+	// 'ngcli repair' fixes: dangling, foreign-target and unmanaged-copy;
+	// Enabled is false for them.
+	Status  string `json:"status"`
+	Enabled *bool  `json:"enabled"`
+	// Link is the entry in the include directory, and Target what it
+	// points to
+	Link   string `json:"link,omitempty"`
+	Target string `json:"target,omitempty"`
+	// Managed configurations were written by 'ngcli generate', which
+	// recorded the template and the files generated with it.
+	Managed         bool       `json:"managed"`
+	Template        string     `json:"template,omitempty"`
+	TemplateVersion string     `json:"template_version,omitempty"`
+	GeneratedAt     *time.Time `json:"generated_at,omitempty"`
+	ServerNames     []string   `json:"server_names"`
+	Files           []string   `json:"files,omitempty"`
+}
+
+// ConfigList is the result of 'ngcli list'.
+type ConfigList struct {
+	TypeMeta
+	Directory string   `json:"directory"`
+	Items     []Config `json:"items"`
+}
+
+// NewConfigList returns a ConfigList of items found in dir.
+func NewConfigList(dir string, items []Config) *ConfigList {
+	if items == nil {
+		items = []Config{}
+	}
+	return &ConfigList{TypeMeta: typeMeta("ConfigList"), Directory: dir, Items: items}
+}
+
+// Names implements Result.
+func (l *ConfigList) Names() []string {
+	var names []string
+	for _, item := range l.Items {
+		names = append(names, item.Name)
+	}
+	return names
+}
+
+// ConfigDetail is the result of 'ngcli show'.
+type ConfigDetail struct {
+	TypeMeta
+	Config
+	Content string `json:"content"`
+}
+
+// NewConfigDetail returns the ConfigDetail of config.
+func NewConfigDetail(config Config, content string) *ConfigDetail {
+	return &ConfigDetail{TypeMeta: typeMeta("Config"), Config: config, Content: content}
+}
+
+// Names implements Result.
+func (d *ConfigDetail) Names() []string {
+	return []string{d.Name}
+}
+
+// Template is a template in the template directory. Type is built-in,
+// modified (a changed copy of a built-in template), package or custom.
+// Error is set for templates that fail to parse.
+type Template struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	File        string `json:"file"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version,omitempty"`
+	// UpdateAvailable is set for built-in templates with upstream changes.
+	UpdateAvailable bool   `json:"update_available,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// TemplateList is the result of 'ngcli template list'.
+type TemplateList struct {
+	TypeMeta
+	Directory string     `json:"directory"`
+	Items     []Template `json:"items"`
+}
+
+// NewTemplateList returns a TemplateList of items found in dir.
+func NewTemplateList(dir string, items []Template) *TemplateList {
+	if items == nil {
+		items = []Template{}
+	}
+	return &TemplateList{TypeMeta: typeMeta("TemplateList"), Directory: dir, Items: items}
+}
+
+// Names implements Result.
+func (l *TemplateList) Names() []string {
+	var names []string
+	for _, item := range l.Items {
+		names = append(names, item.Name)
+	}
+	return names
+}
+
+// TemplateDetail is the result of 'ngcli template show'. Content is left
+// out with --params.
+type TemplateDetail struct {
+	TypeMeta
+	Template
+	Author     string                  `json:"author,omitempty"`
+	Parameters []template.ParameterDoc `json:"parameters"`
+	Rules      []string                `json:"rules,omitempty"`
+	Content    string                  `json:"content,omitempty"`
+}
+
+// NewTemplateDetail returns the TemplateDetail of tmpl.
+func NewTemplateDetail(tmpl Template, author string, parameters []template.ParameterDoc, rules []string, content string) *TemplateDetail {
+	if parameters == nil {
+		parameters = []template.ParameterDoc{}
+	}
+	return &TemplateDetail{
+		TypeMeta:   typeMeta("Template"),
+		Template:   tmpl,
+		Author:     author,
+		Parameters: parameters,
+		Rules:      rules,
+		Content:    content,
+	}
+}
+
+// Names implements Result.
+func (d *TemplateDetail) Names() []string {
+	return []string{d.Name}
+}
+
+// ErrorReport is printed to stderr for a failed command with
+// --error-format json.
+type ErrorReport struct {
+	TypeMeta
+	Code     errcode.Code    `json:"code"`
+	ExitCode int             `json:"exit_code"`
+	Message  string          `json:"message"`
+	Fields   []errcode.Field `json:"fields,omitempty"`
+}
+
+// NewErrorReport describes err.
+func NewErrorReport(err error) *ErrorReport {
+	code := errcode.CodeOf(err)
+	return &ErrorReport{
+		TypeMeta: typeMeta("Error"),
+		Code:     code,
+		ExitCode: code.ExitCode(),
+		Message:  err.Error(),
+		Fields:   errcode.FieldsOf(err),
+	}
+}
+
+// Names implements Result.
+func (r *ErrorReport) Names() []string {
+	return []string{string(r.Code)}
+}
+
+// Info is the result of 'ngcli info'. Nginx is null where nginx is not
+// installed.
+type Info struct {
+	TypeMeta
+	Nginx       *Nginx `json:"nginx"`
+	Layout      Layout `json:"layout"`
+	TemplateDir string `json:"template_dir"`
+	Cache       string `json:"cache"`
+}
+
+// Nginx is the nginx installation found with 'nginx -V'. Source is how its
+// configuration was read: files, or nginx -T.
+type Nginx struct {
+	Binary       string         `json:"binary"`
+	Version      string         `json:"version"`
+	Prefix       string         `json:"prefix"`
+	ConfPath     string         `json:"conf_path"`
+	Source       string         `json:"source"`
+	Includes     []NginxInclude `json:"includes"`
+	Problems     []string       `json:"problems,omitempty"`
+	DiscoveredAt time.Time      `json:"discovered_at"`
+}
+
+// NginxInclude is an include directive of the nginx configuration.
+type NginxInclude struct {
+	Pattern string `json:"pattern"`
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Context string `json:"context"`
+}
+
+// Layout is how configurations are enabled. Source is config, nginx (the
+// includes of nginx.conf), detected (the directories that exist) or none.
+type Layout struct {
+	Name       string `json:"name"`
+	Source     string `json:"source"`
+	ConfigDir  string `json:"config_dir,omitempty"`
+	IncludeDir string `json:"include_dir,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// NewInfo returns the Info of an installation.
+func NewInfo(nginx *Nginx, layout Layout, templateDir, cache string) *Info {
+	if nginx != nil && nginx.Includes == nil {
+		nginx.Includes = []NginxInclude{}
+	}
+	return &Info{TypeMeta: typeMeta("Info"), Nginx: nginx, Layout: layout, TemplateDir: templateDir, Cache: cache}
+}
+
+// Names implements Result.
+func (i *Info) Names() []string {
+	return []string{i.Layout.Name}
+}
+
+// Check statuses.
+const (
+	Pass = "pass"
+	Warn = "warn"
+	Fail = "fail"
+)
+
+// Check is one check of 'ngcli doctor'. Hint says how to fix a warning or
+// failure; Details lists what the check found, e.g. the files concerned.
+type Check struct {
+	Name    string   `json:"name"`
+	Status  string   `json:"status"`
+	Message string   `json:"message"`
+	Hint    string   `json:"hint,omitempty"`
+	Details []string `json:"details,omitempty"`
+}
+
+// Doctor is the result of 'ngcli doctor'.
+type Doctor struct {
+	TypeMeta
+	Checks   []Check `json:"checks"`
+	Passed   int     `json:"passed"`
+	Warnings int     `json:"warnings"`
+	Failures int     `json:"failures"`
+}
+
+// NewDoctor returns the Doctor result of checks.
+func NewDoctor(checks []Check) *Doctor {
+	d := &Doctor{TypeMeta: typeMeta("Doctor"), Checks: checks}
+	for _, check := range checks {
+		switch check.Status {
+		case Pass:
+			d.Passed++
+		case Warn:
+			d.Warnings++
+		case Fail:
+			d.Failures++
+		}
+	}
+	return d
+}
+
+// Names implements Result; it returns the checks that did not pass.
+func (d *Doctor) Names() []string {
+	var names []string
+	for _, check := range d.Checks {
+		if check.Status != Pass {
+			names = append(names, check.Name)
+		}
+	}
+	return names
+}
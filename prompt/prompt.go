@@ -0,0 +1,159 @@
+// Package prompt asks the user questions on the terminal. Input is only
+// read when it can be: stdin must be a terminal, and neither --no-input
+// nor NGCLI_NONINTERACTIVE may be set. Otherwise a question fails with an
+// InputRequired error naming the flag that answers it, instead of quietly
+// taking a default.
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/vourteen14/ngcli/errcode"
+)
+
+// EnvNonInteractive disables all prompts when set to anything but "",
+// "0" or "false".
+const EnvNonInteractive = "NGCLI_NONINTERACTIVE"
+
+var (
+	assumeYes bool
+	noInput   bool
+)
+
+// Configure sets the global --yes and --no-input flags. With yes, every
+// confirmation is answered yes without asking.
+func Configure(yes, disableInput bool) {
+	assumeYes = yes
+	noInput = disableInput
+}
+
+// Interactive reports whether questions can be asked.
+func Interactive() bool {
+	return unavailable() == ""
+}
+
+// unavailable returns why questions cannot be asked, or "" if they can.
+func unavailable() string {
+	if noInput {
+		return "--no-input is set"
+	}
+	switch strings.ToLower(os.Getenv(EnvNonInteractive)) {
+	case "", "0", "false":
+	default:
+		return EnvNonInteractive + " is set"
+	}
+	if !IsTerminal(os.Stdin) {
+		return "stdin is not a terminal"
+	}
+	return ""
+}
+
+// IsTerminal reports whether f is a terminal. /dev/null, which CI systems
+// often connect to stdin, is a character device too.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	if null, err := os.Stat(os.DevNull); err == nil && os.SameFile(info, null) {
+		return false
+	}
+	return true
+}
+
+// Required returns the error for a question that cannot be asked; hint
+// says how to answer it without a prompt, e.g. "use --set domain=...".
+func Required(what, hint string) error {
+	return errcode.Errorf(errcode.InputRequired, "%s needs input, but %s; %s", what, unavailable(), hint)
+}
+
+// Confirm asks a yes/no question; an empty answer is def. With --yes it
+// returns true without asking. hint says how to answer without a prompt,
+// for the error returned when the question cannot be asked.
+func Confirm(question string, def bool, hint string) (bool, error) {
+	if assumeYes {
+		return true, nil
+	}
+	if !Interactive() {
+		return false, Required(subject(question), hint)
+	}
+
+	choices := "(y/N)"
+	if def {
+		choices = "(Y/n)"
+	}
+	fmt.Printf("%s %s: ", question, choices)
+
+	answer, err := readLine(question)
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(answer) {
+	case "":
+		return def, nil
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
+// Line prints label and reads one line, without surrounding whitespace.
+func Line(label, hint string) (string, error) {
+	if !Interactive() {
+		return "", Required(subject(label), hint)
+	}
+	fmt.Print(label)
+	line, err := readLine(label)
+	return strings.TrimSpace(line), err
+}
+
+// Secret is Line with echo turned off.
+func Secret(label, hint string) (string, error) {
+	if !Interactive() {
+		return "", Required(subject(label), hint)
+	}
+	fmt.Print(label)
+
+	stty := func(arg string) error {
+		cmd := exec.Command("stty", arg)
+		cmd.Stdin = os.Stdin
+		return cmd.Run()
+	}
+	if err := stty("-echo"); err == nil {
+		defer func() {
+			stty("echo")
+			fmt.Println()
+		}()
+	}
+
+	return readLine(label)
+}
+
+// subject quotes a question or prompt label for errors.
+func subject(label string) string {
+	return fmt.Sprintf("%q", strings.TrimSuffix(strings.TrimSpace(label), ":"))
+}
+
+// readLine reads one line from stdin. It reads byte by byte so no input
+// meant for later questions is buffered away. Input that ends (Ctrl-D)
+// before a line is complete is an error, so questions asked again after
+// an invalid answer cannot loop forever.
+func readLine(label string) (string, error) {
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if n == 0 || err != nil {
+			fmt.Println()
+			return "", errcode.Errorf(errcode.InputRequired, "input ended while waiting for %s", subject(label))
+		}
+		if buf[0] == '\n' {
+			break
+		}
+		line = append(line, buf[0])
+	}
+	return strings.TrimRight(string(line), "\r"), nil
+}
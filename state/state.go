@@ -0,0 +1,104 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Manifest records the files generated for one configuration, so that they
+// are replaced and deleted together. It never contains parameter values,
+// which may be secrets.
+type Manifest struct {
+	Config          string    `yaml:"config"`
+	Template        string    `yaml:"template"`
+	TemplateVersion string    `yaml:"template_version,omitempty"`
+	GeneratedAt     time.Time `yaml:"generated_at"`
+	Files           []File    `yaml:"files"`
+}
+
+// File is one generated file. Output is the name of the template output it
+// was rendered from, empty for the main configuration.
+type File struct {
+	Path   string `yaml:"path"`
+	Mode   string `yaml:"mode"`
+	Output string `yaml:"output,omitempty"`
+}
+
+// Dir returns the directory manifests are kept in.
+func Dir() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".ngcli", "state")
+}
+
+func manifestPath(config string) string {
+	return filepath.Join(Dir(), strings.ReplaceAll(config, "/", "-")+".yaml")
+}
+
+// Load returns the manifest of a configuration, or nil if there is none.
+func Load(config string) (*Manifest, error) {
+	data, err := os.ReadFile(manifestPath(config))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state of %s: %w", config, err)
+	}
+
+	var manifest Manifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse state of %s: %w", config, err)
+	}
+
+	return &manifest, nil
+}
+
+// Save writes the manifest, replacing the previous one.
+func (m *Manifest) Save() error {
+	if err := os.MkdirAll(Dir(), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to encode state of %s: %w", m.Config, err)
+	}
+
+	if err := os.WriteFile(manifestPath(m.Config), data, 0644); err != nil {
+		return fmt.Errorf("failed to write state of %s: %w", m.Config, err)
+	}
+
+	return nil
+}
+
+// Remove deletes the manifest of a configuration, if there is one.
+func Remove(config string) error {
+	if err := os.Remove(manifestPath(config)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove state of %s: %w", config, err)
+	}
+	return nil
+}
+
+// Main returns the main configuration file, if recorded.
+func (m *Manifest) Main() (File, bool) {
+	for _, file := range m.Files {
+		if file.Output == "" {
+			return file, true
+		}
+	}
+	return File{}, false
+}
+
+// Has reports whether path is one of the recorded files.
+func (m *Manifest) Has(path string) bool {
+	for _, file := range m.Files {
+		if file.Path == path {
+			return true
+		}
+	}
+	return false
+}
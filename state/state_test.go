@@ -0,0 +1,74 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestManifestRoundTrip(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if m, err := Load("acme/shop"); m != nil || err != nil {
+		t.Fatalf("Load without state = %v, %v; want nil, nil", m, err)
+	}
+
+	saved := &Manifest{
+		Config:          "acme/shop",
+		Template:        "acme/prod",
+		TemplateVersion: "1.2",
+		GeneratedAt:     time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+		Files: []File{
+			{Path: "/etc/nginx/sites-available/shop.conf", Mode: "0644"},
+			{Path: "/etc/nginx/shop.htpasswd", Mode: "0600", Output: "htpasswd"},
+		},
+	}
+	if err := saved.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	// The configuration name does not create subdirectories
+	if _, err := os.Stat(filepath.Join(Dir(), "acme-shop.yaml")); err != nil {
+		t.Errorf("manifest not stored as acme-shop.yaml: %v", err)
+	}
+
+	loaded, err := Load("acme/shop")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, saved) {
+		t.Errorf("Load = %+v, want %+v", loaded, saved)
+	}
+
+	main, ok := loaded.Main()
+	if !ok || main.Path != "/etc/nginx/sites-available/shop.conf" {
+		t.Errorf("Main = %+v, %v; want the configuration", main, ok)
+	}
+	if !loaded.Has("/etc/nginx/shop.htpasswd") || loaded.Has("/etc/nginx/other.htpasswd") {
+		t.Errorf("Has does not match the recorded files")
+	}
+
+	if err := Remove("acme/shop"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if m, err := Load("acme/shop"); m != nil || err != nil {
+		t.Errorf("Load after Remove = %v, %v; want nil, nil", m, err)
+	}
+	if err := Remove("acme/shop"); err != nil {
+		t.Errorf("Remove without state: %v", err)
+	}
+}
+
+func TestLoadRejectsCorruptState(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := os.MkdirAll(Dir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(Dir(), "shop.yaml"), []byte("files: {"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load("shop"); err == nil {
+		t.Errorf("Load of a corrupt manifest succeeded")
+	}
+}
@@ -1,15 +1,35 @@
 package system
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/vourteen14/ngcli/errcode"
 )
 
+// ErrNginxNotFound is returned when the nginx binary is not on PATH.
+var ErrNginxNotFound = errcode.New(errcode.NginxNotInstalled, "nginx binary not found in PATH")
+
+// nginxError gives a failure to run nginx its code: NginxNotInstalled if
+// the binary could not be started, code otherwise.
+func nginxError(code errcode.Code, runErr error, format string, args ...interface{}) error {
+	if errors.Is(runErr, exec.ErrNotFound) {
+		return ErrNginxNotFound
+	}
+	return errcode.Errorf(code, format, args...)
+}
+
 func NginxReload() error {
 	cmd := exec.Command("nginx", "-s", "reload")
 	
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to reload nginx: %w", err)
+		return nginxError(errcode.NginxReloadFailed, err, "failed to reload nginx: %w", err)
 	}
 	
 	return nil
@@ -20,7 +40,7 @@ func NginxTest() error {
 	
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return fmt.Errorf("nginx configuration test failed: %s", string(output))
+		return nginxError(errcode.NginxTestFailed, err, "nginx configuration test failed: %s", string(output))
 	}
 	
 	return nil
@@ -30,8 +50,242 @@ func NginxStatus() error {
 	cmd := exec.Command("nginx", "-v")
 	
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("nginx is not available: %w", err)
+		return errcode.Errorf(errcode.NginxNotInstalled, "nginx is not available: %w", err)
 	}
 	
 	return nil
-}
\ No newline at end of file
+}
+// NginxTestConfig runs "nginx -t" against a standalone configuration that
+// only includes the given server-level content, so a rendered site can be
+// checked without touching the system configuration.
+func NginxTestConfig(content string) error {
+	if _, err := exec.LookPath("nginx"); err != nil {
+		return ErrNginxNotFound
+	}
+	
+	dir, err := os.MkdirTemp("", "ngcli-nginx-test-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	
+	sitePath := filepath.Join(dir, "site.conf")
+	if err := os.WriteFile(sitePath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write test configuration: %w", err)
+	}
+	
+	mainConf := fmt.Sprintf(`pid %s;
+error_log %s;
+events {}
+http {
+    include %s;
+}
+`, filepath.Join(dir, "nginx.pid"), filepath.Join(dir, "error.log"), sitePath)
+	
+	mainPath := filepath.Join(dir, "nginx.conf")
+	if err := os.WriteFile(mainPath, []byte(mainConf), 0644); err != nil {
+		return fmt.Errorf("failed to write test configuration: %w", err)
+	}
+	
+	cmd := exec.Command("nginx", "-t", "-q", "-p", dir, "-c", mainPath)
+	
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return errcode.Errorf(errcode.NginxTestFailed, "nginx configuration test failed: %s", strings.TrimSpace(string(output)))
+	}
+	
+	return nil
+}
+
+// NginxBuild is what 'nginx -V' reports about the nginx binary on PATH.
+// Prefix and ConfPath are the compiled-in defaults when the build does not
+// set them. Modules are the optional modules compiled in, e.g.
+// "http_ssl_module" or "stream (dynamic)".
+type NginxBuild struct {
+	Binary   string
+	Version  string
+	Prefix   string
+	ConfPath string
+	Modules  []string
+}
+
+// NginxPath returns the path of the nginx binary on PATH.
+func NginxPath() (string, error) {
+	binary, err := exec.LookPath("nginx")
+	if err != nil {
+		return "", ErrNginxNotFound
+	}
+	return binary, nil
+}
+
+// NginxBuildInfo runs 'nginx -V'.
+func NginxBuildInfo() (*NginxBuild, error) {
+	binary, err := NginxPath()
+	if err != nil {
+		return nil, err
+	}
+	
+	// nginx -V writes to stderr
+	output, err := exec.Command(binary, "-V").CombinedOutput()
+	if err != nil {
+		return nil, errcode.Errorf(errcode.NginxNotInstalled, "failed to run nginx -V: %s", strings.TrimSpace(string(output)))
+	}
+	
+	build := parseBuildInfo(string(output))
+	build.Binary = binary
+	return build, nil
+}
+
+// parseBuildInfo reads the version and the --prefix and --conf-path
+// configure arguments from the output of 'nginx -V'.
+func parseBuildInfo(output string) *NginxBuild {
+	build := &NginxBuild{Prefix: "/usr/local/nginx"}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if version, ok := strings.CutPrefix(line, "nginx version:"); ok {
+			build.Version = strings.TrimSpace(version)
+		}
+		args, ok := strings.CutPrefix(line, "configure arguments:")
+		if !ok {
+			continue
+		}
+		for _, arg := range strings.Fields(args) {
+			if value, ok := strings.CutPrefix(arg, "--prefix="); ok {
+				build.Prefix = strings.Trim(value, `"'`)
+			}
+			if value, ok := strings.CutPrefix(arg, "--conf-path="); ok {
+				build.ConfPath = strings.Trim(value, `"'`)
+			}
+			if module := moduleName(arg); module != "" {
+				build.Modules = append(build.Modules, module)
+			}
+		}
+	}
+	
+	// Relative paths are relative to the prefix
+	if build.ConfPath == "" {
+		build.ConfPath = "conf/nginx.conf"
+	}
+	if !filepath.IsAbs(build.ConfPath) {
+		build.ConfPath = filepath.Join(build.Prefix, build.ConfPath)
+	}
+	return build
+}
+
+// moduleName returns the module a configure argument compiles in, or "".
+// Other --with options, such as --with-cc-opt, are not modules.
+func moduleName(arg string) string {
+	if path, ok := strings.CutPrefix(arg, "--add-module="); ok {
+		return filepath.Base(strings.Trim(path, `"'`))
+	}
+	if path, ok := strings.CutPrefix(arg, "--add-dynamic-module="); ok {
+		return filepath.Base(strings.Trim(path, `"'`)) + " (dynamic)"
+	}
+	
+	name, ok := strings.CutPrefix(arg, "--with-")
+	if !ok {
+		return ""
+	}
+	name, dynamic := strings.CutSuffix(name, "=dynamic")
+	if !strings.HasSuffix(name, "_module") && name != "stream" && name != "mail" {
+		return ""
+	}
+	if dynamic {
+		name += " (dynamic)"
+	}
+	return name
+}
+
+// NginxDumpConfig runs 'nginx -T' and returns the content of every file of
+// the configuration it printed, by path. nginx only prints the files
+// after a successful test, which usually needs root.
+func NginxDumpConfig() (map[string]string, error) {
+	cmd := exec.Command("nginx", "-T")
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, nginxError(errcode.NginxTestFailed, err, "nginx -T failed: %s", strings.TrimSpace(stderr.String()))
+	}
+	return parseDumpConfig(string(output)), nil
+}
+
+// parseDumpConfig splits the output of 'nginx -T' into the files it
+// printed, each after a "# configuration file <path>:" line.
+func parseDumpConfig(output string) map[string]string {
+	files := make(map[string]string)
+	var current string
+	var content strings.Builder
+	for _, line := range strings.SplitAfter(output, "\n") {
+		header := strings.TrimRight(line, "\r\n")
+		if path, ok := strings.CutPrefix(header, "# configuration file "); ok && strings.HasSuffix(path, ":") {
+			if current != "" {
+				files[current] = content.String()
+			}
+			current = strings.TrimSuffix(path, ":")
+			content.Reset()
+			continue
+		}
+		content.WriteString(line)
+	}
+	if current != "" {
+		files[current] = content.String()
+	}
+	return files
+}
+
+// NginxProcess is a running nginx master or worker process.
+type NginxProcess struct {
+	PID    int
+	Master bool
+	User   string
+}
+
+// NginxProcesses returns the running nginx processes, found by their
+// process titles in /proc. It fails where there is no /proc.
+func NginxProcesses() ([]NginxProcess, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, fmt.Errorf("cannot list processes: %w", err)
+	}
+	
+	var processes []NginxProcess
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		cmdline, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "cmdline"))
+		if err != nil {
+			continue
+		}
+		title := strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " "))
+		master := strings.HasPrefix(title, "nginx: master process")
+		if !master && !strings.HasPrefix(title, "nginx: worker process") {
+			continue
+		}
+		processes = append(processes, NginxProcess{PID: pid, Master: master, User: processUser(pid)})
+	}
+	return processes, nil
+}
+
+// processUser returns the name of the effective user of a process, or its
+// uid if the name is unknown.
+func processUser(pid int) string {
+	status, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "status"))
+	if err != nil {
+		return "?"
+	}
+	for _, line := range strings.Split(string(status), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] != "Uid:" {
+			continue
+		}
+		if u, err := user.LookupId(fields[2]); err == nil {
+			return u.Username
+		}
+		return fields[2]
+	}
+	return "?"
+}
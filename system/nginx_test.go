@@ -0,0 +1,145 @@
+package system
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseBuildInfo(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   NginxBuild
+	}{
+		{
+			name: "distribution package",
+			output: `nginx version: nginx/1.24.0 (Ubuntu)
+built with OpenSSL 3.0.13 30 Jan 2024
+TLS SNI support enabled
+configure arguments: --with-cc-opt='-g -O2 -fstack-protector-strong' --prefix=/usr/share/nginx --conf-path=/etc/nginx/nginx.conf --http-log-path=/var/log/nginx/access.log --with-http_ssl_module --with-http_v2_module --with-stream=dynamic --add-dynamic-module=/build/modules/http-geoip2
+`,
+			want: NginxBuild{
+				Version:  "nginx/1.24.0 (Ubuntu)",
+				Prefix:   "/usr/share/nginx",
+				ConfPath: "/etc/nginx/nginx.conf",
+				Modules:  []string{"http_ssl_module", "http_v2_module", "stream (dynamic)", "http-geoip2 (dynamic)"},
+			},
+		},
+		{
+			name: "custom prefix with a relative conf path",
+			output: `nginx version: nginx/1.25.3
+built by gcc 12.2.0 (Debian 12.2.0-14)
+configure arguments: --prefix=/opt/nginx --conf-path=etc/nginx.conf --with-http_ssl_module --add-module=/src/ngx_brotli
+`,
+			want: NginxBuild{
+				Version:  "nginx/1.25.3",
+				Prefix:   "/opt/nginx",
+				ConfPath: "/opt/nginx/etc/nginx.conf",
+				Modules:  []string{"http_ssl_module", "ngx_brotli"},
+			},
+		},
+		{
+			name: "custom prefix without a conf path",
+			output: `nginx version: nginx/1.25.3
+configure arguments: --prefix=/srv/web
+`,
+			want: NginxBuild{
+				Version:  "nginx/1.25.3",
+				Prefix:   "/srv/web",
+				ConfPath: "/srv/web/conf/nginx.conf",
+			},
+		},
+		{
+			name: "OpenResty",
+			output: `nginx version: openresty/1.21.4.1
+built with OpenSSL 1.1.1n  15 Mar 2022
+TLS SNI support enabled
+configure arguments: --prefix=/usr/local/openresty/nginx --with-cc-opt=-O2 --add-module=../ngx_devel_kit-0.3.1 --add-module=../ngx_lua-0.10.21 --with-ld-opt=-Wl,-rpath,/usr/local/openresty/luajit/lib --with-stream --with-stream_ssl_module --with-http_ssl_module
+`,
+			want: NginxBuild{
+				Version:  "openresty/1.21.4.1",
+				Prefix:   "/usr/local/openresty/nginx",
+				ConfPath: "/usr/local/openresty/nginx/conf/nginx.conf",
+				Modules:  []string{"ngx_devel_kit-0.3.1", "ngx_lua-0.10.21", "stream", "stream_ssl_module", "http_ssl_module"},
+			},
+		},
+		{
+			name:   "no configure arguments",
+			output: "nginx version: nginx/1.25.3\nconfigure arguments:\n",
+			want: NginxBuild{
+				Version:  "nginx/1.25.3",
+				Prefix:   "/usr/local/nginx",
+				ConfPath: "/usr/local/nginx/conf/nginx.conf",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseBuildInfo(tt.output); !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("parseBuildInfo = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDumpConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   map[string]string
+	}{
+		{
+			name: "custom prefix",
+			output: `# configuration file /opt/nginx/etc/nginx.conf:
+events {}
+http {
+    include mime.types;
+    include /opt/nginx/sites/*.conf;
+}
+
+# configuration file /opt/nginx/etc/mime.types:
+types { text/html html; }
+
+# configuration file /opt/nginx/sites/app.conf:
+server { listen 80; }
+`,
+			want: map[string]string{
+				"/opt/nginx/etc/nginx.conf": "events {}\nhttp {\n    include mime.types;\n    include /opt/nginx/sites/*.conf;\n}\n\n",
+				"/opt/nginx/etc/mime.types": "types { text/html html; }\n\n",
+				"/opt/nginx/sites/app.conf": "server { listen 80; }\n",
+			},
+		},
+		{
+			name:   "OpenResty with CRLF line endings",
+			output: "# configuration file /usr/local/openresty/nginx/conf/nginx.conf:\r\nhttp { include conf.d/*.conf; }\r\n# configuration file /usr/local/openresty/nginx/conf/conf.d/api.conf:\r\nserver {}\r\n",
+			want: map[string]string{
+				"/usr/local/openresty/nginx/conf/nginx.conf":      "http { include conf.d/*.conf; }\r\n",
+				"/usr/local/openresty/nginx/conf/conf.d/api.conf": "server {}\r\n",
+			},
+		},
+		{
+			name: "header-like comment inside a file",
+			output: `# configuration file /etc/nginx/nginx.conf:
+# configuration file layout: see below
+http {}
+`,
+			want: map[string]string{
+				"/etc/nginx/nginx.conf": "# configuration file layout: see below\nhttp {}\n",
+			},
+		},
+		{
+			name:   "nothing printed",
+			output: "",
+			want:   map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseDumpConfig(tt.output); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseDumpConfig = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package template
+
+import (
+	"sort"
+	"strings"
+	"text/template/parse"
+)
+
+// ParameterReference is a top-level field such as {{.domain}} used by a template.
+type ParameterReference struct {
+	Name string
+	Line int
+}
+
+// ParameterUsage compares the fields a template references with its @param declarations.
+type ParameterUsage struct {
+	Undeclared []ParameterReference
+	Unused     []ParameterInfo
+}
+
+// ReferencedParameters walks the parse tree of the template (including any
+// {{define}} blocks) and returns every field read from the root data, in
+// source order. Fields read inside {{range}} or {{with}} bodies refer to a
+// different dot and are only counted when accessed through $.
+func (t *Template) ReferencedParameters() []ParameterReference {
+	var refs []ParameterReference
+
+	for _, tmpl := range t.Template.Templates() {
+		if tmpl.Tree == nil || tmpl.Tree.Root == nil {
+			continue
+		}
+		w := &referenceWalker{content: t.Content}
+		w.walk(tmpl.Tree.Root, true)
+		refs = append(refs, w.refs...)
+	}
+
+	sort.SliceStable(refs, func(i, j int) bool {
+		return refs[i].Line < refs[j].Line
+	})
+
+	return refs
+}
+
+// CheckParameterUsage reports referenced-but-undeclared and
+// declared-but-unused parameters. Parameters that only control other
+// parameters through @when or feed computed defaults are not reported as
+// unused. The implicit config_name counts as declared.
+func (t *Template) CheckParameterUsage() ParameterUsage {
+	var usage ParameterUsage
+
+	declared := map[string]bool{ConfigNameParameter: true}
+	for _, param := range t.Metadata.Parameters {
+		declared[param.Name] = true
+	}
+
+	used := make(map[string]bool)
+	for _, ref := range t.ReferencedParameters() {
+		used[ref.Name] = true
+		if !declared[ref.Name] {
+			usage.Undeclared = append(usage.Undeclared, ref)
+		}
+	}
+
+	for _, param := range t.Metadata.Parameters {
+		if tmpl, computed := t.Metadata.defaults[param.Name]; computed {
+			w := &referenceWalker{content: param.Default}
+			w.walk(tmpl.Tree.Root, true)
+			for _, ref := range w.refs {
+				used[ref.Name] = true
+			}
+		}
+		if param.When != "" {
+			controller := strings.TrimPrefix(param.When, "!")
+			controller, _, _ = strings.Cut(controller, "=")
+			used[controller] = true
+		}
+	}
+
+	for _, param := range t.Metadata.Parameters {
+		if !used[param.Name] {
+			usage.Unused = append(usage.Unused, param)
+		}
+	}
+
+	return usage
+}
+
+type referenceWalker struct {
+	content string
+	refs    []ParameterReference
+}
+
+func (w *referenceWalker) add(name string, pos parse.Pos) {
+	w.refs = append(w.refs, ParameterReference{
+		Name: name,
+		Line: lineOf(w.content, int(pos)),
+	})
+}
+
+func (w *referenceWalker) walk(node parse.Node, rootDot bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			w.walk(child, rootDot)
+		}
+	case *parse.ActionNode:
+		w.walk(n.Pipe, rootDot)
+	case *parse.IfNode:
+		w.walkBranch(&n.BranchNode, rootDot, rootDot)
+	case *parse.RangeNode:
+		w.walkBranch(&n.BranchNode, rootDot, false)
+	case *parse.WithNode:
+		w.walkBranch(&n.BranchNode, rootDot, false)
+	case *parse.TemplateNode:
+		w.walk(n.Pipe, rootDot)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			w.walk(cmd, rootDot)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			w.walk(arg, rootDot)
+		}
+	case *parse.ChainNode:
+		w.walk(n.Node, rootDot)
+	case *parse.FieldNode:
+		if rootDot && len(n.Ident) > 0 {
+			w.add(n.Ident[0], n.Pos)
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			w.add(n.Ident[1], n.Pos)
+		}
+	}
+}
+
+func (w *referenceWalker) walkBranch(n *parse.BranchNode, rootDot, bodyRootDot bool) {
+	w.walk(n.Pipe, rootDot)
+	w.walk(n.List, bodyRootDot)
+	w.walk(n.ElseList, rootDot)
+}
+
+func lineOf(content string, offset int) int {
+	if offset > len(content) {
+		offset = len(content)
+	}
+	return strings.Count(content[:offset], "\n") + 1
+}
@@ -0,0 +1,92 @@
+package template
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vourteen14/ngcli/errcode"
+)
+
+// usageTemplate references parameters at the root, inside range and with
+// bodies, through $ and in a define block, and declares some it never
+// substitutes.
+const usageTemplate = `{{/*
+# Template: usage
+# Version: 1.0
+# @param domain string required "Domain"
+# @param upstreams array optional "Upstreams" items=string
+# @param enable_cache boolean optional "Cache"
+# @when enable_cache
+# @param cache_ttl integer optional "Cache TTL"
+# @param log_name string optional "Log name" default="{{.domain}}"
+# @param unused string optional "Never used"
+*/}}
+server {
+    server_name "{{.domain}}";
+{{- range .upstreams}}
+    server "{{.}}" weight={{.weight}};
+    proxy_pass http://{{$.backend}};
+{{- end}}
+{{- with .extra}}
+    include {{.path}};
+{{- end}}
+    access_log /var/log/nginx/{{.log_name}}.log;
+    # {{.config_name}}
+}
+{{define "upstream"}}upstream {{.pool}} {}{{end}}
+`
+
+func TestCheckParameterUsage(t *testing.T) {
+	tmpl, err := ParseTemplate("usage", "usage.conf.tpl", usageTemplate)
+	if err != nil {
+		t.Fatalf("ParseTemplate: %v", err)
+	}
+
+	usage := tmpl.CheckParameterUsage()
+
+	// .weight and .path are fields of range and with values
+	want := []ParameterReference{{Name: "backend", Line: 16}, {Name: "extra", Line: 18}, {Name: "pool", Line: 24}}
+	if !reflect.DeepEqual(usage.Undeclared, want) {
+		t.Errorf("undeclared = %+v, want %+v", usage.Undeclared, want)
+	}
+
+	// enable_cache only controls cache_ttl and domain feeds a computed
+	// default; cache_ttl is declared but never substituted
+	var unused []string
+	for _, param := range usage.Unused {
+		unused = append(unused, param.Name)
+	}
+	if !reflect.DeepEqual(unused, []string{"cache_ttl", "unused"}) {
+		t.Errorf("unused = %v, want [cache_ttl unused]", unused)
+	}
+}
+
+func TestRenderFailsOnUndeclaredKey(t *testing.T) {
+	tmpl, err := ParseTemplate("undeclared", "undeclared.conf.tpl", "# Template: undeclared\n# @param domain string required \"Domain\"\nserver { server_name \"{{.domain}}\"; root {{.root}}; }\n")
+	if err != nil {
+		t.Fatalf("ParseTemplate: %v", err)
+	}
+
+	_, err = tmpl.RenderWithValidation(map[string]string{"domain": "a.b"})
+	if err == nil || !strings.Contains(err.Error(), `map has no entry for key "root"`) {
+		t.Fatalf("RenderWithValidation = %v, want an error about root", err)
+	}
+	if code := errcode.CodeOf(err); code != errcode.TemplateInvalid {
+		t.Errorf("code = %s, want %s", code, errcode.TemplateInvalid)
+	}
+}
+
+func TestDeclaredOptionalParametersRenderEmpty(t *testing.T) {
+	tmpl, err := ParseTemplate("optional", "optional.conf.tpl", "# Template: optional\n# @param domain string required \"Domain\"\n# @param alias string optional \"Alias\"\nserver_name {{.domain}}{{if .alias}} {{.alias}}{{end}};\n")
+	if err != nil {
+		t.Fatalf("ParseTemplate: %v", err)
+	}
+	content, err := tmpl.RenderWithValidation(map[string]string{"domain": "a.b"})
+	if err != nil {
+		t.Fatalf("RenderWithValidation: %v", err)
+	}
+	if !strings.Contains(content, "server_name a.b;") {
+		t.Errorf("render = %q, want server_name a.b;", content)
+	}
+}
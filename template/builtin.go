@@ -0,0 +1,312 @@
+package template
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/vourteen14/ngcli/filesystem"
+	"github.com/vourteen14/ngcli/utils"
+)
+
+// The built-in templates of this release are builtin/<name>.conf.tpl;
+// earlier releases are kept as builtin/<version>/<name>.conf.tpl.
+//
+//go:embed builtin/*.conf.tpl builtin/*/*.conf.tpl
+var builtinFS embed.FS
+
+// builtinBaseDir keeps, for every built-in template copied into the template
+// directory, the exact upstream content it was copied from. It is the common
+// ancestor for three-way merges of later upstream changes.
+const builtinBaseDir = ".builtin"
+
+// BuiltinStatus compares the user's copy of a built-in template with the
+// version it was copied from and with the version embedded in this binary.
+type BuiltinStatus struct {
+	Name string
+	// Installed is false when the template directory has no copy.
+	Installed bool
+	// Tracked is false for copies made before ngcli recorded their origin.
+	// Such a copy is still compared with the earlier release of its
+	// version, if this binary knows it.
+	Tracked bool
+	// Modified reports local changes to the copy.
+	Modified bool
+	// UpdateAvailable reports upstream changes not yet merged into the copy.
+	UpdateAvailable  bool
+	InstalledVersion string
+	UpstreamVersion  string
+}
+
+// BuiltinUpdate is the result of merging upstream changes into a copy.
+type BuiltinUpdate struct {
+	Name      string
+	Merged    string
+	Conflicts int
+	Changed   bool
+}
+
+// BuiltinNames returns the names of the templates embedded in the binary.
+func BuiltinNames() []string {
+	entries, err := fs.ReadDir(builtinFS, "builtin")
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(entry.Name(), ".conf.tpl"))
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// IsBuiltin reports whether name is one of the embedded templates.
+func IsBuiltin(name string) bool {
+	_, ok := BuiltinContent(name)
+	return ok
+}
+
+// BuiltinContent returns the embedded content of a built-in template.
+func BuiltinContent(name string) (string, bool) {
+	content, err := builtinFS.ReadFile("builtin/" + name + ".conf.tpl")
+	if err != nil {
+		return "", false
+	}
+	return string(content), true
+}
+
+// previousBuiltinContent returns the built-in template name as released
+// with the given version.
+func previousBuiltinContent(name, version string) (string, bool) {
+	if version == "" || strings.ContainsAny(version, "/\\") {
+		return "", false
+	}
+	content, err := builtinFS.ReadFile("builtin/" + version + "/" + name + ".conf.tpl")
+	if err != nil {
+		return "", false
+	}
+	return string(content), true
+}
+
+func builtinCopyPath(name, templateDir string) string {
+	return filepath.Join(templateDir, name+".conf.tpl")
+}
+
+func builtinBasePath(name, templateDir string) string {
+	return filepath.Join(templateDir, builtinBaseDir, name+".conf.tpl")
+}
+
+func recordBuiltinBase(name, content, templateDir string) error {
+	return filesystem.WriteFile(builtinBasePath(name, templateDir), content, true)
+}
+
+// builtinBase returns the upstream content the copy current was made from:
+// the recorded base or, for a copy made before bases were recorded, the
+// earlier release named by its Version header. ok is false when the origin
+// is unknown.
+func builtinBase(name, current, templateDir string) (base string, tracked, ok bool, err error) {
+	data, err := os.ReadFile(builtinBasePath(name, templateDir))
+	if err == nil {
+		return string(data), true, true, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", false, false, fmt.Errorf("failed to read recorded base of %s: %w", name, err)
+	}
+
+	if upstream, _ := BuiltinContent(name); current == upstream {
+		return upstream, false, true, nil
+	}
+	base, ok = previousBuiltinContent(name, versionOf(current))
+	return base, false, ok, nil
+}
+
+// InstallBuiltins copies every embedded template that is missing from
+// templateDir and records the copied version. Existing copies are left
+// untouched; an untracked copy identical to upstream starts being tracked.
+func InstallBuiltins(templateDir string) ([]string, error) {
+	var created []string
+
+	for _, name := range BuiltinNames() {
+		upstream, _ := BuiltinContent(name)
+		copyPath := builtinCopyPath(name, templateDir)
+
+		if utils.FileExists(copyPath) {
+			if !utils.FileExists(builtinBasePath(name, templateDir)) {
+				if current, err := os.ReadFile(copyPath); err == nil && string(current) == upstream {
+					if err := recordBuiltinBase(name, upstream, templateDir); err != nil {
+						return created, err
+					}
+				}
+			}
+			continue
+		}
+
+		if err := filesystem.WriteFile(copyPath, upstream, false); err != nil {
+			return created, fmt.Errorf("failed to create %s: %w", filepath.Base(copyPath), err)
+		}
+		if err := recordBuiltinBase(name, upstream, templateDir); err != nil {
+			return created, err
+		}
+
+		created = append(created, name)
+	}
+
+	return created, nil
+}
+
+// GetBuiltinStatus inspects the copy of one built-in template.
+func GetBuiltinStatus(name, templateDir string) (BuiltinStatus, error) {
+	upstream, ok := BuiltinContent(name)
+	if !ok {
+		return BuiltinStatus{}, fmt.Errorf("not a built-in template: %s", name)
+	}
+
+	status := BuiltinStatus{
+		Name:            name,
+		UpstreamVersion: versionOf(upstream),
+	}
+
+	current, err := os.ReadFile(builtinCopyPath(name, templateDir))
+	if os.IsNotExist(err) {
+		return status, nil
+	}
+	if err != nil {
+		return status, fmt.Errorf("failed to read template %s: %w", name, err)
+	}
+	status.Installed = true
+	status.InstalledVersion = versionOf(string(current))
+
+	base, tracked, ok, err := builtinBase(name, string(current), templateDir)
+	if err != nil {
+		return status, err
+	}
+	status.Tracked = tracked
+	if ok {
+		status.Modified = string(current) != base
+		status.UpdateAvailable = base != upstream
+	} else {
+		status.Modified = string(current) != upstream
+		status.UpdateAvailable = status.Modified
+	}
+
+	return status, nil
+}
+
+// BuiltinStatuses returns the status of every built-in template.
+func BuiltinStatuses(templateDir string) ([]BuiltinStatus, error) {
+	var statuses []BuiltinStatus
+	for _, name := range BuiltinNames() {
+		status, err := GetBuiltinStatus(name, templateDir)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// DiffBuiltin returns a unified diff from the user's copy to the upstream
+// built-in template.
+func DiffBuiltin(name, templateDir string) (string, error) {
+	upstream, ok := BuiltinContent(name)
+	if !ok {
+		return "", fmt.Errorf("not a built-in template: %s", name)
+	}
+
+	current, err := os.ReadFile(builtinCopyPath(name, templateDir))
+	if err != nil {
+		return "", fmt.Errorf("failed to read template %s: %w", name, err)
+	}
+
+	return utils.UnifiedDiff(string(current), upstream, name+".conf.tpl (local)", name+".conf.tpl (upstream)"), nil
+}
+
+// MergeBuiltin three-way merges upstream changes into the user's copy of a
+// built-in template, using the recorded base as common ancestor, or the
+// earlier release the copy was made from when no base was recorded.
+// Nothing is written; see ApplyBuiltinUpdate.
+func MergeBuiltin(name, templateDir string) (*BuiltinUpdate, error) {
+	upstream, ok := BuiltinContent(name)
+	if !ok {
+		return nil, fmt.Errorf("not a built-in template: %s", name)
+	}
+
+	current, err := os.ReadFile(builtinCopyPath(name, templateDir))
+	if os.IsNotExist(err) {
+		return &BuiltinUpdate{Name: name, Merged: upstream, Changed: true}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template %s: %w", name, err)
+	}
+
+	base, _, ok, err := builtinBase(name, string(current), templateDir)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("template %s was copied by an older ngcli and its origin is unknown (use --force to replace it with upstream)", name)
+	}
+
+	merged, conflicts := utils.Merge3(base, string(current), upstream, "local", "upstream")
+
+	return &BuiltinUpdate{
+		Name:      name,
+		Merged:    merged,
+		Conflicts: conflicts,
+		Changed:   merged != string(current) || base != upstream,
+	}, nil
+}
+
+// ApplyBuiltinUpdate writes a conflict-free merge result and records the
+// current upstream version as the new base. The previous copy is backed up.
+func ApplyBuiltinUpdate(update *BuiltinUpdate, templateDir string) error {
+	if update.Conflicts > 0 {
+		return fmt.Errorf("template %s has %d unresolved conflict(s)", update.Name, update.Conflicts)
+	}
+
+	copyPath := builtinCopyPath(update.Name, templateDir)
+	if err := filesystem.BackupFile(copyPath); err != nil {
+		return err
+	}
+	if err := filesystem.WriteFile(copyPath, update.Merged, true); err != nil {
+		return err
+	}
+
+	upstream, _ := BuiltinContent(update.Name)
+	return recordBuiltinBase(update.Name, upstream, templateDir)
+}
+
+// ResetBuiltin replaces the user's copy with upstream, backing it up first.
+func ResetBuiltin(name, templateDir string) error {
+	upstream, ok := BuiltinContent(name)
+	if !ok {
+		return fmt.Errorf("not a built-in template: %s", name)
+	}
+
+	copyPath := builtinCopyPath(name, templateDir)
+	if err := filesystem.BackupFile(copyPath); err != nil {
+		return err
+	}
+	if err := filesystem.WriteFile(copyPath, upstream, true); err != nil {
+		return err
+	}
+
+	return recordBuiltinBase(name, upstream, templateDir)
+}
+
+func versionOf(content string) string {
+	metadata, err := ParseTemplateMetadata(content)
+	if err != nil {
+		return ""
+	}
+	return metadata.Version
+}
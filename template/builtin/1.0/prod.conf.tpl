@@ -0,0 +1,131 @@
+# Template: prod
+# Description: Production-ready reverse proxy with maximum security
+# Author: ngcli
+# Version: 1.0
+#
+# @param domain string required "Primary domain for the service"
+# @param upstream_host string required "Backend service host" default="127.0.0.1"
+# @param upstream_port integer required "Backend service port" default=3000
+# @param ssl_cert file_path required "Path to SSL certificate file"
+# @param ssl_key file_path required "Path to SSL private key file"
+# @param client_max_body_size string optional "Maximum request body size" default="10m"
+
+# Rate limiting zones
+limit_req_zone $binary_remote_addr zone=api:10m rate=10r/s;
+limit_req_zone $binary_remote_addr zone=login:10m rate=5r/m;
+limit_conn_zone $binary_remote_addr zone=conn_limit_per_ip:10m;
+
+# Security headers map
+map $sent_http_content_type $nosniff_header {
+    ~^text/ "nosniff";
+    default "";
+}
+
+server {
+    listen 80;
+    server_name {{.domain}};
+    
+    # Security: Force HTTPS redirect
+    return 301 https://$server_name$request_uri;
+}
+
+server {
+    listen 443 ssl http2;
+    server_name {{.domain}};
+    
+    # SSL Configuration - Production Grade
+    ssl_certificate {{.ssl_cert}};
+    ssl_certificate_key {{.ssl_key}};
+    ssl_protocols TLSv1.2 TLSv1.3;
+    ssl_ciphers ECDHE-ECDSA-AES128-GCM-SHA256:ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES256-GCM-SHA384:ECDHE-RSA-AES256-GCM-SHA384;
+    ssl_prefer_server_ciphers off;
+    ssl_session_timeout 1d;
+    ssl_session_cache shared:SSL:50m;
+    ssl_stapling on;
+    ssl_stapling_verify on;
+    
+    # Security Headers - Production Grade
+    add_header Strict-Transport-Security "max-age=63072000; includeSubDomains; preload" always;
+    add_header X-Frame-Options "DENY" always;
+    add_header X-Content-Type-Options "nosniff" always;
+    add_header X-XSS-Protection "1; mode=block" always;
+    add_header Referrer-Policy "strict-origin-when-cross-origin" always;
+    add_header Content-Security-Policy "default-src 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline';" always;
+    add_header Permissions-Policy "geolocation=(), microphone=(), camera=()" always;
+    
+    # Connection and rate limits
+    limit_conn conn_limit_per_ip 20;
+    limit_req zone=api burst=20 nodelay;
+    
+    # Basic security settings
+    client_max_body_size {{.client_max_body_size}};
+    server_tokens off;
+    
+    # Hide nginx version
+    more_clear_headers Server;
+    
+    # Security: Block common attack patterns
+    location ~* /(\.git|\.svn|\.env|config\.json|package\.json) {
+        deny all;
+        return 404;
+    }
+    
+    # Main proxy configuration
+    location / {
+        proxy_pass http://{{.upstream_host}}:{{.upstream_port}};
+        proxy_http_version 1.1;
+        proxy_set_header Upgrade $http_upgrade;
+        proxy_set_header Connection 'upgrade';
+        proxy_set_header Host $host;
+        proxy_set_header X-Real-IP $remote_addr;
+        proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+        proxy_set_header X-Forwarded-Proto $scheme;
+        proxy_set_header X-Forwarded-Host $host;
+        proxy_set_header X-Forwarded-Port $server_port;
+        
+        # Timeouts
+        proxy_connect_timeout 30s;
+        proxy_send_timeout 30s;
+        proxy_read_timeout 30s;
+        
+        # Buffer settings
+        proxy_buffering on;
+        proxy_buffer_size 8k;
+        proxy_buffers 8 8k;
+        
+        # Security: Remove potentially dangerous headers
+        proxy_hide_header X-Powered-By;
+        proxy_hide_header Server;
+    }
+    
+    # Rate limited login endpoint
+    location /login {
+        limit_req zone=login burst=3 nodelay;
+        proxy_pass http://{{.upstream_host}}:{{.upstream_port}};
+        proxy_http_version 1.1;
+        proxy_set_header Host $host;
+        proxy_set_header X-Real-IP $remote_addr;
+        proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+        proxy_set_header X-Forwarded-Proto $scheme;
+    }
+    
+    # Health check endpoint (internal only)
+    location /health {
+        access_log off;
+        proxy_pass http://{{.upstream_host}}:{{.upstream_port}};
+        allow 127.0.0.1;
+        allow 10.0.0.0/8;
+        allow 172.16.0.0/12;
+        allow 192.168.0.0/16;
+        deny all;
+    }
+    
+    # Static assets with caching
+    location ~* \.(js|css|png|jpg|jpeg|gif|ico|svg|woff|woff2|ttf|eot)$ {
+        proxy_pass http://{{.upstream_host}}:{{.upstream_port}};
+        proxy_cache_valid 200 302 1h;
+        proxy_cache_valid 404 1m;
+        add_header Cache-Control "public, immutable";
+        expires 1y;
+    }
+}
\ No newline at end of file
@@ -0,0 +1,105 @@
+# Template: staging
+# Description: Staging environment with basic security and debugging capabilities
+# Author: ngcli
+# Version: 1.0
+#
+# @param domain string required "Staging domain"
+# @param upstream_host string required "Backend service host" default="127.0.0.1"
+# @param upstream_port integer required "Backend service port" default=3000
+# @param auth_file file_path optional "Basic auth file path" default="/etc/nginx/.htpasswd"
+# @param ssl_enabled string optional "Enable SSL" default="no" options=["yes","no"]
+# @param ssl_cert file_path optional "Path to SSL certificate file"
+# @param ssl_key file_path optional "Path to SSL private key file"
+
+# Rate limiting for staging (more lenient)
+limit_req_zone $binary_remote_addr zone=staging_api:10m rate=30r/s;
+
+server {
+    listen 80;
+    server_name {{.domain}};
+    
+    # Basic auth for staging access
+    {{if .auth_file}}auth_basic "Staging Environment - Authorized Access Only";
+    auth_basic_user_file {{.auth_file}};{{end}}
+    
+    # Basic security headers
+    add_header X-Frame-Options "SAMEORIGIN" always;
+    add_header X-Content-Type-Options "nosniff" always;
+    add_header X-XSS-Protection "1; mode=block" always;
+    add_header X-Environment "staging" always;
+    
+    # Development-friendly settings
+    add_header X-Debug-Backend "{{.upstream_host}}:{{.upstream_port}}" always;
+    add_header X-Request-ID "$request_id" always;
+    
+    # Rate limiting (lenient)
+    limit_req zone=staging_api burst=50 nodelay;
+    
+    client_max_body_size 50m;
+    
+    # Main proxy configuration
+    location / {
+        proxy_pass http://{{.upstream_host}}:{{.upstream_port}};
+        proxy_http_version 1.1;
+        proxy_set_header Upgrade $http_upgrade;
+        proxy_set_header Connection 'upgrade';
+        proxy_set_header Host $host;
+        proxy_set_header X-Real-IP $remote_addr;
+        proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+        proxy_set_header X-Forwarded-Proto $scheme;
+        proxy_set_header X-Request-ID $request_id;
+        
+        # Generous timeouts for debugging
+        proxy_connect_timeout 60s;
+        proxy_send_timeout 60s;
+        proxy_read_timeout 60s;
+        
+        # Debug headers
+        add_header X-Upstream-Response-Time $upstream_response_time always;
+        add_header X-Upstream-Status $upstream_status always;
+    }
+    
+    # Health and debug endpoints
+    location /health {
+        proxy_pass http://{{.upstream_host}}:{{.upstream_port}};
+        access_log off;
+    }
+    
+    location /debug {
+        proxy_pass http://{{.upstream_host}}:{{.upstream_port}};
+        proxy_set_header X-Debug-Mode "enabled";
+    }
+    
+    # Enhanced logging for staging
+    access_log /var/log/nginx/{{.domain}}_access.log combined;
+    error_log /var/log/nginx/{{.domain}}_error.log info;
+}
+
+# SSL server block (conditional)
+{{if eq .ssl_enabled "yes"}}
+server {
+    listen 443 ssl http2;
+    server_name {{.domain}};
+    
+    ssl_certificate {{.ssl_cert}};
+    ssl_certificate_key {{.ssl_key}};
+    ssl_protocols TLSv1.2 TLSv1.3;
+    
+    # Same configuration as HTTP block above
+    {{if .auth_file}}auth_basic "Staging Environment - Authorized Access Only";
+    auth_basic_user_file {{.auth_file}};{{end}}
+    
+    add_header X-Environment "staging-ssl" always;
+    
+    location / {
+        proxy_pass http://{{.upstream_host}}:{{.upstream_port}};
+        proxy_http_version 1.1;
+        proxy_set_header Upgrade $http_upgrade;
+        proxy_set_header Connection 'upgrade';
+        proxy_set_header Host $host;
+        proxy_set_header X-Real-IP $remote_addr;
+        proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+        proxy_set_header X-Forwarded-Proto $scheme;
+    }
+}
+{{end}}
\ No newline at end of file
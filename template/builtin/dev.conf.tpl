@@ -0,0 +1,94 @@
+# Template: dev
+# Description: Development environment with minimal security and maximum debugging
+# Author: ngcli
+# Version: 1.1
+#
+# @param domain string required "Development domain" default="dev.local" pattern="^[^;{}#\"']+$"
+# @param upstream_host string required "Backend service host" default="127.0.0.1" pattern="^[^;{}#\"'\s]+$"
+# @param upstream_port integer required "Backend service port" default=3000
+# @param debug_mode string optional "Enable debug mode" default="on" options=["on","off"]
+
+server {
+    listen 80;
+    server_name {{.domain}};
+    
+    # Development-friendly settings
+    client_max_body_size 100m;
+    
+    # CORS headers for local development
+    add_header Access-Control-Allow-Origin "*" always;
+    add_header Access-Control-Allow-Methods "GET, POST, PUT, DELETE, OPTIONS, PATCH" always;
+    add_header Access-Control-Allow-Headers "DNT,User-Agent,X-Requested-With,If-Modified-Since,Cache-Control,Content-Type,Range,Authorization,X-API-Key" always;
+    add_header Access-Control-Expose-Headers "Content-Length,Content-Range,X-Request-ID" always;
+    
+    # Debug headers
+    add_header X-Environment "development" always;
+    add_header X-Debug-Mode "{{.debug_mode}}" always;
+    add_header X-Backend "{{.upstream_host}}:{{.upstream_port}}" always;
+    add_header X-Request-ID "$request_id" always;
+    add_header X-Response-Time "$upstream_response_time" always;
+    
+    # Handle preflight requests
+    location ~ ^/.*$ {
+        if ($request_method = 'OPTIONS') {
+            add_header Access-Control-Allow-Origin "*";
+            add_header Access-Control-Allow-Methods "GET, POST, PUT, DELETE, OPTIONS, PATCH";
+            add_header Access-Control-Allow-Headers "DNT,User-Agent,X-Requested-With,If-Modified-Since,Cache-Control,Content-Type,Range,Authorization,X-API-Key";
+            add_header Access-Control-Max-Age 1728000;
+            add_header Content-Type "text/plain charset=UTF-8";
+            add_header Content-Length 0;
+            return 204;
+        }
+    }
+    
+    # Main proxy configuration
+    location / {
+        proxy_pass http://{{.upstream_host}}:{{.upstream_port}};
+        proxy_http_version 1.1;
+        proxy_set_header Upgrade $http_upgrade;
+        proxy_set_header Connection 'upgrade';
+        proxy_set_header Host $host;
+        proxy_set_header X-Real-IP $remote_addr;
+        proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+        proxy_set_header X-Forwarded-Proto $scheme;
+        proxy_set_header X-Request-ID $request_id;
+        proxy_set_header X-Dev-Mode "true";
+        
+        # Very generous timeouts for debugging
+        proxy_connect_timeout 300s;
+        proxy_send_timeout 300s;
+        proxy_read_timeout 300s;
+        
+        # Disable buffering for real-time debugging
+        proxy_buffering off;
+        proxy_request_buffering off;
+        
+        # Debug response headers
+        add_header X-Upstream-Response-Time "$upstream_response_time" always;
+        add_header X-Upstream-Status "$upstream_status" always;
+        add_header X-Upstream-Address "$upstream_addr" always;
+    }
+    
+    # Development tools endpoints
+    location /dev-tools {
+        proxy_pass http://{{.upstream_host}}:{{.upstream_port}};
+        proxy_set_header X-Dev-Tools "enabled";
+    }
+    
+    location /metrics {
+        proxy_pass http://{{.upstream_host}}:{{.upstream_port}};
+        add_header X-Metrics-Access "dev-mode" always;
+    }
+    
+    # Hot reload support for development servers
+    location /hot-reload {
+        proxy_pass http://{{.upstream_host}}:{{.upstream_port}};
+        proxy_http_version 1.1;
+        proxy_set_header Upgrade $http_upgrade;
+        proxy_set_header Connection "upgrade";
+    }
+    
+    # Verbose logging for development
+    access_log "/var/log/nginx/{{.domain}}_access.log" combined;
+    error_log "/var/log/nginx/{{.domain}}_error.log" debug;
+}
\ No newline at end of file
@@ -0,0 +1,181 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMergeBuiltin(t *testing.T) {
+	upstream, _ := BuiltinContent("prod")
+	previous, ok := previousBuiltinContent("prod", "1.0")
+	if !ok {
+		t.Fatal("release 1.0 of prod is not embedded")
+	}
+	if previous == upstream {
+		t.Fatal("release 1.0 of prod equals upstream; the merge is not exercised")
+	}
+	tuned := strings.Replace(previous, "server_tokens off;", "server_tokens on;", 1)
+	renamed := strings.Replace(previous, `"Primary domain for the service"`, `"Public domain"`, 1)
+
+	tests := []struct {
+		name string
+		// copy is the user's copy and base the recorded base, if any
+		copy, base string
+		// want is the merge result, wantConflicts the conflicts in it
+		want          string
+		wantConflicts int
+		wantStatus    BuiltinStatus
+		wantErr       string
+	}{
+		{
+			name:       "clean merge",
+			copy:       tuned,
+			base:       previous,
+			want:       strings.Replace(upstream, "server_tokens off;", "server_tokens on;", 1),
+			wantStatus: BuiltinStatus{Tracked: true, Modified: true, UpdateAvailable: true},
+		},
+		{
+			name:          "conflicting merge",
+			copy:          renamed,
+			base:          previous,
+			wantConflicts: 1,
+			wantStatus:    BuiltinStatus{Tracked: true, Modified: true, UpdateAvailable: true},
+		},
+		{
+			name:       "untracked copy of an earlier release",
+			copy:       previous,
+			want:       upstream,
+			wantStatus: BuiltinStatus{UpdateAvailable: true},
+		},
+		{
+			name:       "untracked modified copy of an earlier release",
+			copy:       tuned,
+			want:       strings.Replace(upstream, "server_tokens off;", "server_tokens on;", 1),
+			wantStatus: BuiltinStatus{Modified: true, UpdateAvailable: true},
+		},
+		{
+			name:       "untracked copy of an unknown release",
+			copy:       strings.Replace(tuned, "# Version: 1.0", "# Version: 0.9", 1),
+			wantStatus: BuiltinStatus{Modified: true, UpdateAvailable: true},
+			wantErr:    "origin is unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templateDir := t.TempDir()
+			if err := os.WriteFile(builtinCopyPath("prod", templateDir), []byte(tt.copy), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if tt.base != "" {
+				if err := recordBuiltinBase("prod", tt.base, templateDir); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			status, err := GetBuiltinStatus("prod", templateDir)
+			if err != nil {
+				t.Fatalf("GetBuiltinStatus: %v", err)
+			}
+			if status.Tracked != tt.wantStatus.Tracked || status.Modified != tt.wantStatus.Modified || status.UpdateAvailable != tt.wantStatus.UpdateAvailable {
+				t.Errorf("status = %+v, want %+v", status, tt.wantStatus)
+			}
+
+			update, err := MergeBuiltin("prod", templateDir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("MergeBuiltin = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MergeBuiltin: %v", err)
+			}
+			if update.Conflicts != tt.wantConflicts || !update.Changed {
+				t.Fatalf("MergeBuiltin = %d conflict(s), changed %v; want %d, true", update.Conflicts, update.Changed, tt.wantConflicts)
+			}
+			if tt.wantConflicts > 0 {
+				if !strings.Contains(update.Merged, `"Public domain"`) || !strings.Contains(update.Merged, "<<<<<<<") {
+					t.Errorf("conflicting merge does not show both sides:\n%s", update.Merged)
+				}
+				if err := ApplyBuiltinUpdate(update, templateDir); err == nil {
+					t.Errorf("ApplyBuiltinUpdate with conflicts succeeded")
+				}
+				return
+			}
+			if update.Merged != tt.want {
+				t.Fatalf("merge result differs from the expected one:\n%s", update.Merged)
+			}
+
+			if err := ApplyBuiltinUpdate(update, templateDir); err != nil {
+				t.Fatalf("ApplyBuiltinUpdate: %v", err)
+			}
+			if base, _ := os.ReadFile(filepath.Join(templateDir, builtinBaseDir, "prod.conf.tpl")); string(base) != upstream {
+				t.Errorf("recorded base is not upstream after the update")
+			}
+			if status, _ := GetBuiltinStatus("prod", templateDir); !status.Tracked || status.UpdateAvailable {
+				t.Errorf("status after the update = %+v, want tracked and current", status)
+			}
+		})
+	}
+}
+
+func TestBuiltinNamesSkipEarlierReleases(t *testing.T) {
+	names := BuiltinNames()
+	if strings.Join(names, ",") != "dev,prod,staging" {
+		t.Errorf("BuiltinNames = %v, want [dev prod staging]", names)
+	}
+}
+
+func TestBuiltinTemplatesAcceptServerNames(t *testing.T) {
+	tests := []struct {
+		domain, upstream string
+		// invalid names the parameters expected to be rejected, by the
+		// pattern, the injection check or both
+		invalid []string
+	}{
+		{domain: "example.com", upstream: "127.0.0.1"},
+		{domain: "example.com www.example.com", upstream: "backend.internal"},
+		{domain: "*.example.com .example.org", upstream: "[::1]"},
+		{domain: `~^(?<sub>\w+)\.example\.com$`, upstream: "app_1"},
+		{domain: "example.com; include /etc/passwd", upstream: "127.0.0.1 backup", invalid: []string{"domain", "upstream_host"}},
+		{domain: "example.com #", upstream: "127.0.0.1", invalid: []string{"domain"}},
+	}
+
+	for _, name := range BuiltinNames() {
+		content, _ := BuiltinContent(name)
+		tmpl, err := ParseTemplate(name, name+".conf.tpl", content)
+		if err != nil {
+			t.Fatalf("ParseTemplate(%s): %v", name, err)
+		}
+
+		for _, tt := range tests {
+			params := map[string]string{"domain": tt.domain, "upstream_host": tt.upstream, "ssl_cert": "/etc/ssl/a.pem", "ssl_key": "/etc/ssl/a.key"}
+			err := tmpl.Metadata.ValidateParameters(tmpl.Metadata.ApplyDefaults(params))
+
+			var invalid []string
+			if verr, ok := err.(*ValidationError); ok {
+				for _, problem := range verr.Errors {
+					if len(invalid) == 0 || invalid[len(invalid)-1] != problem.Parameter {
+						invalid = append(invalid, problem.Parameter)
+					}
+				}
+			} else if err != nil {
+				t.Fatalf("%s: ValidateParameters: %v", name, err)
+			}
+			if strings.Join(invalid, ",") != strings.Join(tt.invalid, ",") {
+				t.Errorf("%s with domain %q, upstream %q: invalid %v, want %v (%v)", name, tt.domain, tt.upstream, invalid, tt.invalid, err)
+			}
+		}
+
+		rendered, err := tmpl.RenderWithValidation(map[string]string{"domain": "example.com www.example.com", "ssl_cert": "/etc/ssl/a.pem", "ssl_key": "/etc/ssl/a.key"})
+		if err != nil {
+			t.Fatalf("%s: RenderWithValidation: %v", name, err)
+		}
+		if !strings.Contains(rendered, "server_name example.com www.example.com;") {
+			t.Errorf("%s does not serve both names:\n%s", name, rendered)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// ConfigNameParameter is set by 'ngcli generate' to the name of the
+// configuration being generated. Templates and computed defaults can use it
+// without declaring it.
+const ConfigNameParameter = "config_name"
+
+// IsComputed reports whether the default of a parameter is a template
+// expression, such as "/var/log/nginx/{{.config_name}}.access.log".
+func (p ParameterInfo) IsComputed() bool {
+	return strings.Contains(p.Default, "{{")
+}
+
+// compileDefaults parses the computed defaults of m and orders them so that
+// every default is evaluated after the defaults it references.
+func (m *TemplateMetadata) compileDefaults() error {
+	declared := map[string]bool{ConfigNameParameter: true}
+	for _, param := range m.Parameters {
+		declared[param.Name] = true
+	}
+
+	m.defaults = make(map[string]*template.Template)
+	deps := make(map[string][]string)
+	for _, param := range m.Parameters {
+		if nested := param.nestedComputedDefault(); nested != "" {
+			return fmt.Errorf("parameter %s: %s cannot have a computed default", param.Name, nested)
+		}
+		if !param.IsComputed() {
+			continue
+		}
+		if !m.wrapped {
+			return fmt.Errorf("parameter %s: computed defaults need the metadata header wrapped in {{/* ... */}}, otherwise the expression is rendered into the configuration", param.Name)
+		}
+
+		tmpl, err := template.New(param.Name).Funcs(templateFuncs).Option("missingkey=error").Parse(param.Default)
+		if err != nil {
+			return fmt.Errorf("parameter %s: invalid computed default: %w", param.Name, err)
+		}
+		m.defaults[param.Name] = tmpl
+
+		w := &referenceWalker{content: param.Default}
+		w.walk(tmpl.Tree.Root, true)
+		for _, ref := range w.refs {
+			if !declared[ref.Name] {
+				return fmt.Errorf("parameter %s: computed default references undeclared parameter %s", param.Name, ref.Name)
+			}
+			deps[param.Name] = append(deps[param.Name], ref.Name)
+		}
+	}
+
+	// Depth-first topological sort; a default met again while its own
+	// dependencies are being visited is part of a cycle
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int)
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case done:
+			return nil
+		case visiting:
+			start := 0
+			for path[start] != name {
+				start++
+			}
+			cycle := append(append([]string{}, path[start:]...), name)
+			return fmt.Errorf("computed defaults form a cycle: %s", strings.Join(cycle, " -> "))
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range deps[name] {
+			if _, computed := m.defaults[dep]; !computed {
+				continue
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = done
+		m.defaultOrder = append(m.defaultOrder, name)
+		return nil
+	}
+
+	for _, param := range m.Parameters {
+		if _, computed := m.defaults[param.Name]; computed {
+			if err := visit(param.Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// nestedComputedDefault returns the name of an array item or object
+// property with a computed default; only parameters can have one.
+func (p ParameterInfo) nestedComputedDefault() string {
+	if p.Items != nil {
+		if p.Items.IsComputed() {
+			return "items"
+		}
+		if nested := p.Items.nestedComputedDefault(); nested != "" {
+			return "items: " + nested
+		}
+	}
+	for _, prop := range p.Properties {
+		if prop.IsComputed() {
+			return "property " + prop.Name
+		}
+		if nested := prop.nestedComputedDefault(); nested != "" {
+			return "property " + prop.Name + ": " + nested
+		}
+	}
+	return ""
+}
+
+// ComputeDefaults returns params with the default of every missing
+// parameter filled in. Computed defaults are evaluated in dependency order
+// against the values given and the defaults resolved so far; one that
+// evaluates to an empty string leaves its parameter unset. Expressions that
+// fail are reported as a *ValidationError.
+func (m *TemplateMetadata) ComputeDefaults(params map[string]string) (map[string]string, error) {
+	result := make(map[string]string)
+
+	// Copy existing parameters
+	for key, value := range params {
+		result[key] = value
+	}
+
+	// Apply defaults for missing parameters
+	for _, param := range m.Parameters {
+		if _, exists := result[param.Name]; !exists && param.Default != "" && !param.IsComputed() {
+			result[param.Name] = param.Default
+		}
+	}
+
+	verr := &ValidationError{}
+	for _, name := range m.defaultOrder {
+		if _, exists := result[name]; exists {
+			continue
+		}
+
+		var value strings.Builder
+		if err := m.defaults[name].Execute(&value, m.TypedValues(result)); err != nil {
+			verr.add(name, "failed to compute default: %v", err)
+			continue
+		}
+		if value.Len() > 0 {
+			result[name] = value.String()
+		}
+	}
+
+	if len(verr.Errors) > 0 {
+		return result, verr
+	}
+
+	return result, nil
+}
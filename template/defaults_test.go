@@ -0,0 +1,155 @@
+package template
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// defaultsHeader wraps @param lines in a metadata header that allows
+// computed defaults.
+func defaultsHeader(params ...string) string {
+	return "{{/*\n# Template: defaults\n# Version: 1.0\n# @param " + strings.Join(params, "\n# @param ") + "\n*/ -}}\nserver {}\n"
+}
+
+func TestComputeDefaults(t *testing.T) {
+	// Declared in reverse dependency order: access_log needs log_dir,
+	// which needs log_root
+	content := defaultsHeader(
+		`domain string required "Domain"`,
+		`access_log string optional "Access log" default="{{.log_dir}}/{{.domain}}.log"`,
+		`log_dir string optional "Log directory" default="{{.log_root}}/{{.config_name}}"`,
+		`log_root string optional "Log root" default="/var/log/nginx"`,
+		`alias string optional "Alias" default="{{if .www}}www.{{.domain}}{{end}}"`,
+		`www boolean optional "Serve www" default=false`,
+	)
+	tmpl, err := ParseTemplate("defaults", "defaults.conf.tpl", content)
+	if err != nil {
+		t.Fatalf("ParseTemplate: %v", err)
+	}
+	if want := []string{"log_dir", "access_log", "alias"}; !reflect.DeepEqual(tmpl.Metadata.defaultOrder, want) {
+		t.Errorf("evaluation order = %v, want %v", tmpl.Metadata.defaultOrder, want)
+	}
+
+	tests := []struct {
+		name   string
+		params map[string]string
+		want   map[string]string
+	}{
+		{
+			name:   "all computed",
+			params: map[string]string{"domain": "a.b", ConfigNameParameter: "site"},
+			want:   map[string]string{"access_log": "/var/log/nginx/site/a.b.log", "log_dir": "/var/log/nginx/site", "log_root": "/var/log/nginx"},
+		},
+		{
+			name:   "given value feeds the defaults after it",
+			params: map[string]string{"domain": "a.b", ConfigNameParameter: "site", "log_root": "/srv/logs"},
+			want:   map[string]string{"access_log": "/srv/logs/site/a.b.log", "log_dir": "/srv/logs/site", "log_root": "/srv/logs"},
+		},
+		{
+			name:   "given value is not recomputed",
+			params: map[string]string{"domain": "a.b", ConfigNameParameter: "site", "log_dir": "/tmp"},
+			want:   map[string]string{"access_log": "/tmp/a.b.log", "log_dir": "/tmp", "log_root": "/var/log/nginx"},
+		},
+		{
+			name:   "conditional default",
+			params: map[string]string{"domain": "a.b", ConfigNameParameter: "site", "www": "true"},
+			want:   map[string]string{"access_log": "/var/log/nginx/site/a.b.log", "log_dir": "/var/log/nginx/site", "log_root": "/var/log/nginx", "alias": "www.a.b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tmpl.Metadata.ComputeDefaults(tt.params)
+			if err != nil {
+				t.Fatalf("ComputeDefaults: %v", err)
+			}
+			for _, name := range []string{"access_log", "log_dir", "log_root", "alias"} {
+				if got[name] != tt.want[name] {
+					t.Errorf("%s = %q, want %q", name, got[name], tt.want[name])
+				}
+			}
+			// An empty default leaves its parameter unset
+			if _, set := got["alias"]; set != (tt.want["alias"] != "") {
+				t.Errorf("alias set = %v, want %v", set, !set)
+			}
+		})
+	}
+}
+
+func TestComputeDefaultsReportsFailures(t *testing.T) {
+	content := defaultsHeader(
+		`port integer optional "Port" default=80`,
+		`upstream string optional "Upstream" default="{{index .port 5}}"`,
+	)
+	tmpl, err := ParseTemplate("defaults", "defaults.conf.tpl", content)
+	if err != nil {
+		t.Fatalf("ParseTemplate: %v", err)
+	}
+
+	_, err = tmpl.Metadata.ComputeDefaults(map[string]string{})
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Errors) != 1 || verr.Errors[0].Parameter != "upstream" {
+		t.Fatalf("ComputeDefaults = %v, want one problem with upstream", err)
+	}
+	if !strings.HasPrefix(verr.Errors[0].Message, "failed to compute default") {
+		t.Errorf("message = %q", verr.Errors[0].Message)
+	}
+}
+
+func TestCompileDefaultsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name: "self reference",
+			content: defaultsHeader(
+				`a string optional "A" default="{{.a}}"`,
+			),
+			wantErr: "computed defaults form a cycle: a -> a",
+		},
+		{
+			name: "cycle",
+			content: defaultsHeader(
+				`domain string required "Domain"`,
+				`a string optional "A" default="{{.b}}"`,
+				`b string optional "B" default="{{.c}}.{{.domain}}"`,
+				`c string optional "C" default="{{.a}}"`,
+			),
+			wantErr: "computed defaults form a cycle: a -> b -> c -> a",
+		},
+		{
+			name: "cycle entered from outside",
+			content: defaultsHeader(
+				`x string optional "X" default="{{.a}}"`,
+				`a string optional "A" default="{{.b}}"`,
+				`b string optional "B" default="{{.a}}"`,
+			),
+			wantErr: "computed defaults form a cycle: a -> b -> a",
+		},
+		{
+			name: "undeclared reference",
+			content: defaultsHeader(
+				`a string optional "A" default="{{.domain}}"`,
+			),
+			wantErr: "references undeclared parameter domain",
+		},
+		{
+			name:    "unwrapped header",
+			content: "# Template: defaults\n# @param a string optional \"A\" default=\"{{.config_name}}\"\nserver {}\n",
+			wantErr: "need the metadata header wrapped",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseTemplate("defaults", "defaults.conf.tpl", tt.content)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ParseTemplate = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,439 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TemplateDoc is the documentation of one template, as published by
+// "ngcli template docs".
+type TemplateDoc struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Author      string         `json:"author,omitempty"`
+	Version     string         `json:"version,omitempty"`
+	Parameters  []ParameterDoc `json:"parameters"`
+	Rules       []string       `json:"rules,omitempty"`
+	Examples    []string       `json:"examples"`
+	Sample      string         `json:"sample,omitempty"`
+	SampleError string         `json:"sample_error,omitempty"`
+}
+
+// ParameterDoc is the documentation of one template parameter.
+type ParameterDoc struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Required    bool     `json:"required"`
+	Description string   `json:"description,omitempty"`
+	Default     string   `json:"default,omitempty"`
+	Options     []string `json:"options,omitempty"`
+	Constraints string   `json:"constraints,omitempty"`
+	When        string   `json:"when,omitempty"`
+	Deprecated  string   `json:"deprecated,omitempty"`
+	Sensitive   bool     `json:"sensitive,omitempty"`
+}
+
+// SampleValue returns a value for param that satisfies its declared type,
+// options and bounds, for examples and sample renders.
+func (p ParameterInfo) SampleValue() string {
+	if p.Type == "secret" {
+		return "env:" + strings.ToUpper(p.Name)
+	}
+	// Raw parameters usually hold directives; a comment is valid wherever
+	// a directive is
+	if p.Type == "raw" && p.Default == "" {
+		return "# " + p.Name
+	}
+	if p.Sensitive {
+		return "<" + p.Name + ">"
+	}
+	if p.Default != "" && !p.IsComputed() {
+		return p.Default
+	}
+	if len(p.Options) > 0 {
+		return p.Options[0]
+	}
+
+	switch p.jsonType() {
+	case "array":
+		text, _ := encodeValue([]interface{}{p.Items.typedValue(p.Items.SampleValue())})
+		return text
+	case "object":
+		object := make(map[string]interface{})
+		for _, prop := range p.Properties {
+			if prop.Required && prop.Default == "" {
+				object[prop.Name] = prop.typedValue(prop.SampleValue())
+			}
+		}
+		text, _ := encodeValue(object)
+		return text
+	}
+
+	switch p.Type {
+	case "integer":
+		n := 8080.0
+		if strings.Contains(p.Name, "port") {
+			n = 80
+		}
+		if p.Min != nil && n < *p.Min {
+			n = *p.Min
+		}
+		if p.Max != nil && n > *p.Max {
+			n = *p.Max
+		}
+		return strconv.Itoa(int(n))
+	case "boolean":
+		return "true"
+	case "file_path":
+		switch {
+		case strings.Contains(p.Name, "key"):
+			return "/etc/ssl/private/example.com.key"
+		case strings.Contains(p.Name, "cert"):
+			return "/etc/ssl/certs/example.com.crt"
+		}
+		return "/var/www/example.com"
+	}
+
+	switch {
+	case strings.Contains(p.Name, "domain") || strings.Contains(p.Name, "server_name"):
+		return "example.com"
+	case strings.Contains(p.Name, "host"):
+		return "localhost"
+	}
+	return "example"
+}
+
+// SampleValues returns sample values for the required parameters without a
+// default, which is the smallest set of --set flags that renders the
+// template. Parameters only active under a @when condition are left out.
+func (m *TemplateMetadata) SampleValues() map[string]string {
+	values := make(map[string]string)
+	for _, param := range m.Parameters {
+		if param.Required && param.Default == "" && param.When == "" {
+			values[param.Name] = param.SampleValue()
+		}
+	}
+	return values
+}
+
+// fullSampleValues extends values with a sample for every optional
+// parameter that does not conflict with an earlier one.
+func (m *TemplateMetadata) fullSampleValues(values map[string]string) map[string]string {
+	full := make(map[string]string)
+	for key, value := range values {
+		full[key] = value
+	}
+	for _, param := range m.Parameters {
+		if _, exists := full[param.Name]; exists || param.Deprecated != "" || m.conflictsWithSet(param.Name, full) {
+			continue
+		}
+		if param.When != "" && !m.IsActive(param, m.ApplyDefaults(full)) {
+			continue
+		}
+		full[param.Name] = param.SampleValue()
+	}
+	return full
+}
+
+// ParameterDocs documents the parameters of m. Defaults of sensitive
+// parameters are left out.
+func (m *TemplateMetadata) ParameterDocs() []ParameterDoc {
+	docs := []ParameterDoc{}
+	for _, param := range m.Parameters {
+		pd := ParameterDoc{
+			Name:        param.Name,
+			Type:        param.DisplayType(),
+			Required:    param.Required,
+			Description: param.Description,
+			Options:     param.Options,
+			Constraints: param.constraintSummary(),
+			When:        param.When,
+			Deprecated:  param.Deprecated,
+			Sensitive:   param.Sensitive,
+		}
+		if !param.Sensitive {
+			pd.Default = param.Default
+		}
+		docs = append(docs, pd)
+	}
+	return docs
+}
+
+// RuleDocs describes the @require and @conflicts rules of m.
+func (m *TemplateMetadata) RuleDocs() []string {
+	var rules []string
+	for _, rule := range m.Requires {
+		rules = append(rules, fmt.Sprintf("%s required if %s is set", strings.Join(rule.Params, ", "), rule.Condition))
+	}
+	for _, group := range m.Conflicts {
+		rules = append(rules, fmt.Sprintf("only one of: %s", strings.Join(group, ", ")))
+	}
+	return rules
+}
+
+// BuildTemplateDoc documents tmpl, including example generate commands and
+// a sample render with SampleValues.
+func BuildTemplateDoc(tmpl *Template) TemplateDoc {
+	m := tmpl.Metadata
+
+	doc := TemplateDoc{
+		Name:        tmpl.Name,
+		Description: m.Description,
+		Author:      m.Author,
+		Version:     m.Version,
+		Parameters:  m.ParameterDocs(),
+		Rules:       m.RuleDocs(),
+	}
+
+	values := m.SampleValues()
+	doc.Examples = append(doc.Examples, exampleCommand(tmpl.Name, m, values))
+
+	full := m.fullSampleValues(values)
+	if len(full) > len(values) {
+		doc.Examples = append(doc.Examples, exampleCommand(tmpl.Name, m, full))
+	}
+
+	sample, err := tmpl.RenderWithValidation(values)
+	if err != nil {
+		doc.SampleError = err.Error()
+	} else {
+		doc.Sample = sample
+	}
+
+	return doc
+}
+
+func (m *TemplateMetadata) conflictsWithSet(name string, values map[string]string) bool {
+	for _, group := range m.Conflicts {
+		inGroup := false
+		for _, member := range group {
+			if member == name {
+				inGroup = true
+			}
+		}
+		if !inGroup {
+			continue
+		}
+		for _, member := range group {
+			if _, exists := values[member]; exists && member != name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// exampleCommand builds a generate command line, with --set flags in
+// declaration order.
+func exampleCommand(name string, m *TemplateMetadata, values map[string]string) string {
+	var cmd strings.Builder
+	cmd.WriteString("ngcli generate my-site --template " + name)
+
+	for _, param := range m.Parameters {
+		value, exists := values[param.Name]
+		if !exists {
+			continue
+		}
+		if strings.ContainsAny(value, " \t\"'$<>|&;*?[]{}()") {
+			if strings.Contains(value, "'") {
+				value = strconv.Quote(value)
+			} else {
+				value = "'" + value + "'"
+			}
+		}
+		cmd.WriteString(fmt.Sprintf(" --set %s=%s", param.Name, value))
+	}
+
+	return cmd.String()
+}
+
+// MarkdownDoc renders the documentation page of one template as Markdown.
+func MarkdownDoc(doc TemplateDoc) string {
+	var out strings.Builder
+
+	out.WriteString(fmt.Sprintf("# %s\n\n", doc.Name))
+	if doc.Description != "" {
+		out.WriteString(doc.Description + "\n\n")
+	}
+	if doc.Author != "" {
+		out.WriteString(fmt.Sprintf("- **Author:** %s\n", doc.Author))
+	}
+	if doc.Version != "" {
+		out.WriteString(fmt.Sprintf("- **Version:** %s\n", doc.Version))
+	}
+	if doc.Author != "" || doc.Version != "" {
+		out.WriteString("\n")
+	}
+
+	out.WriteString("## Parameters\n\n")
+	if len(doc.Parameters) == 0 {
+		out.WriteString("This template has no parameters.\n\n")
+	} else {
+		out.WriteString("| Name | Type | Required | Default | Options | Constraints | Description |\n")
+		out.WriteString("|------|------|----------|---------|---------|-------------|-------------|\n")
+		for _, p := range doc.Parameters {
+			required := "no"
+			if p.Required {
+				required = "yes"
+			}
+			out.WriteString(fmt.Sprintf("| `%s` | %s | %s | %s | %s | %s | %s |\n",
+				p.Name, p.Type, required, markdownCode(p.Default), markdownCode(strings.Join(p.Options, ", ")),
+				markdownCell(p.Constraints), markdownCell(parameterNotes(p))))
+		}
+		out.WriteString("\n")
+	}
+
+	if len(doc.Rules) > 0 {
+		out.WriteString("## Rules\n\n")
+		for _, rule := range doc.Rules {
+			out.WriteString(fmt.Sprintf("- %s\n", rule))
+		}
+		out.WriteString("\n")
+	}
+
+	out.WriteString("## Examples\n\n```bash\n")
+	for _, example := range doc.Examples {
+		out.WriteString(example + "\n")
+	}
+	out.WriteString("```\n\n")
+
+	out.WriteString("## Sample Output\n\n")
+	if doc.SampleError != "" {
+		out.WriteString(fmt.Sprintf("No sample could be rendered: %s\n", doc.SampleError))
+	} else {
+		out.WriteString("```nginx\n" + strings.TrimRight(doc.Sample, "\n") + "\n```\n")
+	}
+
+	return out.String()
+}
+
+// MarkdownIndex renders the catalog index page linking every template page.
+func MarkdownIndex(docs []TemplateDoc) string {
+	var out strings.Builder
+
+	out.WriteString("# Template Catalog\n\n")
+	out.WriteString("| Template | Version | Description |\n")
+	out.WriteString("|----------|---------|-------------|\n")
+	for _, doc := range sortedDocs(docs) {
+		out.WriteString(fmt.Sprintf("| [%s](%s) | %s | %s |\n", doc.Name, DocFileName(doc.Name, "md"), doc.Version, markdownCell(doc.Description)))
+	}
+
+	return out.String()
+}
+
+// DocFileName returns the page file name of a template. Package templates
+// ("acme/proxy") get a flat name ("acme-proxy.md").
+func DocFileName(name, ext string) string {
+	return strings.ReplaceAll(name, "/", "-") + "." + ext
+}
+
+func parameterNotes(p ParameterDoc) string {
+	notes := []string{p.Description}
+	if p.When != "" {
+		notes = append(notes, fmt.Sprintf("Only when %s.", p.When))
+	}
+	if p.Sensitive {
+		notes = append(notes, "Sensitive.")
+	}
+	if p.Deprecated != "" {
+		notes = append(notes, fmt.Sprintf("Deprecated: %s.", p.Deprecated))
+	}
+	return strings.Join(notes, " ")
+}
+
+func markdownCell(text string) string {
+	return strings.ReplaceAll(text, "|", `\|`)
+}
+
+func markdownCode(text string) string {
+	if text == "" {
+		return ""
+	}
+	return "`" + markdownCell(text) + "`"
+}
+
+func sortedDocs(docs []TemplateDoc) []TemplateDoc {
+	sorted := append([]TemplateDoc(nil), docs...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
+
+const htmlStyle = `body{font-family:sans-serif;max-width:960px;margin:2em auto;padding:0 1em;color:#222}
+table{border-collapse:collapse;width:100%}th,td{border:1px solid #ccc;padding:.3em .5em;text-align:left;vertical-align:top}
+th{background:#f4f4f4}pre{background:#f6f8fa;padding:1em;overflow-x:auto}code{font-family:monospace}`
+
+var htmlDocTemplate = htmltemplate.Must(htmltemplate.New("doc").Funcs(htmltemplate.FuncMap{
+	"join":  strings.Join,
+	"notes": parameterNotes,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Doc.Name}} - Template Catalog</title>
+<style>` + htmlStyle + `</style>
+</head>
+<body>
+<p><a href="index.html">Template Catalog</a></p>
+<h1>{{.Doc.Name}}</h1>
+{{with .Doc.Description}}<p>{{.}}</p>{{end}}
+<ul>
+{{with .Doc.Author}}<li><strong>Author:</strong> {{.}}</li>{{end}}
+{{with .Doc.Version}}<li><strong>Version:</strong> {{.}}</li>{{end}}
+</ul>
+<h2>Parameters</h2>
+{{if .Doc.Parameters}}<table>
+<tr><th>Name</th><th>Type</th><th>Required</th><th>Default</th><th>Options</th><th>Constraints</th><th>Description</th></tr>
+{{range .Doc.Parameters}}<tr><td><code>{{.Name}}</code></td><td>{{.Type}}</td><td>{{if .Required}}yes{{else}}no{{end}}</td><td>{{with .Default}}<code>{{.}}</code>{{end}}</td><td>{{join .Options ", "}}</td><td>{{.Constraints}}</td><td>{{notes .}}</td></tr>
+{{end}}</table>{{else}}<p>This template has no parameters.</p>{{end}}
+{{if .Doc.Rules}}<h2>Rules</h2>
+<ul>
+{{range .Doc.Rules}}<li>{{.}}</li>
+{{end}}</ul>{{end}}
+<h2>Examples</h2>
+<pre><code>{{join .Doc.Examples "\n"}}</code></pre>
+<h2>Sample Output</h2>
+{{if .Doc.SampleError}}<p>No sample could be rendered: {{.Doc.SampleError}}</p>{{else}}<pre><code>{{.Doc.Sample}}</code></pre>{{end}}
+</body>
+</html>
+`))
+
+var htmlIndexTemplate = htmltemplate.Must(htmltemplate.New("index").Funcs(htmltemplate.FuncMap{
+	"page": func(name string) string { return DocFileName(name, "html") },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Template Catalog</title>
+<style>` + htmlStyle + `</style>
+</head>
+<body>
+<h1>Template Catalog</h1>
+<table>
+<tr><th>Template</th><th>Version</th><th>Description</th></tr>
+{{range .}}<tr><td><a href="{{page .Name}}">{{.Name}}</a></td><td>{{.Version}}</td><td>{{.Description}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// HTMLDoc renders the documentation page of one template as HTML.
+func HTMLDoc(doc TemplateDoc) (string, error) {
+	var out bytes.Buffer
+	if err := htmlDocTemplate.Execute(&out, struct{ Doc TemplateDoc }{doc}); err != nil {
+		return "", fmt.Errorf("failed to render documentation for %s: %w", doc.Name, err)
+	}
+	return out.String(), nil
+}
+
+// HTMLIndex renders the catalog index page as HTML.
+func HTMLIndex(docs []TemplateDoc) (string, error) {
+	var out bytes.Buffer
+	if err := htmlIndexTemplate.Execute(&out, sortedDocs(docs)); err != nil {
+		return "", fmt.Errorf("failed to render documentation index: %w", err)
+	}
+	return out.String(), nil
+}
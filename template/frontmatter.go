@@ -0,0 +1,657 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// parameterTypes are the parameter types a template may declare.
+var parameterTypes = map[string]bool{
+	"string":    true,
+	"integer":   true,
+	"boolean":   true,
+	"file_path": true,
+	"array":     true,
+	"object":    true,
+	"secret":    true,
+	"raw":       true,
+}
+
+var (
+	frontMatterOpenRegex   = regexp.MustCompile(`^\{\{-?\s*/\*\s*---$`)
+	frontMatterCloseRegex  = regexp.MustCompile(`^---\s*\*/\s*(-?)\}\}$`)
+	frontMatterHashRegex   = regexp.MustCompile(`^#\s*---$`)
+	commentOpenRegex       = regexp.MustCompile(`^\{\{-?\s*/\*$`)
+	commentCloseRegex      = regexp.MustCompile(`^\*/\s*(-?)\}\}$`)
+	frontMatterNameRegex   = regexp.MustCompile(`^\w+$`)
+	frontMatterWhenRegex   = regexp.MustCompile(`^!?\w+(?:=\S+)?$`)
+	commentHeaderLineRegex = regexp.MustCompile(`^#\s*(?:(?:Template|Description|Author|Version):|@|$)`)
+)
+
+// frontMatter is the YAML metadata block of a template:
+//
+//	{{/* ---
+//	template: api
+//	parameters:
+//	  - name: domain
+//	    type: string
+//	    required: true
+//	--- */}}
+type frontMatter struct {
+	Template    string              `yaml:"template"`
+	Description string              `yaml:"description,omitempty"`
+	Author      string              `yaml:"author,omitempty"`
+	Version     string              `yaml:"version,omitempty"`
+	Parameters  []frontMatterParam  `yaml:"parameters,omitempty"`
+	Requires    []frontMatterRule   `yaml:"requires,omitempty"`
+	Conflicts   [][]string          `yaml:"conflicts,omitempty"`
+	Outputs     []frontMatterOutput `yaml:"outputs,omitempty"`
+}
+
+type frontMatterParam struct {
+	Name        string             `yaml:"name,omitempty"`
+	Type        string             `yaml:"type"`
+	Required    bool               `yaml:"required,omitempty"`
+	Description string             `yaml:"description,omitempty"`
+	Default     interface{}        `yaml:"default,omitempty"`
+	Options     []interface{}      `yaml:"options,omitempty,flow"`
+	Min         *float64           `yaml:"min,omitempty"`
+	Max         *float64           `yaml:"max,omitempty"`
+	MinLen      *int               `yaml:"minlen,omitempty"`
+	MaxLen      *int               `yaml:"maxlen,omitempty"`
+	Pattern     string             `yaml:"pattern,omitempty"`
+	Deprecated  string             `yaml:"deprecated,omitempty"`
+	Sensitive   bool               `yaml:"sensitive,omitempty"`
+	When        string             `yaml:"when,omitempty"`
+	Items       *frontMatterParam  `yaml:"items,omitempty"`
+	Properties  []frontMatterParam `yaml:"properties,omitempty"`
+}
+
+type frontMatterOutput struct {
+	Name string `yaml:"name"`
+	Path string `yaml:"path"`
+	Mode string `yaml:"mode,omitempty"`
+}
+
+type frontMatterRule struct {
+	Params []string `yaml:"params,flow"`
+	If     string   `yaml:"if"`
+}
+
+// headerBlock is the location of a metadata header in a template. Lines are
+// 1-based and inclusive.
+type headerBlock struct {
+	start, end int
+	// yaml is the front-matter document and yamlLine the line it starts on.
+	yaml     string
+	yamlLine int
+	// wrapped headers are inside a template comment; trim records whether
+	// the comment ends with "-}}".
+	wrapped bool
+	trim    bool
+}
+
+// findFrontMatter locates a YAML front-matter block at the top of content,
+// either "{{/* --- ... --- */}}" or a "# ---" block of comment lines.
+func findFrontMatter(content string) (headerBlock, bool, error) {
+	lines := strings.Split(content, "\n")
+	first := strings.TrimSpace(lines[0])
+
+	block := headerBlock{start: 1}
+	var body []string
+
+	switch {
+	case frontMatterOpenRegex.MatchString(first):
+		block.wrapped = true
+		block.yamlLine = 2
+	case commentOpenRegex.MatchString(first) && len(lines) > 1 && strings.TrimSpace(lines[1]) == "---":
+		block.wrapped = true
+		block.yamlLine = 3
+	case frontMatterHashRegex.MatchString(first):
+		block.yamlLine = 2
+	default:
+		return block, false, nil
+	}
+
+	for i := block.yamlLine - 1; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+
+		if block.wrapped {
+			if match := frontMatterCloseRegex.FindStringSubmatch(line); match != nil {
+				block.end = i + 1
+				block.trim = match[1] == "-"
+				break
+			}
+			if line == "---" && i+1 < len(lines) {
+				if match := commentCloseRegex.FindStringSubmatch(strings.TrimSpace(lines[i+1])); match != nil {
+					block.end = i + 2
+					block.trim = match[1] == "-"
+					break
+				}
+			}
+			body = append(body, lines[i])
+			continue
+		}
+
+		if frontMatterHashRegex.MatchString(line) {
+			block.end = i + 1
+			break
+		}
+		if !strings.HasPrefix(line, "#") {
+			break
+		}
+		body = append(body, strings.TrimPrefix(strings.TrimPrefix(line, "#"), " "))
+	}
+
+	if block.end == 0 {
+		return block, false, fmt.Errorf("line %d: YAML front-matter is not terminated by ---", block.start)
+	}
+
+	block.yaml = strings.Join(body, "\n")
+	return block, true, nil
+}
+
+func parseFrontMatter(block headerBlock) (*TemplateMetadata, error) {
+	var fm frontMatter
+	if err := yaml.UnmarshalStrict([]byte(block.yaml), &fm); err != nil {
+		return nil, fmt.Errorf("invalid YAML front-matter: %w", err)
+	}
+
+	metadata := &TemplateMetadata{
+		Name:        fm.Template,
+		Description: fm.Description,
+		Author:      fm.Author,
+		Version:     fm.Version,
+		Parameters:  make([]ParameterInfo, 0, len(fm.Parameters)),
+		Format:      MetadataFrontMatter,
+	}
+
+	yamlLines := strings.Split(block.yaml, "\n")
+	searchFrom := 0
+	seen := make(map[string]bool)
+
+	for _, fp := range fm.Parameters {
+		param, err := fp.toParameter(true)
+		if err != nil {
+			return nil, err
+		}
+		if seen[param.Name] {
+			return nil, fmt.Errorf("parameter %s is declared twice", param.Name)
+		}
+		seen[param.Name] = true
+
+		// YAML decoding loses positions; find the "name:" line instead
+		nameRegex := regexp.MustCompile(`^\s*(?:-\s*)?name:\s*["']?` + regexp.QuoteMeta(param.Name) + `["']?\s*$`)
+		for i := searchFrom; i < len(yamlLines); i++ {
+			if nameRegex.MatchString(yamlLines[i]) {
+				param.Line = block.yamlLine + i
+				searchFrom = i + 1
+				break
+			}
+		}
+
+		metadata.Parameters = append(metadata.Parameters, param)
+	}
+
+	for _, rule := range fm.Requires {
+		if len(rule.Params) == 0 || rule.If == "" {
+			return nil, fmt.Errorf("requires rules need params and if")
+		}
+		metadata.Requires = append(metadata.Requires, RequireRule{Params: rule.Params, Condition: rule.If})
+	}
+
+	for _, group := range fm.Conflicts {
+		if len(group) < 2 {
+			return nil, fmt.Errorf("conflicts need at least two parameters: %s", strings.Join(group, ", "))
+		}
+		metadata.Conflicts = append(metadata.Conflicts, group)
+	}
+
+	for _, fo := range fm.Outputs {
+		if !frontMatterNameRegex.MatchString(fo.Name) {
+			return nil, fmt.Errorf("invalid output name %q", fo.Name)
+		}
+		output := OutputFile{Name: fo.Name, Path: fo.Path}
+		if fo.Mode != "" {
+			mode, err := parseFileMode(fo.Mode)
+			if err != nil {
+				return nil, fmt.Errorf("output %s: %w", fo.Name, err)
+			}
+			output.Mode = mode
+		}
+		metadata.Outputs = append(metadata.Outputs, output)
+	}
+
+	return metadata, nil
+}
+
+// toParameter converts and checks a front-matter parameter. Array items are
+// the only parameters without a name.
+func (fp frontMatterParam) toParameter(named bool) (ParameterInfo, error) {
+	label := fp.Name
+	if !named {
+		label = "items"
+	}
+
+	if named && !frontMatterNameRegex.MatchString(fp.Name) {
+		return ParameterInfo{}, fmt.Errorf("invalid parameter name %q", fp.Name)
+	}
+	if !named && fp.Name != "" {
+		return ParameterInfo{}, fmt.Errorf("array items cannot have a name (%s)", fp.Name)
+	}
+	if !parameterTypes[fp.Type] {
+		return ParameterInfo{}, fmt.Errorf("parameter %s: unknown type %q", label, fp.Type)
+	}
+	if !named && fp.Type == "secret" {
+		return ParameterInfo{}, fmt.Errorf("array items cannot be secrets")
+	}
+	if fp.Items != nil && fp.Type != "array" {
+		return ParameterInfo{}, fmt.Errorf("parameter %s: items are only allowed for arrays", label)
+	}
+	if len(fp.Properties) > 0 && fp.Type != "object" {
+		return ParameterInfo{}, fmt.Errorf("parameter %s: properties are only allowed for objects", label)
+	}
+	if fp.When != "" && !frontMatterWhenRegex.MatchString(fp.When) {
+		return ParameterInfo{}, fmt.Errorf("parameter %s: invalid when condition %q", label, fp.When)
+	}
+	if fp.Pattern != "" {
+		if _, err := regexp.Compile(fp.Pattern); err != nil {
+			return ParameterInfo{}, fmt.Errorf("parameter %s: invalid pattern %q: %w", label, fp.Pattern, err)
+		}
+	}
+	if (fp.MinLen != nil && *fp.MinLen < 0) || (fp.MaxLen != nil && *fp.MaxLen < 0) {
+		return ParameterInfo{}, fmt.Errorf("parameter %s: minlen and maxlen cannot be negative", label)
+	}
+
+	param := ParameterInfo{
+		Name:        fp.Name,
+		Type:        fp.Type,
+		Required:    fp.Required,
+		Description: fp.Description,
+		Min:         fp.Min,
+		Max:         fp.Max,
+		MinLen:      fp.MinLen,
+		MaxLen:      fp.MaxLen,
+		Pattern:     fp.Pattern,
+		Deprecated:  fp.Deprecated,
+		Sensitive:   fp.Sensitive,
+		When:        fp.When,
+	}
+	if param.Deprecated == "true" {
+		param.Deprecated = "no longer used"
+	}
+
+	if fp.Default != nil {
+		value, err := encodeValue(fp.Default)
+		if err != nil {
+			return ParameterInfo{}, fmt.Errorf("parameter %s: invalid default: %w", label, err)
+		}
+		param.Default = value
+	}
+	for _, option := range fp.Options {
+		value, ok := scalarString(option)
+		if !ok {
+			return ParameterInfo{}, fmt.Errorf("parameter %s: options must be single values", label)
+		}
+		param.Options = append(param.Options, value)
+	}
+
+	if fp.Items != nil {
+		items, err := fp.Items.toParameter(false)
+		if err != nil {
+			return ParameterInfo{}, fmt.Errorf("parameter %s: %w", label, err)
+		}
+		param.Items = &items
+	}
+
+	seen := make(map[string]bool)
+	for _, fprop := range fp.Properties {
+		prop, err := fprop.toParameter(true)
+		if err != nil {
+			return ParameterInfo{}, fmt.Errorf("parameter %s: %w", label, err)
+		}
+		if prop.When != "" {
+			return ParameterInfo{}, fmt.Errorf("parameter %s: object properties cannot have when conditions", label)
+		}
+		if prop.Type == "secret" {
+			return ParameterInfo{}, fmt.Errorf("parameter %s: object properties cannot be secrets", label)
+		}
+		if seen[prop.Name] {
+			return ParameterInfo{}, fmt.Errorf("parameter %s: property %s is declared twice", label, prop.Name)
+		}
+		seen[prop.Name] = true
+		param.Properties = append(param.Properties, prop)
+	}
+
+	return param, nil
+}
+
+func toFrontMatterParam(p ParameterInfo) frontMatterParam {
+	fp := frontMatterParam{
+		Name:        p.Name,
+		Type:        p.Type,
+		Required:    p.Required,
+		Description: p.Description,
+		Min:         p.Min,
+		Max:         p.Max,
+		MinLen:      p.MinLen,
+		MaxLen:      p.MaxLen,
+		Pattern:     p.Pattern,
+		Deprecated:  p.Deprecated,
+		Sensitive:   p.Sensitive && p.Type != "secret",
+		When:        p.When,
+	}
+	if p.Default != "" {
+		fp.Default = p.typedValue(p.Default)
+	}
+	for _, option := range p.Options {
+		fp.Options = append(fp.Options, p.typedValue(option))
+	}
+	if p.Items != nil {
+		items := toFrontMatterParam(*p.Items)
+		fp.Items = &items
+	}
+	for _, prop := range p.Properties {
+		fp.Properties = append(fp.Properties, toFrontMatterParam(prop))
+	}
+	return fp
+}
+
+// MigrateMetadata rewrites the metadata header of a template in another
+// format (MetadataComments or MetadataFrontMatter). The rest of the template
+// is left untouched.
+func MigrateMetadata(content, format string) (string, error) {
+	if format != MetadataComments && format != MetadataFrontMatter {
+		return "", fmt.Errorf("unknown metadata format %q (use %s or %s)", format, MetadataComments, MetadataFrontMatter)
+	}
+
+	metadata, err := ParseTemplateMetadata(content)
+	if err != nil {
+		return "", err
+	}
+	if metadata.Format == format {
+		return "", fmt.Errorf("template already uses %s metadata", format)
+	}
+
+	lines := strings.Split(content, "\n")
+
+	var block headerBlock
+	var kept []string
+	if metadata.Format == MetadataFrontMatter {
+		block, _, err = findFrontMatter(content)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		block, kept = commentHeader(lines)
+		if block.end == 0 {
+			return "", fmt.Errorf("template has no metadata header")
+		}
+	}
+
+	var header string
+	if format == MetadataFrontMatter {
+		header, err = frontMatterHeader(metadata, block, kept)
+	} else {
+		header, err = commentMetadataHeader(metadata, block)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	body := strings.Join(lines[block.end:], "\n")
+	if block.end == len(lines) {
+		return header, nil
+	}
+	return header + "\n" + body, nil
+}
+
+// commentHeader locates a comment-syntax header. Free-form comments between
+// metadata lines are returned so they can be preserved.
+func commentHeader(lines []string) (headerBlock, []string) {
+	block := headerBlock{start: 1}
+	var kept []string
+
+	i := 0
+	if commentOpenRegex.MatchString(strings.TrimSpace(lines[0])) {
+		block.wrapped = true
+		i = 1
+	}
+
+	var pending []string
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+
+		if block.wrapped {
+			if match := commentCloseRegex.FindStringSubmatch(line); match != nil {
+				block.end = i + 1
+				block.trim = match[1] == "-"
+				kept = append(kept, pending...)
+				return block, kept
+			}
+		} else if line != "" && !strings.HasPrefix(line, "#") {
+			break
+		}
+
+		switch {
+		case commentHeaderLineRegex.MatchString(line):
+			block.end = i + 1
+			kept = append(kept, pending...)
+			pending = nil
+		case line != "":
+			pending = append(pending, line)
+		}
+	}
+
+	return block, kept
+}
+
+func frontMatterHeader(metadata *TemplateMetadata, block headerBlock, kept []string) (string, error) {
+	fm := frontMatter{
+		Template:    metadata.Name,
+		Description: metadata.Description,
+		Author:      metadata.Author,
+		Version:     metadata.Version,
+		Conflicts:   metadata.Conflicts,
+	}
+	for _, param := range metadata.Parameters {
+		fm.Parameters = append(fm.Parameters, toFrontMatterParam(param))
+	}
+	for _, rule := range metadata.Requires {
+		fm.Requires = append(fm.Requires, frontMatterRule{Params: rule.Params, If: rule.Condition})
+	}
+	for _, output := range metadata.Outputs {
+		fo := frontMatterOutput{Name: output.Name, Path: output.Path}
+		if output.Mode != 0 {
+			fo.Mode = FormatFileMode(output.Mode)
+		}
+		fm.Outputs = append(fm.Outputs, fo)
+	}
+
+	data, err := yaml.Marshal(fm)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode front-matter: %w", err)
+	}
+	doc := strings.TrimRight(string(data), "\n")
+
+	var out strings.Builder
+	if block.wrapped {
+		out.WriteString("{{/* ---\n")
+		out.WriteString(doc + "\n")
+		for _, comment := range kept {
+			out.WriteString(comment + "\n")
+		}
+		out.WriteString(closingMarker("---", block.trim))
+		return out.String(), nil
+	}
+
+	out.WriteString("# ---\n")
+	for _, line := range strings.Split(doc, "\n") {
+		out.WriteString(strings.TrimRight("# "+line, " ") + "\n")
+	}
+	out.WriteString("# ---")
+	for _, comment := range kept {
+		out.WriteString("\n" + comment)
+	}
+	return out.String(), nil
+}
+
+func commentMetadataHeader(metadata *TemplateMetadata, block headerBlock) (string, error) {
+	var lines []string
+	if metadata.Name != "" {
+		lines = append(lines, "# Template: "+metadata.Name)
+	}
+	if metadata.Description != "" {
+		lines = append(lines, "# Description: "+metadata.Description)
+	}
+	if metadata.Author != "" {
+		lines = append(lines, "# Author: "+metadata.Author)
+	}
+	if metadata.Version != "" {
+		lines = append(lines, "# Version: "+metadata.Version)
+	}
+	for _, line := range lines {
+		if strings.ContainsAny(line, "\r\n") {
+			return "", fmt.Errorf("multi-line header values cannot be expressed in comment metadata")
+		}
+	}
+	lines = append(lines, "#")
+
+	when := ""
+	for _, param := range metadata.Parameters {
+		line, err := commentParamLine(param)
+		if err != nil {
+			return "", err
+		}
+		if param.When != when {
+			if when != "" {
+				lines = append(lines, "# @end")
+			}
+			if param.When != "" {
+				lines = append(lines, "# @when "+param.When)
+			}
+			when = param.When
+		}
+		lines = append(lines, line)
+	}
+	if when != "" {
+		lines = append(lines, "# @end")
+	}
+
+	for _, rule := range metadata.Requires {
+		lines = append(lines, fmt.Sprintf("# @require %s if %s", strings.Join(rule.Params, ", "), rule.Condition))
+	}
+	for _, group := range metadata.Conflicts {
+		lines = append(lines, fmt.Sprintf("# @conflicts %s", strings.Join(group, ", ")))
+	}
+	for _, output := range metadata.Outputs {
+		if strings.ContainsAny(output.Path, "\r\n") || strings.HasSuffix(output.Path, `\`) {
+			return "", fmt.Errorf("output %s: path %q cannot be expressed in comment metadata", output.Name, output.Path)
+		}
+		line := fmt.Sprintf(`# @output %s "%s"`, output.Name, strings.ReplaceAll(output.Path, `"`, `\"`))
+		if output.Mode != 0 {
+			line += " mode=" + FormatFileMode(output.Mode)
+		}
+		lines = append(lines, line)
+	}
+
+	if !block.wrapped {
+		return strings.Join(lines, "\n"), nil
+	}
+	return "{{/*\n" + strings.Join(lines, "\n") + "\n" + closingMarker("", block.trim), nil
+}
+
+// commentParamLine formats a parameter as an "# @param" line.
+func commentParamLine(p ParameterInfo) (string, error) {
+	if p.Items != nil || len(p.Properties) > 0 || p.Type == "object" {
+		return "", fmt.Errorf("parameter %s uses nested types, which comment metadata cannot express", p.Name)
+	}
+
+	required := "optional"
+	if p.Required {
+		required = "required"
+	}
+
+	attrs := []string{}
+	quote := func(key, value string) error {
+		if strings.ContainsAny(value, "\r\n") || strings.HasSuffix(value, `\`) {
+			return fmt.Errorf("parameter %s: %s value %q cannot be expressed in comment metadata", p.Name, key, value)
+		}
+		attrs = append(attrs, fmt.Sprintf(`%s="%s"`, key, strings.ReplaceAll(value, `"`, `\"`)))
+		return nil
+	}
+
+	if p.Default != "" {
+		if p.Type == "integer" {
+			if _, err := strconv.Atoi(p.Default); err == nil {
+				attrs = append(attrs, "default="+p.Default)
+			}
+		}
+		if len(attrs) == 0 {
+			if err := quote("default", p.Default); err != nil {
+				return "", err
+			}
+		}
+	}
+	if len(p.Options) > 0 {
+		var options []string
+		for _, option := range p.Options {
+			if strings.ContainsAny(option, `",[]`) {
+				return "", fmt.Errorf("parameter %s: option %q cannot be expressed in comment metadata", p.Name, option)
+			}
+			options = append(options, `"`+option+`"`)
+		}
+		attrs = append(attrs, "options=["+strings.Join(options, ",")+"]")
+	}
+	if p.Min != nil {
+		attrs = append(attrs, "min="+formatNumber(*p.Min))
+	}
+	if p.Max != nil {
+		attrs = append(attrs, "max="+formatNumber(*p.Max))
+	}
+	if p.MinLen != nil {
+		attrs = append(attrs, fmt.Sprintf("minlen=%d", *p.MinLen))
+	}
+	if p.MaxLen != nil {
+		attrs = append(attrs, fmt.Sprintf("maxlen=%d", *p.MaxLen))
+	}
+	if p.Pattern != "" {
+		if err := quote("pattern", p.Pattern); err != nil {
+			return "", err
+		}
+	}
+	if p.Deprecated != "" {
+		if err := quote("deprecated", p.Deprecated); err != nil {
+			return "", err
+		}
+	}
+	if p.Sensitive && p.Type != "secret" {
+		attrs = append(attrs, "sensitive")
+	}
+
+	if strings.ContainsAny(p.Description, "\r\n") {
+		return "", fmt.Errorf("parameter %s: multi-line descriptions cannot be expressed in comment metadata", p.Name)
+	}
+	description := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(p.Description)
+
+	line := fmt.Sprintf(`# @param %s %s %s "%s"`, p.Name, p.Type, required, description)
+	if len(attrs) > 0 {
+		line += " " + strings.Join(attrs, " ")
+	}
+	return line, nil
+}
+
+func closingMarker(prefix string, trim bool) string {
+	marker := "*/}}"
+	if trim {
+		marker = "*/ -}}"
+	}
+	if prefix != "" {
+		return prefix + " " + marker
+	}
+	return marker
+}
@@ -0,0 +1,181 @@
+package template
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// commentsTemplate uses every kind of comment metadata, with a free-form
+// comment inside the header.
+const commentsTemplate = `{{/*
+# Template: api
+# Description: API gateway with "quotes" and a \ backslash
+# Author: ops
+# Version: 2.1
+#
+# Upstream settings
+# @param domain string required "Public domain" pattern="^[a-z0-9.-]+$" minlen=3 maxlen=253
+# @param port integer optional "Listen port" default=443 min=1 max=65535
+# @param mode string optional "Mode" default="proxy" options=["proxy","static"]
+# @param token string optional "Token" sensitive
+# @param legacy string optional "Old name" deprecated="use domain"
+# @when mode=static
+# @param root file_path required "Document root"
+# @end
+# @param ssl_cert file_path optional "Certificate"
+# @param ssl_key file_path optional "Key"
+# @require ssl_key if ssl_cert
+# @conflicts token legacy
+# @output htpasswd "/etc/nginx/{{.domain}}.htpasswd" mode=0640
+*/ -}}
+server {
+    server_name "{{.domain}}";
+    listen {{.port}} ssl;
+}
+{{define "htpasswd"}}admin:{{.token}}{{end}}
+`
+
+// comparableMetadata drops what depends on the header layout: line
+// numbers, the format and parse caches.
+func comparableMetadata(t *testing.T, content string) TemplateMetadata {
+	t.Helper()
+	m, err := ParseTemplateMetadata(content)
+	if err != nil {
+		t.Fatalf("ParseTemplateMetadata: %v\n%s", err, content)
+	}
+	if len(m.Warnings) > 0 {
+		t.Errorf("warnings = %q\n%s", m.Warnings, content)
+	}
+	out := TemplateMetadata{
+		Name:        m.Name,
+		Description: m.Description,
+		Author:      m.Author,
+		Version:     m.Version,
+		Requires:    m.Requires,
+		Conflicts:   m.Conflicts,
+	}
+	for _, param := range m.Parameters {
+		param.Line = 0
+		out.Parameters = append(out.Parameters, param)
+	}
+	for _, output := range m.Outputs {
+		output.Line = 0
+		out.Outputs = append(out.Outputs, output)
+	}
+	return out
+}
+
+func TestMigrateMetadataRoundTrip(t *testing.T) {
+	want := comparableMetadata(t, commentsTemplate)
+	body := commentsTemplate[strings.Index(commentsTemplate, "server {"):]
+
+	yamlContent, err := MigrateMetadata(commentsTemplate, MetadataFrontMatter)
+	if err != nil {
+		t.Fatalf("MigrateMetadata(yaml): %v", err)
+	}
+	if m, _ := ParseTemplateMetadata(yamlContent); m == nil || m.Format != MetadataFrontMatter {
+		t.Fatalf("migrated template does not use front-matter:\n%s", yamlContent)
+	}
+	if got := comparableMetadata(t, yamlContent); !reflect.DeepEqual(got, want) {
+		t.Errorf("front-matter metadata = %+v\nwant %+v", got, want)
+	}
+	if !strings.HasSuffix(yamlContent, "*/ -}}\n"+body) {
+		t.Errorf("body changed by the migration:\n%s", yamlContent)
+	}
+	if !strings.Contains(yamlContent, "Upstream settings") {
+		t.Errorf("free-form header comment dropped:\n%s", yamlContent)
+	}
+
+	commentContent, err := MigrateMetadata(yamlContent, MetadataComments)
+	if err != nil {
+		t.Fatalf("MigrateMetadata(comments): %v", err)
+	}
+	if got := comparableMetadata(t, commentContent); !reflect.DeepEqual(got, want) {
+		t.Errorf("comment metadata = %+v\nwant %+v", got, want)
+	}
+	if !strings.HasSuffix(commentContent, body) {
+		t.Errorf("body changed by the migration back:\n%s", commentContent)
+	}
+
+	// Both forms render the same configuration
+	params := map[string]string{"domain": "api.example.com"}
+	var rendered []string
+	for _, content := range []string{commentsTemplate, yamlContent, commentContent} {
+		tmpl, err := ParseTemplate("api", "api.conf.tpl", content)
+		if err != nil {
+			t.Fatalf("ParseTemplate: %v", err)
+		}
+		out, err := tmpl.RenderWithValidation(params)
+		if err != nil {
+			t.Fatalf("RenderWithValidation: %v", err)
+		}
+		rendered = append(rendered, out)
+	}
+	if rendered[0] != rendered[1] || rendered[0] != rendered[2] {
+		t.Errorf("renders differ:\n%q\n%q\n%q", rendered[0], rendered[1], rendered[2])
+	}
+
+	if _, err := MigrateMetadata(yamlContent, MetadataFrontMatter); err == nil {
+		t.Errorf("migrating to the format in use succeeded")
+	}
+}
+
+func TestParseFrontMatter(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "wrapped",
+			content: "{{/* ---\ntemplate: api\nversion: \"1.0\"\nparameters:\n  - name: upstreams\n    type: array\n    items:\n      type: object\n      properties:\n        - {name: host, type: string, required: true}\n        - {name: port, type: integer, min: 1}\n--- */ -}}\nserver {}\n",
+		},
+		{
+			name:    "comment lines",
+			content: "# ---\n# template: api\n# version: \"1.0\"\n# parameters:\n#   - {name: upstreams, type: array, items: {type: object, properties: [{name: host, type: string, required: true}, {name: port, type: integer, min: 1}]}}\n# ---\nserver {}\n",
+		},
+		{
+			name:    "unterminated",
+			content: "{{/* ---\ntemplate: api\nserver {}\n",
+			wantErr: "not terminated",
+		},
+		{
+			name:    "unknown key",
+			content: "# ---\n# template: api\n# params: []\n# ---\n",
+			wantErr: "params",
+		},
+		{
+			name:    "unknown type",
+			content: "# ---\n# template: api\n# parameters:\n#   - {name: port, type: number}\n# ---\n",
+			wantErr: "number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseTemplateMetadata(tt.content)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseTemplateMetadata = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTemplateMetadata: %v", err)
+			}
+			if m.Format != MetadataFrontMatter || m.Name != "api" || m.Version != "1.0" || len(m.Parameters) != 1 {
+				t.Fatalf("metadata = %+v", m)
+			}
+			items := m.Parameters[0].Items
+			if items == nil || items.Type != "object" || len(items.Properties) != 2 || !items.Properties[0].Required || *items.Properties[1].Min != 1 {
+				t.Errorf("items = %+v, want an object with host and port", items)
+			}
+
+			// Nested types have no comment syntax
+			if _, err := MigrateMetadata(tt.content, MetadataComments); err == nil {
+				t.Errorf("migrating nested types to comments succeeded")
+			}
+		})
+	}
+}
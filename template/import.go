@@ -0,0 +1,229 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/vourteen14/ngcli/nginxconf"
+	"github.com/vourteen14/ngcli/utils"
+)
+
+// ImportedParameter is a literal of an imported nginx config that was
+// replaced by a template parameter.
+type ImportedParameter struct {
+	Name        string
+	Type        string
+	Description string
+	Value       string
+	Occurrences int
+}
+
+// ImportResult is a template reverse-engineered from an nginx config.
+// Rendering Content with Values reproduces the original config exactly.
+type ImportResult struct {
+	Content    string
+	Parameters []*ImportedParameter
+	Values     map[string]string
+}
+
+var (
+	listenPortRegex = regexp.MustCompile(`(?:^|:)(\d+)$`)
+	proxyPassRegex  = regexp.MustCompile(`^(?:https?|grpcs?)://([A-Za-z0-9._-]+)(?::(\d+))?`)
+)
+
+type importReplacement struct {
+	start int
+	end   int
+	param string
+}
+
+type importer struct {
+	content      string
+	params       []*ImportedParameter
+	byValue      map[string]*ImportedParameter
+	names        map[string]bool
+	replacements []importReplacement
+}
+
+// ImportConfig detects candidate parameters in an nginx config (server
+// names, listen ports, root paths, certificate paths and proxy_pass hosts)
+// and turns it into a template named name.
+func ImportConfig(name, source, content string) (*ImportResult, error) {
+	directives, err := nginxconf.Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", source, err)
+	}
+
+	im := &importer{
+		content: content,
+		byValue: make(map[string]*ImportedParameter),
+		names:   make(map[string]bool),
+	}
+
+	// proxy_pass targets that name an upstream block stay literal
+	upstreams := make(map[string]bool)
+	nginxconf.Walk(directives, func(d *nginxconf.Directive, parents []string) {
+		if d.Name == "upstream" && len(d.Args) == 1 {
+			upstreams[d.Args[0].Value] = true
+		}
+	})
+
+	nginxconf.Walk(directives, func(d *nginxconf.Directive, parents []string) {
+		switch d.Name {
+		case "server_name":
+			for _, arg := range d.Args {
+				if arg.Value == "_" || arg.Value == "" || strings.HasPrefix(arg.Value, "~") || strings.Contains(arg.Value, "$") {
+					continue
+				}
+				im.literal(arg, 0, len(arg.Value), "domain", "string", "Server name")
+			}
+		case "listen":
+			if len(d.Args) == 0 || strings.HasPrefix(d.Args[0].Value, "unix:") {
+				return
+			}
+			match := listenPortRegex.FindStringSubmatchIndex(d.Args[0].Value)
+			if match == nil {
+				return
+			}
+			base, description := "port", "Listen port"
+			for _, arg := range d.Args[1:] {
+				if arg.Value == "ssl" || arg.Value == "quic" {
+					base, description = "ssl_port", "HTTPS listen port"
+				}
+			}
+			im.literal(d.Args[0], match[2], match[3], base, "integer", description)
+		case "root":
+			if len(d.Args) == 1 && !strings.Contains(d.Args[0].Value, "$") {
+				im.literal(d.Args[0], 0, len(d.Args[0].Value), "root_path", "file_path", "Document root path")
+			}
+		case "ssl_certificate":
+			if len(d.Args) == 1 && !strings.Contains(d.Args[0].Value, "$") {
+				im.literal(d.Args[0], 0, len(d.Args[0].Value), "ssl_cert", "file_path", "Path to SSL certificate file")
+			}
+		case "ssl_certificate_key":
+			if len(d.Args) == 1 && !strings.Contains(d.Args[0].Value, "$") {
+				im.literal(d.Args[0], 0, len(d.Args[0].Value), "ssl_key", "file_path", "Path to SSL private key file")
+			}
+		case "proxy_pass", "grpc_pass":
+			if len(d.Args) != 1 {
+				return
+			}
+			match := proxyPassRegex.FindStringSubmatchIndex(d.Args[0].Value)
+			if match == nil || upstreams[d.Args[0].Value[match[2]:match[3]]] {
+				return
+			}
+			im.literal(d.Args[0], match[2], match[3], "upstream_host", "string", "Backend service host")
+			if match[4] >= 0 {
+				im.literal(d.Args[0], match[4], match[5], "upstream_port", "integer", "Backend service port")
+			}
+		}
+	})
+
+	values := make(map[string]string)
+	for _, param := range im.params {
+		values[param.Name] = param.Value
+	}
+
+	return &ImportResult{
+		Content:    im.build(name, source),
+		Parameters: im.params,
+		Values:     values,
+	}, nil
+}
+
+// literal turns value[from:to] of tok into a parameter. Equal values of the
+// same kind share one parameter.
+func (im *importer) literal(tok nginxconf.Token, from, to int, base, typ, description string) {
+	// Escaped quoted strings do not map 1:1 onto the source bytes
+	if im.content[tok.Start:tok.End] != tok.Value {
+		return
+	}
+
+	value := tok.Value[from:to]
+	key := base + "\x00" + value
+
+	param, exists := im.byValue[key]
+	if !exists {
+		name := base
+		for i := 2; im.names[name]; i++ {
+			name = fmt.Sprintf("%s_%d", base, i)
+		}
+		im.names[name] = true
+
+		param = &ImportedParameter{Name: name, Type: typ, Description: description, Value: value}
+		im.byValue[key] = param
+		im.params = append(im.params, param)
+	}
+
+	param.Occurrences++
+	im.replacements = append(im.replacements, importReplacement{
+		start: tok.Start + from,
+		end:   tok.Start + to,
+		param: param.Name,
+	})
+}
+
+func (im *importer) build(name, source string) string {
+	var out strings.Builder
+
+	out.WriteString("{{/*\n")
+	out.WriteString(fmt.Sprintf("# Template: %s\n", name))
+	out.WriteString(fmt.Sprintf("# Description: Imported from %s\n", strings.ReplaceAll(source, "*/", "")))
+	if user := os.Getenv("USER"); user != "" {
+		out.WriteString(fmt.Sprintf("# Author: %s\n", user))
+	}
+	out.WriteString("# Version: 1.0\n")
+	out.WriteString("#\n")
+	for _, param := range im.params {
+		out.WriteString(fmt.Sprintf("# @param %s %s required \"%s\"\n", param.Name, param.Type, param.Description))
+	}
+
+	// Trim the newline after the header unless the config itself starts with
+	// whitespace, which the trim marker would swallow
+	if im.content != "" && strings.ContainsRune(" \t\r\n", rune(im.content[0])) {
+		out.WriteString("*/}}")
+	} else {
+		out.WriteString("*/ -}}\n")
+	}
+
+	sort.Slice(im.replacements, func(i, j int) bool {
+		return im.replacements[i].start < im.replacements[j].start
+	})
+
+	pos := 0
+	for _, r := range im.replacements {
+		out.WriteString(escapeTemplateText(im.content[pos:r.start]))
+		out.WriteString("{{." + r.param + "}}")
+		pos = r.end
+	}
+	out.WriteString(escapeTemplateText(im.content[pos:]))
+
+	return out.String()
+}
+
+// escapeTemplateText protects literal "{{" in the config from text/template.
+func escapeTemplateText(text string) string {
+	return strings.ReplaceAll(text, "{{", `{{"{{"}}`)
+}
+
+// VerifyImport renders tmpl with values and compares the output with the
+// original config. It returns a unified diff when they differ.
+func VerifyImport(tmpl *Template, values map[string]string, original string) (string, error) {
+	rendered, err := tmpl.RenderWithValidation(values)
+	if err != nil {
+		return "", err
+	}
+
+	if rendered == original {
+		return "", nil
+	}
+
+	diff := utils.UnifiedDiff(original, rendered, "original", "rendered")
+	if diff == "" {
+		diff = "outputs differ in trailing whitespace or newlines"
+	}
+	return diff, nil
+}
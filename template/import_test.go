@@ -0,0 +1,102 @@
+package template
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestImportConfigRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		// want holds "name=value" for each detected parameter
+		want []string
+	}{
+		{
+			name: "proxy with TLS",
+			config: `server {
+    listen 80;
+    server_name shop.example.com www.shop.example.com;
+    return 301 https://$host$request_uri;
+}
+
+server {
+    listen 443 ssl http2;
+    server_name shop.example.com;
+    ssl_certificate /etc/ssl/shop.pem;
+    ssl_certificate_key /etc/ssl/shop.key;
+
+    location / {
+        proxy_pass http://10.0.0.5:8080/;
+    }
+}
+`,
+			want: []string{"port=80", "domain=shop.example.com", "domain_2=www.shop.example.com", "ssl_port=443", "ssl_cert=/etc/ssl/shop.pem", "ssl_key=/etc/ssl/shop.key", "upstream_host=10.0.0.5", "upstream_port=8080"},
+		},
+		{
+			name: "upstream block and variables stay literal",
+			config: `upstream app { server 127.0.0.1:3000; }
+server {
+    listen [::]:8080;
+    server_name _ ~^(?<sub>.+)\.example\.com$ $hostname;
+    root /srv/$host;
+    location / { proxy_pass http://app; }
+}
+`,
+			want: []string{"port=8080"},
+		},
+		{
+			name:   "literal braces and escaped strings",
+			config: "\n# {{ not a template }}\nserver {\n    listen unix:/run/nginx.sock;\n    root \"/var/www/my\\\"site\";\n    add_header X-Tpl \"{{.x}}\";\n    root /var/www/html;\n}",
+			want:   []string{"root_path=/var/www/html"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ImportConfig("imported", "site.conf", tt.config)
+			if err != nil {
+				t.Fatalf("ImportConfig: %v", err)
+			}
+
+			var got []string
+			for _, param := range result.Parameters {
+				got = append(got, param.Name+"="+param.Value)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parameters = %q, want %q", got, tt.want)
+			}
+
+			tmpl, err := ParseTemplate("imported", "imported.conf.tpl", result.Content)
+			if err != nil {
+				t.Fatalf("ParseTemplate: %v\n%s", err, result.Content)
+			}
+			diff, err := VerifyImport(tmpl, result.Values, tt.config)
+			if err != nil {
+				t.Fatalf("VerifyImport: %v", err)
+			}
+			if diff != "" {
+				t.Errorf("rendering does not reproduce the config:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestImportConfigSharesEqualValues(t *testing.T) {
+	result, err := ImportConfig("imported", "site.conf", "server {\n    server_name a.example.com;\n    listen 8080;\n}\nserver {\n    server_name a.example.com;\n    listen 8080;\n}\n")
+	if err != nil {
+		t.Fatalf("ImportConfig: %v", err)
+	}
+	if len(result.Parameters) != 2 {
+		t.Fatalf("parameters = %d, want 2", len(result.Parameters))
+	}
+	for _, param := range result.Parameters {
+		if param.Occurrences != 2 {
+			t.Errorf("%s occurs %d time(s), want 2", param.Name, param.Occurrences)
+		}
+	}
+	if strings.Count(result.Content, "{{.domain}}") != 2 {
+		t.Errorf("template does not substitute both server names:\n%s", result.Content)
+	}
+}
@@ -0,0 +1,112 @@
+package template
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// safeValuePattern matches the values of non-raw string parameters: no ';',
+// '{', '}' or line breaks, every quote closed and no '#' starting a word.
+// unsafeValue implements the same rule with better messages; the pattern
+// is what the exported JSON Schema uses. Words are separated by blanks; a
+// '#' may only follow another character of its word.
+const safeValuePattern = `^[ \t]*(?:` + safeWordPattern + `(?:[ \t]+` + safeWordPattern + `)*[ \t]*)?$`
+
+const (
+	safeCharPattern = `(?:[^;{}\r\n"'\\ \t#]|\\[^;{}\r\n]|"(?:[^"\\;{}\r\n]|\\[^;{}\r\n])*"|'(?:[^'\\;{}\r\n]|\\[^;{}\r\n])*')`
+	safeWordPattern = safeCharPattern + `(?:` + safeCharPattern + `|#)*`
+)
+
+// unsafeValue returns why value could inject nginx directives when it is
+// substituted into a configuration, or "" if it cannot.
+func unsafeValue(value string) string {
+	if i := strings.IndexAny(value, ";{}\r\n"); i >= 0 {
+		switch value[i] {
+		case ';':
+			return "contains ';', which would end the directive"
+		case '{':
+			return "contains '{', which would open a block"
+		case '}':
+			return "contains '}', which would close the enclosing block"
+		default:
+			return "contains a line break"
+		}
+	}
+
+	trailing := len(value) - len(strings.TrimRight(value, `\`))
+	if trailing%2 == 1 {
+		return "ends with a backslash, which would escape the character after it"
+	}
+	if insideQuotes(value) {
+		return "has an unbalanced quote"
+	}
+	if startsComment(value) {
+		return "has '#' at the start of a word, which would comment out the rest of the line"
+	}
+	return ""
+}
+
+// startsComment reports whether a '#' outside quotes starts a word of
+// value, where nginx reads it as the start of a comment.
+func startsComment(value string) bool {
+	var quote byte
+	wordStart := true
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == ' ' || c == '\t':
+			wordStart = true
+			continue
+		case c == '#' && wordStart:
+			return true
+		case c == '\\':
+			i++
+		case c == '"' || c == '\'':
+			quote = c
+		}
+		wordStart = false
+	}
+	return false
+}
+
+// injectionConstraint rejects values of string parameters that could end
+// a directive or open a block, so that --set, values files and prompts
+// cannot add directives. Parameters of type raw are exempt; they are meant
+// to hold configuration snippets.
+func (p ParameterInfo) injectionConstraint() (valueConstraint, bool) {
+	if p.jsonType() != "string" || p.Type == "raw" {
+		return valueConstraint{}, false
+	}
+	return valueConstraint{"pattern", safeValuePattern, func(v string) []string {
+		if problem := unsafeValue(v); problem != "" {
+			return []string{fmt.Sprintf("%s (declare the parameter as raw to allow it)", problem)}
+		}
+		return nil
+	}}, true
+}
+
+// checkUndeclaredValues reports the values of parameters m does not
+// declare, such as --set keys of a template without metadata, that could
+// inject directives. They are substituted as given, like string parameters.
+func (m *TemplateMetadata) checkUndeclaredValues(params map[string]string, verr *ValidationError) {
+	var names []string
+	for name := range params {
+		if _, declared := m.Parameter(name); !declared {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if problem := unsafeValue(params[name]); problem != "" {
+			verr.add(name, "%s (declare the parameter as raw to allow it)", problem)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package template
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestUnsafeValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		// want is a fragment of the problem, empty for a safe value
+		want string
+	}{
+		{"plain", "example.com", ""},
+		{"words", "example.com www.example.com", ""},
+		{"empty", "", ""},
+		{"semicolon", "a.b; return 200", "contains ';'"},
+		{"opening brace", "/srv { deny all", "contains '{'"},
+		{"closing brace", "/srv } server", "contains '}'"},
+		{"line break", "a.b\ninclude /etc/passwd", "line break"},
+		{"carriage return", "a.b\rinclude", "line break"},
+		{"comment at the start", "#a.b", "'#' at the start of a word"},
+		{"comment after a blank", "a.b #rest", "'#' at the start of a word"},
+		{"comment after a tab", "a.b\t#", "'#' at the start of a word"},
+		{"hash inside a word", "/srv/a#b", ""},
+		{"hash after a closing quote", `"a"#b`, ""},
+		{"quoted hash", `"# not a comment"`, ""},
+		{"escaped hash", `\#a`, ""},
+		{"hash after an escaped blank", `a\ #b`, ""},
+		{"unbalanced double quote", `"/srv`, "unbalanced quote"},
+		{"unbalanced single quote", `'/srv`, "unbalanced quote"},
+		{"balanced quotes", `"a b" 'c d'`, ""},
+		{"escaped quote", `\"/srv`, ""},
+		{"trailing backslash", `/srv\`, "ends with a backslash"},
+		{"escaped backslash", `/srv\\`, ""},
+	}
+
+	pattern := regexp.MustCompile(safeValuePattern)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problem := unsafeValue(tt.value)
+			if tt.want == "" && problem != "" || !strings.Contains(problem, tt.want) {
+				t.Errorf("unsafeValue(%q) = %q, want %q", tt.value, problem, tt.want)
+			}
+			// The JSON Schema pattern implements the same rule
+			if pattern.MatchString(tt.value) != (problem == "") {
+				t.Errorf("pattern matches %q: %v, unsafeValue: %q", tt.value, pattern.MatchString(tt.value), problem)
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template/parse"
+
+	"github.com/vourteen14/ngcli/nginxconf"
+)
+
+// Lint severities.
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+)
+
+// LintIssue is a problem found by Lint. Line is a line of the template, or
+// 0 for problems in a sample render.
+type LintIssue struct {
+	Line     int
+	Severity string
+	Message  string
+}
+
+// LintOptions configures Lint.
+type LintOptions struct {
+	// NginxCheck, if set, also validates the sample renders, typically with
+	// an isolated nginx -t. Errors for which NginxSkipped reports true are
+	// ignored.
+	NginxCheck   func(content string) error
+	NginxSkipped func(err error) bool
+}
+
+// templateKeywords are the text/template keywords and builtin functions;
+// parameters named like them are confusing at best in {{ }} actions.
+var templateKeywords = map[string]bool{
+	"if": true, "else": true, "end": true, "range": true, "with": true,
+	"define": true, "template": true, "block": true, "break": true,
+	"continue": true, "nil": true, "true": true, "false": true,
+	"and": true, "or": true, "not": true, "len": true, "index": true,
+	"slice": true, "print": true, "printf": true, "println": true,
+	"html": true, "js": true, "urlquery": true, "call": true,
+	"eq": true, "ne": true, "lt": true, "le": true, "gt": true, "ge": true,
+	"quote": true, "regexQuote": true,
+}
+
+var (
+	actionTextRegex    = regexp.MustCompile(`\{\{.*?\}\}`)
+	actionOpenRegex    = regexp.MustCompile(`\{\{-?\s*$`)
+	regexLocationRegex = regexp.MustCompile(`^\s*location\s+~\*?\s`)
+)
+
+// Lint looks for nginx-specific mistakes in the template source and in
+// sample renders: substitutions that can inject directives, unescaped
+// values in regex locations, parameters named like template keywords,
+// missing Template/Version headers, and configuration problems found by
+// nginxconf.Lint in the output.
+func (t *Template) Lint(opts LintOptions) []LintIssue {
+	var issues []LintIssue
+	add := func(line int, severity, format string, args ...interface{}) {
+		issues = append(issues, LintIssue{Line: line, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	m := t.Metadata
+	if m.Name == "" {
+		add(1, LintWarning, "metadata header has no Template: name")
+	}
+	if m.Version == "" {
+		add(1, LintWarning, "metadata header has no Version:, so copies cannot be told apart")
+	}
+
+	for _, param := range m.Parameters {
+		if templateKeywords[param.Name] {
+			add(param.Line, LintWarning, "parameter %s has the name of a template keyword or function", param.Name)
+		}
+	}
+
+	for _, action := range t.actions() {
+		line := lineOf(t.Content, action.pos)
+		context := t.lineBefore(action.pos)
+		trimmed := strings.TrimSpace(context)
+		if strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		for _, name := range action.params {
+			param, declared := m.Parameter(name)
+			if !declared || !param.injectable() {
+				continue
+			}
+			if regexLocationRegex.MatchString(context) && !action.funcs["regexQuote"] {
+				add(line, LintWarning, "%s is used in a regex location without regexQuote; characters such as '.' match more than intended", name)
+			}
+			// A substitution that makes up a whole line is meant to be
+			// directives; only arguments can be injected into
+			if trimmed != "" && !action.funcs["quote"] && !insideQuotes(context) {
+				// Only raw values may contain ';', '{' or '}'
+				risk := "a value with spaces becomes several arguments"
+				if param.Type == "raw" {
+					risk = "a raw value can inject directives"
+				}
+				add(line, LintWarning, "%s is substituted unquoted; %s (quote it, use {{quote .%s}}, or declare a pattern or options)", name, risk, name)
+			}
+		}
+	}
+
+	issues = append(issues, t.lintSamples(opts)...)
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Line < issues[j].Line
+	})
+	return issues
+}
+
+// injectable reports whether a value of the parameter can contain
+// characters that end an nginx argument or directive.
+func (p ParameterInfo) injectable() bool {
+	return p.jsonType() == "string" && len(p.Options) == 0 && p.Pattern == ""
+}
+
+// lineBefore returns the text of the line containing offset up to offset,
+// with earlier actions and the opening delimiter of the current one removed.
+func (t *Template) lineBefore(offset int) string {
+	start := strings.LastIndex(t.Content[:offset], "\n") + 1
+	text := actionOpenRegex.ReplaceAllString(t.Content[start:offset], "")
+	return actionTextRegex.ReplaceAllString(text, "")
+}
+
+// insideQuotes reports whether text ends inside a quoted nginx string.
+func insideQuotes(text string) bool {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		}
+	}
+	return quote != 0
+}
+
+// lintSamples renders the template with sample values, once with only the
+// required parameters and once with every optional one, and lints the
+// output.
+func (t *Template) lintSamples(opts LintOptions) []LintIssue {
+	var issues []LintIssue
+	// Problems the full sample shares with the minimal one are reported once
+	seen := make(map[string]bool)
+
+	minimal := t.Metadata.SampleValues()
+	samples := []struct {
+		name   string
+		values map[string]string
+	}{
+		{"minimal sample", minimal},
+		{"full sample", t.Metadata.fullSampleValues(minimal)},
+	}
+
+	for i, sample := range samples {
+		if i > 0 && len(sample.values) == len(minimal) {
+			break
+		}
+
+		values := map[string]string{ConfigNameParameter: "example"}
+		for key, value := range sample.values {
+			values[key] = value
+		}
+
+		content, err := t.RenderWithValidation(values)
+		if err != nil {
+			issues = append(issues, LintIssue{Severity: LintError, Message: fmt.Sprintf("%s does not render: %v", sample.name, err)})
+			continue
+		}
+		files := []RenderedFile{{Content: content}}
+		outputs, err := t.RenderOutputs(values)
+		if err != nil {
+			issues = append(issues, LintIssue{Severity: LintError, Message: fmt.Sprintf("%s: %v", sample.name, err)})
+		}
+		files = append(files, outputs...)
+
+		for _, file := range files {
+			// Outputs such as htpasswd files are not nginx configuration
+			if file.Name != "" && !strings.HasSuffix(file.Path, ".conf") {
+				continue
+			}
+			label := sample.name
+			if file.Name != "" {
+				label += ", output " + file.Name
+			}
+
+			for _, problem := range nginxconf.Lint(file.Content) {
+				key := fmt.Sprintf("%s:%d:%s", file.Name, problem.Line, problem.Message)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+
+				message := fmt.Sprintf("%s: %s", label, problem.Message)
+				if problem.Line > 0 {
+					message = fmt.Sprintf("%s, line %d: %s", label, problem.Line, problem.Message)
+				}
+				issues = append(issues, LintIssue{Severity: LintError, Message: message})
+			}
+
+			if opts.NginxCheck != nil {
+				if err := opts.NginxCheck(file.Content); err != nil && (opts.NginxSkipped == nil || !opts.NginxSkipped(err)) {
+					issues = append(issues, LintIssue{Severity: LintError, Message: fmt.Sprintf("%s: %v", label, err)})
+				}
+			}
+		}
+	}
+
+	return issues
+}
+
+// templateAction is an {{ }} action that writes output, with the root
+// parameters it reads and the functions its pipeline calls.
+type templateAction struct {
+	pos    int
+	params []string
+	funcs  map[string]bool
+}
+
+// actions returns the output actions of the template file itself,
+// including its {{define}} blocks but not partials.
+func (t *Template) actions() []templateAction {
+	var actions []templateAction
+
+	var walk func(node parse.Node, rootDot bool)
+	walk = func(node parse.Node, rootDot bool) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child, rootDot)
+			}
+		case *parse.ActionNode:
+			// Variable declarations such as {{$x := .y}} write nothing
+			if len(n.Pipe.Decl) > 0 {
+				return
+			}
+			w := &referenceWalker{content: t.Content}
+			w.walk(n.Pipe, rootDot)
+			action := templateAction{pos: int(n.Pos), funcs: make(map[string]bool)}
+			for _, ref := range w.refs {
+				action.params = append(action.params, ref.Name)
+			}
+			for _, cmd := range n.Pipe.Cmds {
+				if len(cmd.Args) > 0 {
+					if ident, ok := cmd.Args[0].(*parse.IdentifierNode); ok {
+						action.funcs[ident.Ident] = true
+					}
+				}
+			}
+			actions = append(actions, action)
+		case *parse.IfNode:
+			walk(n.List, rootDot)
+			walk(n.ElseList, rootDot)
+		case *parse.RangeNode:
+			walk(n.List, false)
+			walk(n.ElseList, rootDot)
+		case *parse.WithNode:
+			walk(n.List, false)
+			walk(n.ElseList, rootDot)
+		}
+	}
+
+	for _, tmpl := range t.Template.Templates() {
+		if tmpl.Tree == nil || tmpl.Tree.Root == nil || tmpl.Tree.ParseName != t.Name {
+			continue
+		}
+		walk(tmpl.Tree.Root, true)
+	}
+
+	sort.SliceStable(actions, func(i, j int) bool { return actions[i].pos < actions[j].pos })
+	return actions
+}
@@ -0,0 +1,177 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// lintHeader declares a parameter of each kind the linter tells apart.
+const lintHeader = `# Template: lint
+# Version: 1.0
+# @param domain string required "Domain"
+# @param host string required "Host" pattern="^[a-z0-9.-]+$"
+# @param mode string optional "Mode" default="on" options=["on","off"]
+# @param path string optional "Path prefix" default="api"
+# @param snippet raw optional "Extra directives"
+`
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		// want holds "line: message fragment" for each expected warning
+		want []string
+	}{
+		{
+			name:    "quoted and constrained substitutions",
+			content: lintHeader + "server {\n    server_name \"{{.domain}}\";\n    proxy_pass http://{{.host}};\n    gzip {{.mode}};\n    root {{quote .domain}};\n{{.snippet}}\n}\n",
+		},
+		{
+			name:    "unquoted substitution",
+			content: lintHeader + "server {\n    server_name {{.domain}};\n}\n",
+			want:    []string{"9: domain is substituted unquoted; a value with spaces"},
+		},
+		{
+			name:    "raw substitution as an argument",
+			content: "# Template: lint\n# Version: 1.0\n# @param aliases raw optional \"Aliases\" default=\"www.example.com\"\nserver {\n    server_name example.com {{.aliases}};\n}\n",
+			want:    []string{"5: aliases is substituted unquoted; a raw value can inject directives"},
+		},
+		{
+			name:    "commented substitution",
+			content: lintHeader + "server {\n    # server_name {{.domain}};\n}\n",
+		},
+		{
+			name:    "regex location without regexQuote",
+			content: lintHeader + "server {\n    location ~ ^/\"{{.path}}\"/ { }\n}\n",
+			want:    []string{"9: path is used in a regex location without regexQuote"},
+		},
+		{
+			name:    "regex location with regexQuote",
+			content: lintHeader + "server {\n    location ~* ^/\"{{regexQuote .path}}\"/ { }\n}\n",
+		},
+		{
+			name:    "regex location with a constrained parameter",
+			content: lintHeader + "server {\n    location ~ ^/{{.host}}/ { }\n}\n",
+		},
+		{
+			name:    "keyword-named parameter",
+			content: "# Template: lint\n# Version: 1.0\n# @param index string optional \"Index\" options=[\"a\",\"b\"]\nserver { index {{.index}}.html; }\n",
+			want:    []string{"3: parameter index has the name of a template keyword"},
+		},
+		{
+			name:    "missing Template header",
+			content: "# Version: 1.0\n# @param domain string required \"Domain\"\nserver { server_name \"{{.domain}}\"; }\n",
+			want:    []string{"1: metadata header has no Template: name"},
+		},
+		{
+			name:    "missing Version header",
+			content: "# Template: lint\n# @param domain string required \"Domain\"\nserver { server_name \"{{.domain}}\"; }\n",
+			want:    []string{"1: metadata header has no Version:"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := ParseTemplate("lint", "lint.conf.tpl", tt.content)
+			if err != nil {
+				t.Fatalf("ParseTemplate: %v", err)
+			}
+
+			var got []string
+			for _, issue := range tmpl.Lint(LintOptions{}) {
+				got = append(got, issueString(issue))
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Lint = %q, want %d issue(s) %q", got, len(tt.want), tt.want)
+			}
+			for i, want := range tt.want {
+				if !strings.HasPrefix(got[i], "warning "+want) {
+					t.Errorf("issue %d = %q, want %q", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func issueString(issue LintIssue) string {
+	return fmt.Sprintf("%s %d: %s", issue.Severity, issue.Line, issue.Message)
+}
+
+// samplesTemplate renders a duplicate root in both samples and an alias
+// without argument only when the optional parameter is set.
+const samplesTemplate = `# Template: samples
+# Version: 1.0
+# @param domain string required "Domain" pattern="^[a-z0-9.-]+$"
+# @param enable_root boolean optional "Serve files"
+server {
+    server_name {{.domain}};
+    root /srv;
+    root /var/www;
+{{- if .enable_root}}
+    alias;
+{{- end}}
+}
+`
+
+func TestLintSamples(t *testing.T) {
+	tmpl, err := ParseTemplate("samples", "samples.conf.tpl", samplesTemplate)
+	if err != nil {
+		t.Fatalf("ParseTemplate: %v", err)
+	}
+
+	var checked []string
+	issues := tmpl.lintSamples(LintOptions{
+		NginxCheck: func(content string) error {
+			checked = append(checked, content)
+			if strings.Contains(content, "alias;") {
+				return errors.New("nginx: alias needs an argument")
+			}
+			return errors.New("nginx: skipped")
+		},
+		NginxSkipped: func(err error) bool { return strings.HasSuffix(err.Error(), "skipped") },
+	})
+
+	want := []string{
+		"minimal sample, line 8: root is duplicate (first on line 7)",
+		"full sample, line 9: alias needs at least 1 argument(s), has 0",
+		"full sample: nginx: alias needs an argument",
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("lintSamples = %v, want %q", issues, want)
+	}
+	for i, issue := range issues {
+		if issue.Severity != LintError || issue.Line != 0 || issue.Message != want[i] {
+			t.Errorf("issue %d = %+v, want error %q", i, issue, want[i])
+		}
+	}
+	if len(checked) != 2 {
+		t.Errorf("NginxCheck ran on %d render(s), want 2", len(checked))
+	}
+}
+
+func TestLintSamplesSkipsFullSampleWithoutOptionals(t *testing.T) {
+	tmpl, err := ParseTemplate("minimal", "minimal.conf.tpl", "# Template: minimal\n# Version: 1.0\n# @param domain string required \"Domain\" pattern=\"^[a-z.]+$\"\nserver { server_name {{.domain}}; }\n")
+	if err != nil {
+		t.Fatalf("ParseTemplate: %v", err)
+	}
+	renders := 0
+	issues := tmpl.lintSamples(LintOptions{NginxCheck: func(string) error { renders++; return nil }})
+	if len(issues) != 0 || renders != 1 {
+		t.Errorf("lintSamples = %v with %d render(s), want no issues and 1 render", issues, renders)
+	}
+}
+
+func TestBuiltinTemplatesLintClean(t *testing.T) {
+	for _, name := range BuiltinNames() {
+		content, _ := BuiltinContent(name)
+		tmpl, err := ParseTemplate(name, name+".conf.tpl", content)
+		if err != nil {
+			t.Fatalf("ParseTemplate(%s): %v", name, err)
+		}
+		for _, issue := range tmpl.Lint(LintOptions{}) {
+			t.Errorf("%s: %s", name, issueString(issue))
+		}
+	}
+}
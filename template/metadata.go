@@ -6,6 +6,9 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"text/template"
+
+	"github.com/vourteen14/ngcli/errcode"
 )
 
 type TemplateMetadata struct {
@@ -14,8 +17,30 @@ type TemplateMetadata struct {
 	Author      string
 	Version     string
 	Parameters  []ParameterInfo
+	Requires    []RequireRule
+	Conflicts   [][]string
+	// Outputs are the files written besides the main configuration.
+	Outputs []OutputFile
+	// Format is MetadataComments or MetadataFrontMatter.
+	Format string
+	// Warnings lists header lines that look like metadata but were ignored.
+	Warnings []string
+
+	// wrapped is set when the header is inside a template comment
+	wrapped bool
+	// defaults holds the parsed computed defaults, evaluated in defaultOrder
+	defaults     map[string]*template.Template
+	defaultOrder []string
+	// outputPaths holds the parsed path expressions of Outputs
+	outputPaths map[string]*template.Template
 }
 
+// Metadata header formats.
+const (
+	MetadataComments    = "comments"
+	MetadataFrontMatter = "yaml"
+)
+
 type ParameterInfo struct {
 	Name        string
 	Type        string
@@ -23,24 +48,171 @@ type ParameterInfo struct {
 	Description string
 	Default     string
 	Options     []string
+	Min         *float64
+	Max         *float64
+	Pattern     string
+	MinLen      *int
+	MaxLen      *int
+	Deprecated  string
+	Sensitive   bool
+	When        string
+	Line        int
+	// Items describes the elements of an array and Properties the fields of
+	// an object. Nested types can only be declared in YAML front-matter.
+	Items      *ParameterInfo
+	Properties []ParameterInfo
 }
 
+// RequireRule makes Params mandatory whenever the Condition parameter is set,
+// as declared by "# @require ssl_key if ssl_cert".
+type RequireRule struct {
+	Params    []string
+	Condition string
+}
+
+// missingMessage is the problem reported for a missing required parameter.
+const missingMessage = "required parameter is missing"
+
+// ParameterError describes a single problem with a single parameter.
+type ParameterError struct {
+	Parameter string
+	Message   string
+	// Missing is set when the parameter is required but has no value
+	Missing bool
+}
+
+// ValidationError collects every parameter problem found in one pass.
+type ValidationError struct {
+	Errors []ParameterError
+}
+
+func (e *ValidationError) Error() string {
+	problems := make([]string, 0, len(e.Errors))
+	for _, pe := range e.Errors {
+		problems = append(problems, fmt.Sprintf("%s: %s", pe.Parameter, pe.Message))
+	}
+	return fmt.Sprintf("invalid parameters: %s", strings.Join(problems, "; "))
+}
+
+// ErrorCode reports MissingParameters when every problem is a missing
+// parameter, and InvalidParameters otherwise.
+func (e *ValidationError) ErrorCode() errcode.Code {
+	for _, pe := range e.Errors {
+		if !pe.Missing {
+			return errcode.InvalidParameters
+		}
+	}
+	return errcode.MissingParameters
+}
+
+// FieldErrors returns one field problem per parameter problem.
+func (e *ValidationError) FieldErrors() []errcode.Field {
+	fields := make([]errcode.Field, 0, len(e.Errors))
+	for _, pe := range e.Errors {
+		fields = append(fields, errcode.Field{Name: pe.Parameter, Message: pe.Message})
+	}
+	return fields
+}
+
+func (e *ValidationError) add(param, format string, args ...interface{}) {
+	e.Errors = append(e.Errors, ParameterError{Parameter: param, Message: fmt.Sprintf(format, args...)})
+}
+
+// addMissing records that param is required but has no value.
+func (e *ValidationError) addMissing(param, format string, args ...interface{}) {
+	e.Errors = append(e.Errors, ParameterError{Parameter: param, Message: fmt.Sprintf(format, args...), Missing: true})
+}
+
+// ParseTemplateMetadata reads the metadata header of a template, either YAML
+// front-matter or the comment syntax ("# @param ...").
 func ParseTemplateMetadata(templateContent string) (*TemplateMetadata, error) {
+	block, found, err := findFrontMatter(templateContent)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		metadata, err := parseFrontMatter(block)
+		if err != nil {
+			return nil, err
+		}
+		metadata.Warnings = append(metadata.Warnings, strayMetadataWarnings(templateContent, block.end+1, "the template uses YAML front-matter")...)
+		metadata.wrapped = block.wrapped
+		return finishMetadata(metadata)
+	}
+
+	metadata, err := parseCommentMetadata(templateContent)
+	if err != nil {
+		return nil, err
+	}
+	return finishMetadata(metadata)
+}
+
+// finishMetadata applies what both metadata formats have in common.
+func finishMetadata(metadata *TemplateMetadata) (*TemplateMetadata, error) {
+	markSecrets(metadata)
+	if err := metadata.compileDefaults(); err != nil {
+		return nil, err
+	}
+	if err := metadata.compileOutputs(); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// markSecrets makes secret parameters sensitive: their values and defaults
+// are never displayed.
+func markSecrets(metadata *TemplateMetadata) {
+	for i := range metadata.Parameters {
+		if metadata.Parameters[i].Type == "secret" {
+			metadata.Parameters[i].Sensitive = true
+		}
+	}
+}
+
+func parseCommentMetadata(templateContent string) (*TemplateMetadata, error) {
 	scanner := bufio.NewScanner(strings.NewReader(templateContent))
 	metadata := &TemplateMetadata{
 		Parameters: make([]ParameterInfo, 0),
+		Format:     MetadataComments,
 	}
 	
 	templateLineRegex := regexp.MustCompile(`^#\s*Template:\s*(.+)$`)
 	descriptionRegex := regexp.MustCompile(`^#\s*Description:\s*(.+)$`)
 	authorRegex := regexp.MustCompile(`^#\s*Author:\s*(.+)$`)
 	versionRegex := regexp.MustCompile(`^#\s*Version:\s*(.+)$`)
-	paramRegex := regexp.MustCompile(`^#\s*@param\s+(\w+)\s+(\w+)\s+(required|optional)\s+"([^"]+)"(?:\s+(.*))?$`)
+	paramRegex := regexp.MustCompile(`^#\s*@param\s+(\w+)\s+(\w+)\s+(required|optional)\s+"((?:[^"\\]|\\.)*)"(?:\s+(.*))?$`)
+	requireRegex := regexp.MustCompile(`^#\s*@require\s+(.+?)\s+if\s+(\w+)$`)
+	conflictsRegex := regexp.MustCompile(`^#\s*@conflicts\s+(.+)$`)
+	whenRegex := regexp.MustCompile(`^#\s*@when\s+(!?\w+(?:=\S+)?)$`)
+	endRegex := regexp.MustCompile(`^#\s*@end$`)
+	
+	// A "# @when <condition>" line applies to the @param lines directly below it.
+	var when string
+	
+	// The header may be wrapped in a template comment ({{/* ... */}}) so
+	// that it is not rendered into the generated configuration; see
+	// commentOpenRegex and commentCloseRegex
+	
+	// First line after the header, if the header does not run to the end
+	rest := 0
 	
+	lineNumber := 0
 	for scanner.Scan() {
+		lineNumber++
 		line := strings.TrimSpace(scanner.Text())
 		
+		if lineNumber == 1 && commentOpenRegex.MatchString(line) {
+			metadata.wrapped = true
+			continue
+		}
+		
+		if commentCloseRegex.MatchString(line) {
+			rest = lineNumber + 1
+			break
+		}
+		
 		if !strings.HasPrefix(line, "#") && line != "" {
+			rest = lineNumber
 			break
 		}
 		
@@ -64,104 +236,478 @@ func ParseTemplateMetadata(templateContent string) (*TemplateMetadata, error) {
 			continue
 		}
 		
+		if match := whenRegex.FindStringSubmatch(line); match != nil {
+			when = match[1]
+			continue
+		}
+		
 		if match := paramRegex.FindStringSubmatch(line); match != nil {
 			param := ParameterInfo{
 				Name:        match[1],
 				Type:        match[2],
 				Required:    match[3] == "required",
-				Description: match[4],
+				Description: unescapeQuoted(match[4]),
+				When:        when,
+				Line:        lineNumber,
 			}
 			
 			if len(match) > 5 && match[5] != "" {
-				attributes := match[5]
-				param.Default, param.Options = parseParameterAttributes(attributes)
+				unknown, err := parseParameterAttributes(&param, match[5])
+				if err != nil {
+					return nil, fmt.Errorf("parameter %s: %w", param.Name, err)
+				}
+				for _, key := range unknown {
+					metadata.Warnings = append(metadata.Warnings, fmt.Sprintf("line %d: unknown attribute %q of parameter %s ignored", lineNumber, key, param.Name))
+				}
 			}
 			
 			metadata.Parameters = append(metadata.Parameters, param)
+			continue
+		}
+		
+		when = ""
+		
+		if endRegex.MatchString(line) {
+			continue
 		}
+		
+		if output, ok, err := parseOutputLine(line, lineNumber); ok {
+			if err != nil {
+				return nil, err
+			}
+			metadata.Outputs = append(metadata.Outputs, output)
+			continue
+		}
+		
+		if match := requireRegex.FindStringSubmatch(line); match != nil {
+			metadata.Requires = append(metadata.Requires, RequireRule{
+				Params:    splitNameList(match[1]),
+				Condition: match[2],
+			})
+			continue
+		}
+		
+		if match := conflictsRegex.FindStringSubmatch(line); match != nil {
+			names := splitNameList(match[1])
+			if len(names) < 2 {
+				return nil, fmt.Errorf("@conflicts needs at least two parameters: %s", line)
+			}
+			metadata.Conflicts = append(metadata.Conflicts, names)
+			continue
+		}
+		
+		if metadataLineRegex.MatchString(line) {
+			metadata.Warnings = append(metadata.Warnings, fmt.Sprintf("line %d: malformed metadata line ignored: %s", lineNumber, line))
+		}
+	}
+	
+	if rest > 0 {
+		metadata.Warnings = append(metadata.Warnings, strayMetadataWarnings(templateContent, rest, "it is outside the metadata header")...)
 	}
 	
 	return metadata, nil
 }
 
-func parseParameterAttributes(attributes string) (string, []string) {
-	var defaultValue string
-	var options []string
-	
-	defaultRegex := regexp.MustCompile(`default=([^,\s]+|"[^"]*")`)
-	if match := defaultRegex.FindStringSubmatch(attributes); match != nil {
-		defaultValue = strings.Trim(match[1], `"`)
+// metadataLineRegex matches anything that looks like a metadata directive,
+// including malformed ones such as "#@param domain" without a type.
+var metadataLineRegex = regexp.MustCompile(`^#\s*@(param|require|conflicts|when|end|output)\b`)
+
+// strayMetadataWarnings reports metadata-looking lines from line number
+// from (1-based) on, which the parser never reads.
+func strayMetadataWarnings(content string, from int, reason string) []string {
+	var warnings []string
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if i+1 >= from && metadataLineRegex.MatchString(line) {
+			warnings = append(warnings, fmt.Sprintf("line %d: ignored because %s: %s", i+1, reason, line))
+		}
 	}
-	
-	optionsRegex := regexp.MustCompile(`options=\[([^\]]+)\]`)
-	if match := optionsRegex.FindStringSubmatch(attributes); match != nil {
-		optionsList := match[1]
-		for _, opt := range strings.Split(optionsList, ",") {
-			cleaned := strings.Trim(strings.TrimSpace(opt), `"`)
-			if cleaned != "" {
-				options = append(options, cleaned)
+	return warnings
+}
+
+// unescapeQuoted undoes the \" and \\ escapes of a quoted header value.
+func unescapeQuoted(value string) string {
+	return strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(value)
+}
+
+// parseParameterAttributes fills the optional attributes that follow the
+// description of an @param line, e.g. default=80 min=1 max=65535 sensitive.
+// It returns the attributes it does not know, which are ignored so that
+// templates written for other versions still load.
+func parseParameterAttributes(param *ParameterInfo, attributes string) ([]string, error) {
+	attrs, err := splitAttributes(attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	var unknown []string
+
+	for _, attr := range attrs {
+		switch attr.key {
+		case "default":
+			param.Default = attr.value
+		case "options":
+			param.Options = parseOptionList(attr.value)
+		case "min", "max":
+			number, err := strconv.ParseFloat(attr.value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s value %q", attr.key, attr.value)
+			}
+			if attr.key == "min" {
+				param.Min = &number
+			} else {
+				param.Max = &number
+			}
+		case "minlen", "maxlen":
+			length, err := strconv.Atoi(attr.value)
+			if err != nil || length < 0 {
+				return nil, fmt.Errorf("invalid %s value %q", attr.key, attr.value)
 			}
+			if attr.key == "minlen" {
+				param.MinLen = &length
+			} else {
+				param.MaxLen = &length
+			}
+		case "pattern":
+			if _, err := regexp.Compile(attr.value); err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", attr.value, err)
+			}
+			param.Pattern = attr.value
+		case "deprecated":
+			param.Deprecated = attr.value
+			if param.Deprecated == "" {
+				param.Deprecated = "no longer used"
+			}
+		case "sensitive":
+			param.Sensitive = true
+		default:
+			unknown = append(unknown, attr.key)
 		}
 	}
-	
-	return defaultValue, options
+
+	return unknown, nil
+}
+
+type attribute struct {
+	key   string
+	value string
+}
+
+// splitAttributes tokenizes key=value pairs separated by whitespace or commas.
+// Values may be bare words, "quoted strings" (with \" escapes) or [lists].
+// A key without a value is a flag such as "sensitive".
+func splitAttributes(input string) ([]attribute, error) {
+	var attrs []attribute
+	i := 0
+
+	for i < len(input) {
+		if input[i] == ' ' || input[i] == '\t' || input[i] == ',' {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(input) && input[i] != '=' && input[i] != ' ' && input[i] != '\t' && input[i] != ',' {
+			i++
+		}
+		key := input[start:i]
+
+		if i >= len(input) || input[i] != '=' {
+			attrs = append(attrs, attribute{key: key})
+			continue
+		}
+		i++
+
+		var value strings.Builder
+		switch {
+		case i < len(input) && input[i] == '"':
+			i++
+			closed := false
+			for i < len(input) {
+				if input[i] == '\\' && i+1 < len(input) && input[i+1] == '"' {
+					value.WriteByte('"')
+					i += 2
+					continue
+				}
+				if input[i] == '"' {
+					closed = true
+					i++
+					break
+				}
+				value.WriteByte(input[i])
+				i++
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated quoted value for %s", key)
+			}
+		case i < len(input) && input[i] == '[':
+			start := i
+			inQuotes := false
+			for i < len(input) {
+				if input[i] == '"' {
+					inQuotes = !inQuotes
+				}
+				if input[i] == ']' && !inQuotes {
+					break
+				}
+				i++
+			}
+			if i >= len(input) {
+				return nil, fmt.Errorf("unterminated list for %s", key)
+			}
+			value.WriteString(input[start+1 : i])
+			i++
+		default:
+			start := i
+			for i < len(input) && input[i] != ' ' && input[i] != '\t' && input[i] != ',' {
+				i++
+			}
+			value.WriteString(input[start:i])
+		}
+
+		attrs = append(attrs, attribute{key: key, value: value.String()})
+	}
+
+	return attrs, nil
 }
 
+func parseOptionList(list string) []string {
+	var options []string
+	for _, opt := range strings.Split(list, ",") {
+		cleaned := strings.Trim(strings.TrimSpace(opt), `"`)
+		if cleaned != "" {
+			options = append(options, cleaned)
+		}
+	}
+	return options
+}
+
+func splitNameList(list string) []string {
+	return strings.FieldsFunc(list, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+}
+
+// Parameter returns the declaration of the named parameter, if any.
+func (m *TemplateMetadata) Parameter(name string) (ParameterInfo, bool) {
+	for _, param := range m.Parameters {
+		if param.Name == name {
+			return param, true
+		}
+	}
+	return ParameterInfo{}, false
+}
+
+// isSet reports whether a parameter counts as provided for @require and
+// @conflicts rules. Boolean parameters only count when they are "true".
+func (m *TemplateMetadata) isSet(name string, params map[string]string) bool {
+	value, exists := params[name]
+	if !exists || value == "" {
+		return false
+	}
+	if param, ok := m.Parameter(name); ok && param.Type == "boolean" {
+		return value == "true"
+	}
+	return true
+}
+
+// ValidateParameters checks every parameter, the values given for
+// undeclared ones and every template rule, and returns a *ValidationError
+// listing all violations, or nil when the values are valid.
 func (m *TemplateMetadata) ValidateParameters(params map[string]string) error {
-	var missing []string
-	var invalid []string
-	
+	verr := &ValidationError{}
+
 	for _, param := range m.Parameters {
-		if param.Required {
-			if _, exists := params[param.Name]; !exists {
-				missing = append(missing, param.Name)
+		if !m.IsActive(param, params) {
+			continue
+		}
+
+		value, exists := params[param.Name]
+
+		if param.Required && !exists {
+			verr.addMissing(param.Name, missingMessage)
+			continue
+		}
+
+		if exists {
+			for _, problem := range m.validateParameterValue(param, value) {
+				verr.add(param.Name, "%s", problem)
 			}
 		}
-		
-		if value, exists := params[param.Name]; exists {
-			if err := m.validateParameterValue(param, value); err != nil {
-				invalid = append(invalid, fmt.Sprintf("%s: %v", param.Name, err))
+	}
+	m.checkUndeclaredValues(params, verr)
+
+	for _, rule := range m.Requires {
+		if !m.isSet(rule.Condition, params) {
+			continue
+		}
+		for _, name := range rule.Params {
+			if !m.isSet(name, params) {
+				verr.addMissing(name, "required when %s is set", rule.Condition)
 			}
 		}
 	}
-	
-	if len(missing) > 0 {
-		return fmt.Errorf("missing required parameters: %s", strings.Join(missing, ", "))
+
+	for _, group := range m.Conflicts {
+		var set []string
+		for _, name := range group {
+			if m.isSet(name, params) {
+				set = append(set, name)
+			}
+		}
+		if len(set) < 2 {
+			continue
+		}
+		// One error per parameter, so that each can be reported and asked
+		// again on its own
+		for _, name := range set {
+			var others []string
+			for _, other := range set {
+				if other != name {
+					others = append(others, other)
+				}
+			}
+			verr.add(name, "cannot be used together with %s (at most one of: %s)", strings.Join(others, ", "), strings.Join(group, ", "))
+		}
 	}
-	
-	if len(invalid) > 0 {
-		return fmt.Errorf("invalid parameter values: %s", strings.Join(invalid, "; "))
+
+	if len(verr.Errors) > 0 {
+		return verr
 	}
-	
+
 	return nil
 }
 
-func (m *TemplateMetadata) validateParameterValue(param ParameterInfo, value string) error {
-	switch param.Type {
-	case "integer":
-		if _, err := strconv.Atoi(value); err != nil {
-			return fmt.Errorf("must be an integer")
+func (m *TemplateMetadata) validateParameterValue(param ParameterInfo, value string) []string {
+	// An unresolved secret reference is checked once the secret is resolved
+	if param.Type == "secret" && IsSecretReference(value) {
+		return nil
+	}
+	return param.checkString(value)
+}
+
+// ValidateValue checks one value of the named parameter against its type
+// and constraints, as ValidateParameters does, so answers can be checked
+// as they are given. Rules between parameters are not checked.
+func (m *TemplateMetadata) ValidateValue(name, value string) []string {
+	param, declared := m.Parameter(name)
+	if !declared {
+		return nil
+	}
+	return m.validateParameterValue(param, value)
+}
+
+// IsActive reports whether the @when condition of a parameter holds for the
+// given values. Conditions are "name", "!name" or "name=value"; a parameter
+// whose controlling parameter is itself inactive is inactive too.
+func (m *TemplateMetadata) IsActive(param ParameterInfo, params map[string]string) bool {
+	return m.isActive(param, params, len(m.Parameters))
+}
+
+func (m *TemplateMetadata) isActive(param ParameterInfo, params map[string]string, depth int) bool {
+	if param.When == "" {
+		return true
+	}
+	if depth < 0 {
+		return false
+	}
+
+	condition := param.When
+	negate := strings.HasPrefix(condition, "!")
+	condition = strings.TrimPrefix(condition, "!")
+
+	name, expected, hasValue := strings.Cut(condition, "=")
+	if controller, ok := m.Parameter(name); ok && !m.isActive(controller, params, depth-1) {
+		return false
+	}
+
+	var result bool
+	if hasValue {
+		result = params[name] == expected
+	} else {
+		result = m.isSet(name, params)
+	}
+
+	return result != negate
+}
+
+// TypedValues converts parameter values into template data. Declared boolean
+// parameters become real booleans (missing ones are false) so that
+// {{if .enable_ssl}} behaves as expected, arrays with declared items and
+// objects become lists and maps for {{range}}; everything else stays a string.
+// Every declared parameter is present in the result, so templates executed
+// with missingkey=error only fail on fields that were never declared.
+func (m *TemplateMetadata) TypedValues(params map[string]string) map[string]interface{} {
+	data := make(map[string]interface{}, len(params))
+
+	for key, value := range params {
+		data[key] = value
+	}
+	if _, exists := data[ConfigNameParameter]; !exists {
+		data[ConfigNameParameter] = ""
+	}
+
+	for _, param := range m.Parameters {
+		switch param.jsonType() {
+		case "boolean", "array", "object":
+			data[param.Name] = param.templateValue(params[param.Name])
+		default:
+			if _, exists := data[param.Name]; !exists {
+				data[param.Name] = ""
+			}
 		}
-	case "boolean":
-		if value != "true" && value != "false" {
-			return fmt.Errorf("must be true or false")
+	}
+
+	return data
+}
+
+// DeprecationWarnings returns a warning for every deprecated parameter that
+// is present in params.
+func (m *TemplateMetadata) DeprecationWarnings(params map[string]string) []string {
+	var warnings []string
+	for _, param := range m.Parameters {
+		if param.Deprecated == "" {
+			continue
 		}
-	case "file_path":
-		if strings.TrimSpace(value) == "" {
-			return fmt.Errorf("file path cannot be empty")
+		if _, exists := params[param.Name]; exists {
+			warnings = append(warnings, fmt.Sprintf("parameter %s is deprecated: %s", param.Name, param.Deprecated))
 		}
 	}
-	
-	if len(param.Options) > 0 {
-		for _, option := range param.Options {
-			if value == option {
-				return nil 
-			}
-		}
-		return fmt.Errorf("must be one of: %s", strings.Join(param.Options, ", "))
+	return warnings
+}
+
+// DisplayType returns the type of a parameter for help output, including the
+// item type of arrays ("array<integer>").
+func (p ParameterInfo) DisplayType() string {
+	if p.Items != nil {
+		return fmt.Sprintf("%s<%s>", p.Type, p.Items.DisplayType())
 	}
-	
-	return nil
+	return p.Type
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// constraintSummary describes the value constraints of a parameter for help output.
+func (p ParameterInfo) constraintSummary() string {
+	var parts []string
+	if p.Min != nil {
+		parts = append(parts, "min="+formatNumber(*p.Min))
+	}
+	if p.Max != nil {
+		parts = append(parts, "max="+formatNumber(*p.Max))
+	}
+	if p.MinLen != nil {
+		parts = append(parts, fmt.Sprintf("minlen=%d", *p.MinLen))
+	}
+	if p.MaxLen != nil {
+		parts = append(parts, fmt.Sprintf("maxlen=%d", *p.MaxLen))
+	}
+	if p.Pattern != "" {
+		parts = append(parts, fmt.Sprintf("pattern=%q", p.Pattern))
+	}
+	return strings.Join(parts, ", ")
 }
 
 // GetParameterHelp returns formatted help text for parameters
@@ -180,37 +726,66 @@ func (m *TemplateMetadata) GetParameterHelp() string {
 		}
 		
 		help.WriteString(fmt.Sprintf("  %-15s %-8s %-8s %s\n", 
-			param.Name, param.Type, required, param.Description))
+			param.Name, param.DisplayType(), required, param.Description))
 		
-		if param.Default != "" {
-			help.WriteString(fmt.Sprintf("  %-15s default: %s\n", "", param.Default))
+		if param.Default != "" && !param.Sensitive {
+			label := "default"
+			if param.IsComputed() {
+				label = "computed"
+			}
+			help.WriteString(fmt.Sprintf("  %-15s %s: %s\n", "", label, param.Default))
 		}
 		
 		if len(param.Options) > 0 {
 			help.WriteString(fmt.Sprintf("  %-15s options: %s\n", "", strings.Join(param.Options, ", ")))
 		}
 		
+		if constraints := param.constraintSummary(); constraints != "" {
+			help.WriteString(fmt.Sprintf("  %-15s constraints: %s\n", "", constraints))
+		}
+		
+		if param.Sensitive {
+			help.WriteString(fmt.Sprintf("  %-15s sensitive: value is not displayed\n", ""))
+		}
+		
+		if param.Deprecated != "" {
+			help.WriteString(fmt.Sprintf("  %-15s deprecated: %s\n", "", param.Deprecated))
+		}
+		
+		if param.When != "" {
+			help.WriteString(fmt.Sprintf("  %-15s only when: %s\n", "", param.When))
+		}
+		
+		fields := param.Properties
+		if param.Items != nil {
+			fields = param.Items.Properties
+		}
+		for _, field := range fields {
+			line := fmt.Sprintf("  %-15s field: %s %s %s", "", field.Name, field.DisplayType(), field.Description)
+			help.WriteString(strings.TrimRight(line, " ") + "\n")
+		}
+		
+		help.WriteString("\n")
+	}
+	
+	if len(m.Requires) > 0 || len(m.Conflicts) > 0 {
+		help.WriteString("Rules:\n")
+		for _, rule := range m.Requires {
+			help.WriteString(fmt.Sprintf("  %s required if %s is set\n", strings.Join(rule.Params, ", "), rule.Condition))
+		}
+		for _, group := range m.Conflicts {
+			help.WriteString(fmt.Sprintf("  only one of: %s\n", strings.Join(group, ", ")))
+		}
 		help.WriteString("\n")
 	}
 	
 	return help.String()
 }
 
-// ApplyDefaults applies default values to parameters if not provided
+// ApplyDefaults applies default values to parameters if not provided,
+// including computed defaults (see ComputeDefaults)
 func (m *TemplateMetadata) ApplyDefaults(params map[string]string) map[string]string {
-	result := make(map[string]string)
-	
-	// Copy existing parameters
-	for key, value := range params {
-		result[key] = value
-	}
-	
-	// Apply defaults for missing parameters
-	for _, param := range m.Parameters {
-		if _, exists := result[param.Name]; !exists && param.Default != "" {
-			result[param.Name] = param.Default
-		}
-	}
-	
+	// Defaults that fail to compute stay unset; ComputeDefaults reports them
+	result, _ := m.ComputeDefaults(params)
 	return result
 }
\ No newline at end of file
@@ -0,0 +1,248 @@
+package template
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vourteen14/ngcli/errcode"
+)
+
+// rulesTemplate declares a @require rule, a @conflicts rule and @when
+// conditions of each form.
+const rulesTemplate = `# Template: rules
+# Version: 1.0
+# @param domain string required "Domain"
+# @param ssl_cert file_path optional "Certificate"
+# @param ssl_key file_path optional "Key"
+# @param basic_auth string optional "htpasswd file"
+# @param oauth_proxy string optional "OAuth proxy"
+# @param ip_allow string optional "Allowed network"
+# @param enable_cache boolean optional "Cache" default=false
+# @when enable_cache
+# @param cache_ttl integer required "Cache TTL" min=1
+# @when !enable_cache
+# @param no_cache_header string optional "Header sent without a cache"
+# @when mode=static
+# @param root string required "Document root"
+# @end
+# @param mode string optional "Mode" default="proxy" options=["proxy","static"]
+# @require ssl_key if ssl_cert
+# @conflicts basic_auth oauth_proxy ip_allow
+server { server_name "{{.domain}}"; }
+`
+
+func TestValidateParametersRules(t *testing.T) {
+	tmpl, err := ParseTemplate("rules", "rules.conf.tpl", rulesTemplate)
+	if err != nil {
+		t.Fatalf("ParseTemplate: %v", err)
+	}
+	m := tmpl.Metadata
+
+	tests := []struct {
+		name   string
+		params map[string]string
+		// want lists the expected problems in order
+		want []ParameterError
+		code errcode.Code
+	}{
+		{
+			name:   "valid",
+			params: map[string]string{"domain": "a.b"},
+		},
+		{
+			name:   "missing required parameter",
+			params: map[string]string{},
+			want:   []ParameterError{{Parameter: "domain", Message: missingMessage, Missing: true}},
+			code:   errcode.MissingParameters,
+		},
+		{
+			name:   "require satisfied",
+			params: map[string]string{"domain": "a.b", "ssl_cert": "/c.pem", "ssl_key": "/k.pem"},
+		},
+		{
+			name:   "require violated",
+			params: map[string]string{"domain": "a.b", "ssl_cert": "/c.pem"},
+			want:   []ParameterError{{Parameter: "ssl_key", Message: "required when ssl_cert is set", Missing: true}},
+			code:   errcode.MissingParameters,
+		},
+		{
+			name:   "conflicting parameter left empty",
+			params: map[string]string{"domain": "a.b", "basic_auth": "/etc/htpasswd", "ip_allow": ""},
+		},
+		{
+			name:   "one of the conflicting parameters",
+			params: map[string]string{"domain": "a.b", "oauth_proxy": "http://auth"},
+		},
+		{
+			name:   "conflicting parameters",
+			params: map[string]string{"domain": "a.b", "basic_auth": "/etc/htpasswd", "ip_allow": "10.0.0.0/8"},
+			want: []ParameterError{
+				{Parameter: "basic_auth", Message: "cannot be used together with ip_allow (at most one of: basic_auth, oauth_proxy, ip_allow)"},
+				{Parameter: "ip_allow", Message: "cannot be used together with basic_auth (at most one of: basic_auth, oauth_proxy, ip_allow)"},
+			},
+			code: errcode.InvalidParameters,
+		},
+		{
+			name:   "when condition holds and the parameter is missing",
+			params: map[string]string{"domain": "a.b", "enable_cache": "true"},
+			want:   []ParameterError{{Parameter: "cache_ttl", Message: missingMessage, Missing: true}},
+			code:   errcode.MissingParameters,
+		},
+		{
+			name:   "when condition holds and the value is invalid",
+			params: map[string]string{"domain": "a.b", "enable_cache": "true", "cache_ttl": "0"},
+			want:   []ParameterError{{Parameter: "cache_ttl", Message: "must be at least 1"}},
+			code:   errcode.InvalidParameters,
+		},
+		{
+			name:   "when condition does not hold",
+			params: map[string]string{"domain": "a.b", "enable_cache": "false", "cache_ttl": "0"},
+		},
+		{
+			name:   "negated condition",
+			params: map[string]string{"domain": "a.b", "enable_cache": "false", "no_cache_header": "X-Cache"},
+		},
+		{
+			name:   "value condition holds",
+			params: map[string]string{"domain": "a.b", "mode": "static"},
+			want:   []ParameterError{{Parameter: "root", Message: missingMessage, Missing: true}},
+			code:   errcode.MissingParameters,
+		},
+		{
+			name:   "undeclared values",
+			params: map[string]string{"domain": "a.b", "extra": "x; include /etc/passwd", "note": "a#b", "comment": "# x"},
+			want: []ParameterError{
+				{Parameter: "comment", Message: "has '#' at the start of a word, which would comment out the rest of the line (declare the parameter as raw to allow it)"},
+				{Parameter: "extra", Message: "contains ';', which would end the directive (declare the parameter as raw to allow it)"},
+			},
+			code: errcode.InvalidParameters,
+		},
+		{
+			name:   "missing and invalid together",
+			params: map[string]string{"mode": "redirect"},
+			want: []ParameterError{
+				{Parameter: "domain", Message: missingMessage, Missing: true},
+				{Parameter: "mode", Message: "must be one of: proxy, static"},
+			},
+			code: errcode.InvalidParameters,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := m.ValidateParameters(m.ApplyDefaults(tt.params))
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("ValidateParameters: %v", err)
+				}
+				return
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("ValidateParameters = %v, want a *ValidationError", err)
+			}
+			if !reflect.DeepEqual(verr.Errors, tt.want) {
+				t.Errorf("problems = %+v\nwant       %+v", verr.Errors, tt.want)
+			}
+			if code := errcode.CodeOf(err); code != tt.code {
+				t.Errorf("code = %s, want %s", code, tt.code)
+			}
+		})
+	}
+}
+
+// conditionalTemplate nests a condition on a parameter that is itself
+// conditional.
+const conditionalTemplate = `{{/*
+# Template: conditional
+# Version: 1.0
+# @param enable_ssl boolean optional "HTTPS" default=false
+# @when enable_ssl
+# @param ssl_cert file_path required "Certificate"
+# @param hsts boolean optional "HSTS" default=false
+# @when hsts
+# @param hsts_max_age integer optional "HSTS max-age" default=31536000
+# @end
+# @param domain string required "Domain"
+*/}}
+server {
+    server_name "{{.domain}}";
+{{- if .enable_ssl}}
+    listen 443 ssl;
+{{- if .hsts}}
+    add_header Strict-Transport-Security "max-age={{.hsts_max_age}}";
+{{- end}}
+{{- else}}
+    listen 80;
+{{- end}}
+}
+`
+
+func TestConditionalParameters(t *testing.T) {
+	tmpl, err := ParseTemplate("conditional", "conditional.conf.tpl", conditionalTemplate)
+	if err != nil {
+		t.Fatalf("ParseTemplate: %v", err)
+	}
+	m := tmpl.Metadata
+
+	when := make(map[string]string)
+	for _, param := range m.Parameters {
+		when[param.Name] = param.When
+	}
+	wantWhen := map[string]string{"enable_ssl": "", "ssl_cert": "enable_ssl", "hsts": "enable_ssl", "hsts_max_age": "hsts", "domain": ""}
+	if !reflect.DeepEqual(when, wantWhen) {
+		t.Errorf("conditions = %v, want %v", when, wantWhen)
+	}
+
+	tests := []struct {
+		name   string
+		params map[string]string
+		active []string
+		render string
+	}{
+		{
+			name:   "off",
+			params: map[string]string{"domain": "a.b", "hsts": "true"},
+			active: []string{"enable_ssl", "domain"},
+			render: "listen 80;",
+		},
+		{
+			name:   "on",
+			params: map[string]string{"domain": "a.b", "enable_ssl": "true", "ssl_cert": "/c.pem"},
+			active: []string{"enable_ssl", "ssl_cert", "hsts", "domain"},
+			render: "listen 443 ssl;\n}",
+		},
+		{
+			name:   "nested on",
+			params: map[string]string{"domain": "a.b", "enable_ssl": "true", "ssl_cert": "/c.pem", "hsts": "true"},
+			active: []string{"enable_ssl", "ssl_cert", "hsts", "hsts_max_age", "domain"},
+			render: `add_header Strict-Transport-Security "max-age=31536000";`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := m.ApplyDefaults(tt.params)
+			var active []string
+			for _, param := range m.Parameters {
+				if m.IsActive(param, params) {
+					active = append(active, param.Name)
+				}
+			}
+			if !reflect.DeepEqual(active, tt.active) {
+				t.Errorf("active = %v, want %v", active, tt.active)
+			}
+
+			content, err := tmpl.RenderWithValidation(tt.params)
+			if err != nil {
+				t.Fatalf("RenderWithValidation: %v", err)
+			}
+			if !strings.Contains(content, tt.render) {
+				t.Errorf("render does not contain %q:\n%s", tt.render, content)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/vourteen14/ngcli/errcode"
+)
+
+// DefaultOutputMode is the mode of output files that do not declare one.
+const DefaultOutputMode os.FileMode = 0644
+
+// OutputFile is an additional file written by a template, such as an
+// upstream definition for conf.d or an htpasswd file, declared by
+//
+//	# @output upstream "/etc/nginx/conf.d/{{.config_name}}-upstream.conf" mode=0644
+//
+// Its content is the {{define "upstream"}} block of the template. Path is
+// a template expression like a computed default; relative paths are
+// relative to the directory of the main configuration, and a path that
+// renders empty skips the file.
+type OutputFile struct {
+	Name string
+	Path string
+	Mode os.FileMode
+	Line int
+}
+
+// RenderedFile is the content of an output file for one set of values.
+// Mode is 0 when the template declares none.
+type RenderedFile struct {
+	Name    string
+	Path    string
+	Mode    os.FileMode
+	Content string
+}
+
+var outputRegex = regexp.MustCompile(`^#\s*@output\s+(\w[\w.-]*)\s+(?:"((?:[^"\\]|\\.)*)"|(\S+))(?:\s+mode=(\S+))?$`)
+
+// parseOutputLine parses an "# @output" line; ok is false for other lines.
+func parseOutputLine(line string, lineNumber int) (OutputFile, bool, error) {
+	match := outputRegex.FindStringSubmatch(line)
+	if match == nil {
+		return OutputFile{}, false, nil
+	}
+
+	output := OutputFile{Name: match[1], Path: match[3], Line: lineNumber}
+	if match[3] == "" {
+		output.Path = unescapeQuoted(match[2])
+	}
+	if match[4] != "" {
+		mode, err := parseFileMode(match[4])
+		if err != nil {
+			return OutputFile{}, true, fmt.Errorf("output %s: %w", output.Name, err)
+		}
+		output.Mode = mode
+	}
+	return output, true, nil
+}
+
+func parseFileMode(value string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid file mode %q (use octal, e.g. 0640)", value)
+	}
+	return os.FileMode(mode), nil
+}
+
+// FormatFileMode formats a mode the way it is declared, e.g. "0640".
+func FormatFileMode(mode os.FileMode) string {
+	return fmt.Sprintf("%04o", mode.Perm())
+}
+
+// OutputMode returns the mode a generated file is written with: the
+// declared mode, or DefaultOutputMode, narrowed to 0600 when an undeclared
+// mode would expose secrets. Only root (nginx reads its configuration as
+// root) may read secrets.
+func OutputMode(declared os.FileMode, content string, secrets []string) os.FileMode {
+	if declared != 0 {
+		return declared
+	}
+	if MaskSecrets(content, secrets) != content {
+		return 0600
+	}
+	return DefaultOutputMode
+}
+
+// compileOutputs parses the path expressions of the declared outputs.
+func (m *TemplateMetadata) compileOutputs() error {
+	declared := map[string]bool{ConfigNameParameter: true}
+	for _, param := range m.Parameters {
+		declared[param.Name] = true
+	}
+
+	m.outputPaths = make(map[string]*template.Template)
+	for _, output := range m.Outputs {
+		if _, exists := m.outputPaths[output.Name]; exists {
+			return fmt.Errorf("output %s is declared twice", output.Name)
+		}
+		if strings.TrimSpace(output.Path) == "" {
+			return fmt.Errorf("output %s: path is required", output.Name)
+		}
+		if strings.Contains(output.Path, "{{") && !m.wrapped {
+			return fmt.Errorf("output %s: path expressions need the metadata header wrapped in {{/* ... */}}, otherwise the expression is rendered into the configuration", output.Name)
+		}
+
+		tmpl, err := template.New(output.Name).Funcs(templateFuncs).Option("missingkey=error").Parse(output.Path)
+		if err != nil {
+			return fmt.Errorf("output %s: invalid path: %w", output.Name, err)
+		}
+
+		w := &referenceWalker{content: output.Path}
+		w.walk(tmpl.Tree.Root, true)
+		for _, ref := range w.refs {
+			if !declared[ref.Name] {
+				return fmt.Errorf("output %s: path references undeclared parameter %s", output.Name, ref.Name)
+			}
+		}
+
+		m.outputPaths[output.Name] = tmpl
+	}
+
+	return nil
+}
+
+// checkOutputs verifies that every declared output has a {{define}} block.
+func (t *Template) checkOutputs() error {
+	for _, output := range t.Metadata.Outputs {
+		if t.Template.Lookup(output.Name) == nil {
+			return fmt.Errorf("output %s has no {{define %q}} block", output.Name, output.Name)
+		}
+	}
+	return nil
+}
+
+// RenderOutputs renders the additional output files of the template for
+// params, which should already have passed RenderWithValidation. Outputs
+// whose path renders empty are left out.
+func (t *Template) RenderOutputs(params map[string]string) ([]RenderedFile, error) {
+	withDefaults, err := t.Metadata.ComputeDefaults(params)
+	if err != nil {
+		return nil, err
+	}
+	data := t.Metadata.TypedValues(withDefaults)
+
+	var files []RenderedFile
+	for _, output := range t.Metadata.Outputs {
+		var path strings.Builder
+		if err := t.Metadata.outputPaths[output.Name].Execute(&path, data); err != nil {
+			return nil, errcode.Errorf(errcode.TemplateInvalid, "failed to render path of output %s: %w", output.Name, err)
+		}
+		if strings.TrimSpace(path.String()) == "" {
+			continue
+		}
+
+		var content strings.Builder
+		if err := t.Template.ExecuteTemplate(&content, output.Name, data); err != nil {
+			return nil, errcode.Errorf(errcode.TemplateInvalid, "failed to render output %s: %w", output.Name, err)
+		}
+
+		files = append(files, RenderedFile{
+			Name:    output.Name,
+			Path:    strings.TrimSpace(path.String()),
+			Mode:    output.Mode,
+			Content: content.String(),
+		})
+	}
+
+	return files, nil
+}
@@ -0,0 +1,562 @@
+package template
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vourteen14/ngcli/filesystem"
+	"github.com/vourteen14/ngcli/utils"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// PackageManifestFile is the manifest at the root of a template package.
+	PackageManifestFile = "package.yaml"
+
+	packagesDir = ".packages"
+	partialsDir = "partials"
+)
+
+var packageNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// reservedPackageNames are directories of the template directory that a
+// package, installed as <templateDir>/<name>, must not replace.
+var reservedPackageNames = map[string]bool{
+	partialsDir:    true,
+	builtinBaseDir: true,
+	packagesDir:    true,
+}
+
+// PackageManifest describes a versioned bundle of templates. Installed
+// templates are namespaced by the package name, e.g. acme/prod.
+type PackageManifest struct {
+	Name            string   `yaml:"name"`
+	Version         string   `yaml:"version"`
+	Description     string   `yaml:"description,omitempty"`
+	Templates       []string `yaml:"templates,omitempty"`
+	Partials        []string `yaml:"partials,omitempty"`
+	MinNgcliVersion string   `yaml:"min_ngcli_version,omitempty"`
+}
+
+// InstalledPackage is the record kept for every installed package.
+type InstalledPackage struct {
+	PackageManifest `yaml:",inline"`
+	Source          string    `yaml:"source"`
+	InstalledAt     time.Time `yaml:"installed_at"`
+}
+
+// AvailablePackage is a package found in a configured source.
+type AvailablePackage struct {
+	PackageManifest
+	Source string
+}
+
+// LoadPackageManifest reads and checks package.yaml in dir. Templates and
+// partials default to the *.conf.tpl and partials/*.tpl files of the package.
+func LoadPackageManifest(dir string) (*PackageManifest, error) {
+	path := filepath.Join(dir, PackageManifestFile)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package manifest %s: %w", path, err)
+	}
+
+	var manifest PackageManifest
+	if err := yaml.UnmarshalStrict(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse package manifest %s: %w", path, err)
+	}
+
+	if !packageNameRegex.MatchString(manifest.Name) {
+		return nil, fmt.Errorf("invalid package name %q (use lowercase letters, digits, - and _)", manifest.Name)
+	}
+	if reservedPackageNames[manifest.Name] {
+		return nil, fmt.Errorf("invalid package name %q (reserved for the template directory)", manifest.Name)
+	}
+	if manifest.Version == "" {
+		return nil, fmt.Errorf("package %s has no version", manifest.Name)
+	}
+
+	if len(manifest.Templates) == 0 {
+		for _, pattern := range []string{"*.conf.tpl", "templates/*.conf.tpl"} {
+			matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+			for _, match := range matches {
+				rel, _ := filepath.Rel(dir, match)
+				manifest.Templates = append(manifest.Templates, rel)
+			}
+		}
+	}
+	if len(manifest.Templates) == 0 {
+		return nil, fmt.Errorf("package %s contains no templates", manifest.Name)
+	}
+
+	if len(manifest.Partials) == 0 {
+		matches, _ := filepath.Glob(filepath.Join(dir, partialsDir, "*.tpl"))
+		for _, match := range matches {
+			rel, _ := filepath.Rel(dir, match)
+			manifest.Partials = append(manifest.Partials, rel)
+		}
+	}
+
+	return &manifest, nil
+}
+
+// FetchPackageSource makes a package source available on disk. Sources can be
+// a local directory, a .tar.gz/.tgz archive (local or http), or a git
+// repository, written as <location>[//subdir][#ref]: "//subdir" selects a
+// package inside a multi-package source and "#ref" a git branch or tag. The
+// returned cleanup function removes any temporary checkout.
+func FetchPackageSource(source string) (string, func(), error) {
+	location, ref, subdir := splitPackageSource(source)
+
+	dir, cleanup, err := fetchLocation(location, ref)
+	if err != nil || subdir == "" {
+		return dir, cleanup, err
+	}
+
+	packageDir := filepath.Join(dir, subdir)
+	if !strings.HasPrefix(packageDir, filepath.Clean(dir)+string(os.PathSeparator)) || !utils.FileExists(packageDir) {
+		cleanup()
+		return "", func() {}, fmt.Errorf("package directory %s not found in %s", subdir, location)
+	}
+
+	return packageDir, cleanup, nil
+}
+
+func splitPackageSource(source string) (location, ref, subdir string) {
+	source, ref, _ = strings.Cut(source, "#")
+
+	scheme := ""
+	if i := strings.Index(source, "://"); i >= 0 {
+		scheme, source = source[:i+3], source[i+3:]
+	}
+	if i := strings.Index(source, "//"); i >= 0 {
+		source, subdir = source[:i], source[i+2:]
+	}
+
+	return scheme + source, ref, subdir
+}
+
+func joinPackageSource(source, subdir string) string {
+	location, ref, _ := splitPackageSource(source)
+	joined := location + "//" + subdir
+	if ref != "" {
+		joined += "#" + ref
+	}
+	return joined
+}
+
+func fetchLocation(location, ref string) (string, func(), error) {
+	noop := func() {}
+
+	if isTarball(location) {
+		tmpDir, err := os.MkdirTemp("", "ngcli-package-")
+		if err != nil {
+			return "", noop, fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		cleanup := func() { os.RemoveAll(tmpDir) }
+
+		archive := location
+		if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+			archive = filepath.Join(tmpDir, "package.tar.gz")
+			if err := download(location, archive); err != nil {
+				cleanup()
+				return "", noop, err
+			}
+		}
+
+		extracted := filepath.Join(tmpDir, "package")
+		if err := filesystem.ExtractTarGz(archive, extracted); err != nil {
+			cleanup()
+			return "", noop, err
+		}
+
+		return singleRoot(extracted), cleanup, nil
+	}
+
+	if isGitSource(location) {
+		tmpDir, err := os.MkdirTemp("", "ngcli-package-")
+		if err != nil {
+			return "", noop, fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		cleanup := func() { os.RemoveAll(tmpDir) }
+
+		checkout := filepath.Join(tmpDir, "checkout")
+		args := []string{"clone", "--quiet"}
+		if !utils.FileExists(location) {
+			args = append(args, "--depth", "1")
+		}
+		if ref != "" {
+			args = append(args, "--branch", ref)
+		}
+		args = append(args, location, checkout)
+
+		cmd := exec.Command("git", args...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			cleanup()
+			return "", noop, fmt.Errorf("failed to clone %s: %s", location, strings.TrimSpace(string(output)))
+		}
+
+		return checkout, cleanup, nil
+	}
+
+	info, err := os.Stat(location)
+	if err != nil {
+		return "", noop, fmt.Errorf("package source not found: %s", location)
+	}
+	if !info.IsDir() {
+		return "", noop, fmt.Errorf("unsupported package source: %s (expected a directory, .tar.gz or git repository)", location)
+	}
+
+	return location, noop, nil
+}
+
+func isTarball(location string) bool {
+	return strings.HasSuffix(location, ".tar.gz") || strings.HasSuffix(location, ".tgz")
+}
+
+func isGitSource(location string) bool {
+	for _, prefix := range []string{"git@", "git://", "ssh://", "file://", "http://", "https://"} {
+		if strings.HasPrefix(location, prefix) {
+			return true
+		}
+	}
+
+	if strings.HasSuffix(location, ".git") {
+		return true
+	}
+
+	// A local bare repository or working copy
+	if utils.FileExists(filepath.Join(location, "HEAD")) && utils.FileExists(filepath.Join(location, "objects")) {
+		return true
+	}
+	return utils.FileExists(filepath.Join(location, ".git")) && !utils.FileExists(filepath.Join(location, PackageManifestFile))
+}
+
+func download(url, dest string) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dest, err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		return fmt.Errorf("failed to download %s: %w", url, err)
+	}
+
+	return nil
+}
+
+// singleRoot descends into the only top-level directory of an extracted
+// archive, which is how most tarballs are laid out.
+func singleRoot(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 || !entries[0].IsDir() {
+		return dir
+	}
+	return filepath.Join(dir, entries[0].Name())
+}
+
+// InstallPackage installs the package found at source into templateDir.
+// Templates are copied to <templateDir>/<package>/ so they are addressed as
+// <package>/<template> and never collide with local templates.
+func InstallPackage(source, templateDir, ngcliVersion string, force bool) (*InstalledPackage, error) {
+	dir, cleanup, err := FetchPackageSource(source)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	manifest, err := LoadPackageManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest.MinNgcliVersion != "" && utils.CompareVersions(ngcliVersion, manifest.MinNgcliVersion) < 0 {
+		return nil, fmt.Errorf("package %s requires ngcli >= %s (running %s)", manifest.Name, manifest.MinNgcliVersion, ngcliVersion)
+	}
+
+	if existing, err := LoadInstalledPackage(manifest.Name, templateDir); err == nil {
+		if !force {
+			return nil, fmt.Errorf("package %s %s is already installed (use 'ngcli template upgrade' or --force)", existing.Name, existing.Version)
+		}
+	} else if utils.FileExists(filepath.Join(templateDir, manifest.Name)) {
+		return nil, fmt.Errorf("cannot install package %s: %s already exists and is not a package", manifest.Name, filepath.Join(templateDir, manifest.Name))
+	}
+
+	if err := utils.EnsureDir(templateDir); err != nil {
+		return nil, fmt.Errorf("failed to create template directory: %w", err)
+	}
+
+	// Stage the package next to its final location so a broken package
+	// never replaces a working installation
+	stageDir, err := os.MkdirTemp(templateDir, "."+manifest.Name+"-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(stageDir)
+
+	// Templates and partials are installed flat, addressed by their base
+	// names, so two files may not share one
+	copied := make(map[string]string)
+	for _, partial := range manifest.Partials {
+		if err := copyPackageFile(dir, partial, filepath.Join(stageDir, partialsDir), copied); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, tpl := range manifest.Templates {
+		if !strings.HasSuffix(tpl, ".conf.tpl") {
+			return nil, fmt.Errorf("package template %s must have a .conf.tpl extension", tpl)
+		}
+		if err := copyPackageFile(dir, tpl, stageDir, copied); err != nil {
+			return nil, err
+		}
+
+		testsFile := strings.TrimSuffix(tpl, ".conf.tpl") + ".tests.yaml"
+		if utils.FileExists(filepath.Join(dir, testsFile)) {
+			if err := copyPackageFile(dir, testsFile, stageDir, copied); err != nil {
+				return nil, err
+			}
+		}
+
+		name := strings.TrimSuffix(filepath.Base(tpl), ".conf.tpl")
+		if _, err := LoadTemplate(name, stageDir); err != nil {
+			return nil, fmt.Errorf("package %s: invalid template %s: %w", manifest.Name, tpl, err)
+		}
+	}
+
+	targetDir := filepath.Join(templateDir, manifest.Name)
+	if err := os.RemoveAll(targetDir); err != nil {
+		return nil, fmt.Errorf("failed to remove previous installation: %w", err)
+	}
+	if err := os.Rename(stageDir, targetDir); err != nil {
+		return nil, fmt.Errorf("failed to install package %s: %w", manifest.Name, err)
+	}
+
+	installed := &InstalledPackage{
+		PackageManifest: *manifest,
+		Source:          source,
+		InstalledAt:     time.Now().UTC().Truncate(time.Second),
+	}
+
+	if err := saveInstalledPackage(installed, templateDir); err != nil {
+		return nil, err
+	}
+
+	return installed, nil
+}
+
+// copyPackageFile copies the package file rel into destDir under its base
+// name. copied records the files copied so far by destination, so that
+// two files with the same base name are reported instead of one silently
+// replacing the other. Symbolic links are refused, as they could pull in
+// any file of the host.
+func copyPackageFile(packageDir, rel, destDir string, copied map[string]string) error {
+	src := filepath.Join(packageDir, rel)
+	if !strings.HasPrefix(filepath.Clean(src), filepath.Clean(packageDir)+string(os.PathSeparator)) {
+		return fmt.Errorf("package file escapes the package directory: %s", rel)
+	}
+	info, err := os.Lstat(src)
+	if err != nil {
+		return fmt.Errorf("failed to read package file %s: %w", rel, err)
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("package file %s is a symbolic link", rel)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("package file %s is not a regular file", rel)
+	}
+	dest := filepath.Join(destDir, filepath.Base(rel))
+	if previous, ok := copied[dest]; ok {
+		return fmt.Errorf("package files %s and %s have the same name %s", previous, rel, filepath.Base(rel))
+	}
+	copied[dest] = rel
+	return filesystem.CopyFile(src, dest)
+}
+
+// UpgradePackage reinstalls a package from its recorded source when the
+// source offers a newer version. It returns the new record, or nil when the
+// installed version is already current.
+func UpgradePackage(name, templateDir, ngcliVersion string) (*InstalledPackage, error) {
+	installed, err := LoadInstalledPackage(name, templateDir)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, cleanup, err := FetchPackageSource(installed.Source)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := LoadPackageManifest(dir)
+	cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest.Name != installed.Name {
+		return nil, fmt.Errorf("source %s now provides package %s instead of %s", installed.Source, manifest.Name, installed.Name)
+	}
+
+	if utils.CompareVersions(manifest.Version, installed.Version) <= 0 {
+		return nil, nil
+	}
+
+	return InstallPackage(installed.Source, templateDir, ngcliVersion, true)
+}
+
+// UninstallPackage removes an installed package and its templates.
+func UninstallPackage(name, templateDir string) error {
+	if _, err := LoadInstalledPackage(name, templateDir); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(filepath.Join(templateDir, name)); err != nil {
+		return fmt.Errorf("failed to remove package %s: %w", name, err)
+	}
+
+	if err := os.Remove(installedPackagePath(name, templateDir)); err != nil {
+		return fmt.Errorf("failed to remove package record %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func installedPackagePath(name, templateDir string) string {
+	return filepath.Join(templateDir, packagesDir, name+".yaml")
+}
+
+// LoadInstalledPackage reads the record of an installed package.
+func LoadInstalledPackage(name, templateDir string) (*InstalledPackage, error) {
+	data, err := os.ReadFile(installedPackagePath(name, templateDir))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("package not installed: %s", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package record %s: %w", name, err)
+	}
+
+	var installed InstalledPackage
+	if err := yaml.Unmarshal(data, &installed); err != nil {
+		return nil, fmt.Errorf("failed to parse package record %s: %w", name, err)
+	}
+
+	return &installed, nil
+}
+
+func saveInstalledPackage(installed *InstalledPackage, templateDir string) error {
+	data, err := yaml.Marshal(installed)
+	if err != nil {
+		return fmt.Errorf("failed to encode package record: %w", err)
+	}
+
+	return filesystem.WriteFile(installedPackagePath(installed.Name, templateDir), string(data), true)
+}
+
+// ListInstalledPackages returns the records of all installed packages.
+// Records that cannot be read are skipped and reported as warnings.
+func ListInstalledPackages(templateDir string) ([]InstalledPackage, []string, error) {
+	entries, err := os.ReadDir(filepath.Join(templateDir, packagesDir))
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read installed packages: %w", err)
+	}
+
+	var packages []InstalledPackage
+	var warnings []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".yaml") {
+			continue
+		}
+		installed, err := LoadInstalledPackage(strings.TrimSuffix(entry.Name(), ".yaml"), templateDir)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("skipping package: %v", err))
+			continue
+		}
+		packages = append(packages, *installed)
+	}
+
+	return packages, warnings, nil
+}
+
+// SearchPackages looks for packages matching query (name, description or
+// template names; empty matches everything) in the given sources. A source
+// is either a package itself or a directory/repository whose immediate
+// subdirectories are packages.
+func SearchPackages(sources []string, query string) ([]AvailablePackage, error) {
+	var found []AvailablePackage
+	query = strings.ToLower(query)
+
+	for _, source := range sources {
+		dir, cleanup, err := FetchPackageSource(source)
+		if err != nil {
+			return nil, err
+		}
+
+		var candidates []string
+		if utils.FileExists(filepath.Join(dir, PackageManifestFile)) {
+			candidates = append(candidates, "")
+		} else if entries, err := os.ReadDir(dir); err == nil {
+			for _, entry := range entries {
+				if entry.IsDir() && utils.FileExists(filepath.Join(dir, entry.Name(), PackageManifestFile)) {
+					candidates = append(candidates, entry.Name())
+				}
+			}
+		}
+
+		for _, sub := range candidates {
+			manifest, err := LoadPackageManifest(filepath.Join(dir, sub))
+			if err != nil {
+				continue
+			}
+			if query != "" && !manifestMatches(manifest, query) {
+				continue
+			}
+
+			pkgSource := source
+			if sub != "" {
+				pkgSource = joinPackageSource(source, sub)
+			}
+			found = append(found, AvailablePackage{PackageManifest: *manifest, Source: pkgSource})
+		}
+
+		cleanup()
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].Name < found[j].Name
+	})
+
+	return found, nil
+}
+
+func manifestMatches(manifest *PackageManifest, query string) bool {
+	if strings.Contains(strings.ToLower(manifest.Name), query) || strings.Contains(strings.ToLower(manifest.Description), query) {
+		return true
+	}
+	for _, tpl := range manifest.Templates {
+		if strings.Contains(strings.ToLower(filepath.Base(tpl)), query) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,232 @@
+package template
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gitRepo is a working copy pushing to a bare repository, the package
+// source.
+type gitRepo struct {
+	t    *testing.T
+	work string
+	bare string
+}
+
+func newGitRepo(t *testing.T) *gitRepo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	r := &gitRepo{t: t, work: filepath.Join(root, "work"), bare: filepath.Join(root, "acme.git")}
+	r.git(root, "init", "--quiet", "--bare", r.bare)
+	r.git(root, "init", "--quiet", r.work)
+	return r
+}
+
+func (r *gitRepo) git(dir string, args ...string) {
+	r.t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		r.t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+	}
+}
+
+// commit writes files into the working copy, then commits and pushes
+// them.
+func (r *gitRepo) commit(files map[string]string) {
+	r.t.Helper()
+	for name, content := range files {
+		path := filepath.Join(r.work, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			r.t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			r.t.Fatal(err)
+		}
+	}
+	r.git(r.work, "add", "-A")
+	r.git(r.work, "commit", "--quiet", "-m", "update")
+	r.git(r.work, "push", "--quiet", r.bare, "HEAD:refs/heads/master")
+}
+
+func packageManifest(version string) string {
+	return "name: acme\nversion: " + version + "\ndescription: Acme templates\n"
+}
+
+func packageTemplate(version string) string {
+	return "# Template: prod\n# Version: " + version + "\n# @param domain string required \"Domain\"\nserver { server_name \"{{.domain}}\"; }\n"
+}
+
+func TestPackageLifecycle(t *testing.T) {
+	repo := newGitRepo(t)
+	repo.commit(map[string]string{
+		PackageManifestFile: packageManifest("1.0.0"),
+		"prod.conf.tpl":     packageTemplate("1.0.0"),
+	})
+	templateDir := t.TempDir()
+
+	if _, err := InstallBuiltins(templateDir); err != nil {
+		t.Fatalf("InstallBuiltins: %v", err)
+	}
+
+	installed, err := InstallPackage(repo.bare, templateDir, "1.0.0", false)
+	if err != nil {
+		t.Fatalf("InstallPackage: %v", err)
+	}
+	if installed.Name != "acme" || installed.Version != "1.0.0" {
+		t.Fatalf("installed %s %s, want acme 1.0.0", installed.Name, installed.Version)
+	}
+
+	// The package template is namespaced and leaves the built-in alone
+	packaged, err := LoadTemplate("acme/prod", templateDir)
+	if err != nil {
+		t.Fatalf("LoadTemplate(acme/prod): %v", err)
+	}
+	if packaged.Metadata.Version != "1.0.0" {
+		t.Errorf("acme/prod version = %q, want 1.0.0", packaged.Metadata.Version)
+	}
+	builtin, err := LoadTemplate("prod", templateDir)
+	if err != nil {
+		t.Fatalf("LoadTemplate(prod): %v", err)
+	}
+	if content, _ := BuiltinContent("prod"); builtin.Content != content {
+		t.Errorf("installing acme changed the built-in prod template")
+	}
+
+	if _, err := InstallPackage(repo.bare, templateDir, "1.0.0", false); err == nil {
+		t.Errorf("installing acme again without force succeeded")
+	}
+
+	upgraded, err := UpgradePackage("acme", templateDir, "1.0.0")
+	if err != nil || upgraded != nil {
+		t.Fatalf("UpgradePackage without a new version = %v, %v; want nil, nil", upgraded, err)
+	}
+
+	repo.commit(map[string]string{
+		PackageManifestFile: packageManifest("1.1.0"),
+		"prod.conf.tpl":     packageTemplate("1.1.0"),
+	})
+	upgraded, err = UpgradePackage("acme", templateDir, "1.0.0")
+	if err != nil {
+		t.Fatalf("UpgradePackage: %v", err)
+	}
+	if upgraded == nil || upgraded.Version != "1.1.0" {
+		t.Fatalf("UpgradePackage = %v, want version 1.1.0", upgraded)
+	}
+	if packaged, err = LoadTemplate("acme/prod", templateDir); err != nil || packaged.Metadata.Version != "1.1.0" {
+		t.Fatalf("acme/prod after upgrade = %v, %v; want version 1.1.0", packaged, err)
+	}
+
+	if err := UninstallPackage("acme", templateDir); err != nil {
+		t.Fatalf("UninstallPackage: %v", err)
+	}
+	if _, err := LoadTemplate("acme/prod", templateDir); err == nil {
+		t.Errorf("acme/prod still loads after uninstall")
+	}
+	if _, err := LoadInstalledPackage("acme", templateDir); err == nil {
+		t.Errorf("acme still recorded after uninstall")
+	}
+	if _, err := LoadTemplate("prod", templateDir); err != nil {
+		t.Errorf("uninstalling acme removed the built-in prod: %v", err)
+	}
+}
+
+func TestInstallPackageRejectsDuplicateNames(t *testing.T) {
+	repo := newGitRepo(t)
+	repo.commit(map[string]string{
+		PackageManifestFile: packageManifest("1.0.0") + "templates:\n  - web/prod.conf.tpl\n  - api/prod.conf.tpl\n",
+		"web/prod.conf.tpl": packageTemplate("1.0.0"),
+		"api/prod.conf.tpl": packageTemplate("1.0.0"),
+	})
+	templateDir := t.TempDir()
+
+	_, err := InstallPackage(repo.bare, templateDir, "1.0.0", false)
+	if err == nil || !strings.Contains(err.Error(), "same name") {
+		t.Fatalf("InstallPackage = %v, want an error about the same name", err)
+	}
+	if _, err := os.Stat(filepath.Join(templateDir, "acme")); !os.IsNotExist(err) {
+		t.Errorf("a failed install left %s behind", filepath.Join(templateDir, "acme"))
+	}
+}
+
+// writePackage writes files into a new local package source.
+func writePackage(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestInstallPackageRejectsReservedNames(t *testing.T) {
+	for _, name := range []string{partialsDir, builtinBaseDir, packagesDir} {
+		source := writePackage(t, map[string]string{
+			PackageManifestFile: "name: " + name + "\nversion: 1.0.0\n",
+			"prod.conf.tpl":     packageTemplate("1.0.0"),
+		})
+		templateDir := t.TempDir()
+
+		_, err := InstallPackage(source, templateDir, "1.0.0", false)
+		if err == nil || !strings.Contains(err.Error(), "invalid package name") {
+			t.Errorf("InstallPackage(%s) = %v, want an invalid package name error", name, err)
+		}
+	}
+}
+
+func TestInstallPackageRefusesSymlinks(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "secret.conf.tpl")
+	if err := os.WriteFile(secret, []byte(packageTemplate("1.0.0")), 0600); err != nil {
+		t.Fatal(err)
+	}
+	source := writePackage(t, map[string]string{PackageManifestFile: packageManifest("1.0.0")})
+	if err := os.Symlink(secret, filepath.Join(source, "prod.conf.tpl")); err != nil {
+		t.Fatal(err)
+	}
+	templateDir := t.TempDir()
+
+	_, err := InstallPackage(source, templateDir, "1.0.0", false)
+	if err == nil || !strings.Contains(err.Error(), "symbolic link") {
+		t.Fatalf("InstallPackage = %v, want an error about the symbolic link", err)
+	}
+	if _, err := os.Stat(filepath.Join(templateDir, "acme")); !os.IsNotExist(err) {
+		t.Errorf("a refused install left %s behind", filepath.Join(templateDir, "acme"))
+	}
+}
+
+func TestListTemplatesSkipsUnreadablePackageRecords(t *testing.T) {
+	source := writePackage(t, map[string]string{
+		PackageManifestFile: packageManifest("1.0.0"),
+		"prod.conf.tpl":     packageTemplate("1.0.0"),
+	})
+	templateDir := t.TempDir()
+	if _, err := InstallPackage(source, templateDir, "1.0.0", false); err != nil {
+		t.Fatalf("InstallPackage: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(templateDir, packagesDir, "broken.yaml"), []byte("name: [\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	templates, warnings, err := ListTemplates(templateDir)
+	if err != nil {
+		t.Fatalf("ListTemplates: %v", err)
+	}
+	if len(templates) != 1 || templates[0] != "acme/prod" {
+		t.Errorf("templates = %v, want [acme/prod]", templates)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "package record broken") {
+		t.Errorf("warnings = %q, want one about the broken record", warnings)
+	}
+}
@@ -0,0 +1,444 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// JSONSchemaDraft is the JSON Schema dialect produced by JSONSchema.
+const JSONSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// scalarTypes are the JSON types a values file may contain at all.
+var scalarTypes = []string{"string", "number", "boolean"}
+
+// valueConstraint is a single rule on a parameter value. The same list
+// drives ValidateParameters (check) and the exported JSON Schema (keyword
+// and value), so the two cannot drift apart.
+type valueConstraint struct {
+	keyword string
+	value   interface{}
+	// check returns the problems found, or nil when value satisfies the
+	// constraint. Values are the string form used by --set. Keywords that
+	// are checked together with another one have no check.
+	check func(value string) []string
+}
+
+// jsonType is the JSON type a parameter has in a values file. Arrays
+// without declared items are plain strings, as they always have been.
+func (p ParameterInfo) jsonType() string {
+	switch {
+	case p.Type == "array" && p.Items != nil:
+		return "array"
+	case p.Type == "object":
+		return "object"
+	case p.Type == "integer":
+		return "integer"
+	case p.Type == "boolean":
+		return "boolean"
+	case p.Type == "secret":
+		return "string"
+	case p.Min != nil || p.Max != nil:
+		return "number"
+	}
+	return "string"
+}
+
+// valueConstraints returns every constraint declared for a parameter.
+func (p ParameterInfo) valueConstraints() []valueConstraint {
+	var constraints []valueConstraint
+
+	switch p.jsonType() {
+	case "integer":
+		constraints = append(constraints, valueConstraint{"type", "integer", func(v string) []string {
+			if _, err := strconv.Atoi(v); err != nil {
+				return []string{"must be an integer"}
+			}
+			return nil
+		}})
+	case "boolean":
+		constraints = append(constraints, valueConstraint{"type", "boolean", func(v string) []string {
+			if v != "true" && v != "false" {
+				return []string{"must be true or false"}
+			}
+			return nil
+		}})
+	case "number":
+		constraints = append(constraints, valueConstraint{"type", "number", func(v string) []string {
+			if _, err := strconv.ParseFloat(v, 64); err != nil {
+				return []string{"must be a number"}
+			}
+			return nil
+		}})
+	case "array":
+		constraints = append(constraints, valueConstraint{"type", "array", func(v string) []string {
+			if _, _, err := p.decodeComposite(v); err != nil {
+				return []string{err.Error()}
+			}
+			return nil
+		}})
+		constraints = append(constraints, valueConstraint{"items", p.Items.propertySchema(), func(v string) []string {
+			items, strict, err := p.decodeComposite(v)
+			if err != nil {
+				return nil
+			}
+			var problems []string
+			for i, item := range items.([]interface{}) {
+				for _, problem := range p.Items.checkValue(item, strict) {
+					problems = append(problems, fmt.Sprintf("item %d %s", i+1, problem))
+				}
+			}
+			return problems
+		}})
+	case "object":
+		constraints = append(constraints, valueConstraint{"type", "object", func(v string) []string {
+			if _, _, err := p.decodeComposite(v); err != nil {
+				return []string{err.Error()}
+			}
+			return nil
+		}})
+		properties := make(map[string]interface{}, len(p.Properties))
+		var required []string
+		for _, prop := range p.Properties {
+			properties[prop.Name] = prop.propertySchema()
+			if prop.Required && prop.Default == "" {
+				required = append(required, prop.Name)
+			}
+		}
+		constraints = append(constraints, valueConstraint{"properties", properties, func(v string) []string {
+			decoded, _, err := p.decodeComposite(v)
+			if err != nil {
+				return nil
+			}
+			object := decoded.(map[string]interface{})
+			var problems []string
+			for _, prop := range p.Properties {
+				field, exists := object[prop.Name]
+				if !exists {
+					if prop.Required && prop.Default == "" {
+						problems = append(problems, fmt.Sprintf("field %s is required", prop.Name))
+					}
+					continue
+				}
+				for _, problem := range prop.checkValue(field, true) {
+					problems = append(problems, fmt.Sprintf("field %s %s", prop.Name, problem))
+				}
+			}
+			return problems
+		}})
+		if len(required) > 0 {
+			constraints = append(constraints, valueConstraint{"required", required, nil})
+		}
+	default:
+		constraints = append(constraints, valueConstraint{"type", "string", func(string) []string { return nil }})
+	}
+
+	if p.Type == "file_path" {
+		constraints = append(constraints, valueConstraint{"pattern", `\S`, func(v string) []string {
+			if strings.TrimSpace(v) == "" {
+				return []string{"file path cannot be empty"}
+			}
+			return nil
+		}})
+	}
+
+	if constraint, ok := p.injectionConstraint(); ok {
+		constraints = append(constraints, constraint)
+	}
+
+	if p.Min != nil {
+		min := *p.Min
+		constraints = append(constraints, valueConstraint{"minimum", min, func(v string) []string {
+			if n, err := strconv.ParseFloat(v, 64); err == nil && n < min {
+				return []string{fmt.Sprintf("must be at least %s", formatNumber(min))}
+			}
+			return nil
+		}})
+	}
+	if p.Max != nil {
+		max := *p.Max
+		constraints = append(constraints, valueConstraint{"maximum", max, func(v string) []string {
+			if n, err := strconv.ParseFloat(v, 64); err == nil && n > max {
+				return []string{fmt.Sprintf("must be at most %s", formatNumber(max))}
+			}
+			return nil
+		}})
+	}
+
+	// Length and pattern constraints only exist for strings in JSON Schema
+	if p.jsonType() == "string" {
+		if p.MinLen != nil {
+			minLen := *p.MinLen
+			constraints = append(constraints, valueConstraint{"minLength", minLen, func(v string) []string {
+				if utf8.RuneCountInString(v) < minLen {
+					return []string{fmt.Sprintf("must be at least %d characters", minLen)}
+				}
+				return nil
+			}})
+		}
+		if p.MaxLen != nil {
+			maxLen := *p.MaxLen
+			constraints = append(constraints, valueConstraint{"maxLength", maxLen, func(v string) []string {
+				if utf8.RuneCountInString(v) > maxLen {
+					return []string{fmt.Sprintf("must be at most %d characters", maxLen)}
+				}
+				return nil
+			}})
+		}
+		if p.Pattern != "" {
+			pattern := regexp.MustCompile(p.Pattern)
+			constraints = append(constraints, valueConstraint{"pattern", p.Pattern, func(v string) []string {
+				if !pattern.MatchString(v) {
+					return []string{fmt.Sprintf("must match pattern %s", p.Pattern)}
+				}
+				return nil
+			}})
+		}
+	}
+
+	if len(p.Options) > 0 {
+		var enum []interface{}
+		for _, option := range p.Options {
+			enum = append(enum, p.typedValue(option))
+		}
+		constraints = append(constraints, valueConstraint{"enum", enum, func(v string) []string {
+			for _, option := range p.Options {
+				if v == option {
+					return nil
+				}
+			}
+			return []string{fmt.Sprintf("must be one of: %s", strings.Join(p.Options, ", "))}
+		}})
+	}
+
+	return constraints
+}
+
+// typedValue converts the string form of a value into its JSON form.
+// Values that do not parse stay strings.
+func (p ParameterInfo) typedValue(value string) interface{} {
+	switch p.jsonType() {
+	case "integer":
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil && (value == "true" || value == "false") {
+			return b
+		}
+	case "number":
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	case "array", "object":
+		if decoded, _, err := p.decodeComposite(value); err == nil {
+			return decoded
+		}
+	}
+	return value
+}
+
+// JSONSchema returns a JSON Schema (draft 2020-12) for the values of the
+// template: one property per parameter, with required parameters, @when
+// conditions and the @require/@conflicts rules expressed as if/then and
+// not clauses. A values file is valid against the schema exactly when
+// ParseValues and ValidateParameters accept it.
+func (m *TemplateMetadata) JSONSchema(id string) map[string]interface{} {
+	schema := map[string]interface{}{
+		"$schema": JSONSchemaDraft,
+		"type":    "object",
+	}
+	if id != "" {
+		schema["$id"] = id
+	}
+	if m.Name != "" {
+		schema["title"] = m.Name
+	}
+	if m.Description != "" {
+		schema["description"] = m.Description
+	}
+	if m.Version != "" {
+		schema["x-ngcli-template-version"] = m.Version
+	}
+
+	properties := make(map[string]interface{})
+	var required []string
+	var allOf []interface{}
+
+	for _, param := range m.Parameters {
+		mandatory := param.Required && param.Default == ""
+
+		if param.When == "" {
+			properties[param.Name] = param.propertySchema()
+			if mandatory {
+				required = append(required, param.Name)
+			}
+			continue
+		}
+
+		// Conditional parameters are only checked while their condition
+		// holds, as in ValidateParameters; until then any scalar is accepted
+		loose := param.annotations()
+		loose["type"] = scalarTypes
+		properties[param.Name] = loose
+		then := map[string]interface{}{
+			"properties": map[string]interface{}{param.Name: param.propertySchema()},
+		}
+		if mandatory {
+			then["required"] = []string{param.Name}
+		}
+		allOf = append(allOf, map[string]interface{}{
+			"if":   m.activeSchema(param, len(m.Parameters)),
+			"then": then,
+		})
+	}
+
+	for _, rule := range m.Requires {
+		var sets []interface{}
+		for _, name := range rule.Params {
+			sets = append(sets, m.setSchema(name))
+		}
+		allOf = append(allOf, map[string]interface{}{
+			"if":   m.setSchema(rule.Condition),
+			"then": map[string]interface{}{"allOf": sets},
+		})
+	}
+
+	for _, group := range m.Conflicts {
+		for i := 0; i < len(group); i++ {
+			for j := i + 1; j < len(group); j++ {
+				allOf = append(allOf, map[string]interface{}{
+					"not": map[string]interface{}{"allOf": []interface{}{m.setSchema(group[i]), m.setSchema(group[j])}},
+				})
+			}
+		}
+	}
+
+	schema["properties"] = properties
+	// Undeclared values are passed through to the template, but only safe
+	// scalars
+	schema["additionalProperties"] = map[string]interface{}{"type": scalarTypes, "pattern": safeValuePattern}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	if len(allOf) > 0 {
+		schema["allOf"] = allOf
+	}
+
+	return schema
+}
+
+// annotations returns the descriptive schema keywords of a parameter.
+func (p ParameterInfo) annotations() map[string]interface{} {
+	schema := make(map[string]interface{})
+	if p.Description != "" {
+		schema["description"] = p.Description
+	}
+	if p.Default != "" && !p.Sensitive {
+		if p.IsComputed() {
+			schema["x-ngcli-default-expression"] = p.Default
+		} else {
+			schema["default"] = p.typedValue(p.Default)
+		}
+	}
+	if p.Deprecated != "" {
+		schema["deprecated"] = true
+		schema["x-ngcli-deprecated"] = p.Deprecated
+	}
+	if p.Sensitive {
+		schema["writeOnly"] = true
+	}
+	schema["x-ngcli-type"] = p.Type
+	return schema
+}
+
+// propertySchema returns the full schema of a parameter value. Keywords
+// that occur more than once (file_path, pattern and the injection check
+// all use "pattern") go into allOf.
+func (p ParameterInfo) propertySchema() map[string]interface{} {
+	schema := p.annotations()
+
+	// Values files only ever hold references to secrets; the declared
+	// constraints apply to the resolved secret
+	if p.Type == "secret" {
+		schema["type"] = "string"
+		schema["pattern"] = secretReferencePattern
+		return schema
+	}
+
+	var extra []interface{}
+	for _, c := range p.valueConstraints() {
+		if _, exists := schema[c.keyword]; exists {
+			extra = append(extra, map[string]interface{}{c.keyword: c.value})
+			continue
+		}
+		schema[c.keyword] = c.value
+	}
+	if len(extra) > 0 {
+		schema["allOf"] = extra
+	}
+
+	return schema
+}
+
+// setSchema matches documents in which the named parameter counts as set
+// (see isSet). A parameter that is absent falls back to its default, so
+// the schema only requires it when the default alone would not count.
+func (m *TemplateMetadata) setSchema(name string) map[string]interface{} {
+	param, declared := m.Parameter(name)
+
+	predicate := map[string]interface{}{"not": map[string]interface{}{"const": ""}}
+	if declared && param.Type == "boolean" {
+		predicate = map[string]interface{}{"const": true}
+	}
+
+	return m.valueSchema(name, predicate, m.isSet(name, map[string]string{name: param.Default}))
+}
+
+// valueSchema matches documents where the named parameter satisfies
+// predicate, treating an absent parameter as matching when absentMatches.
+func (m *TemplateMetadata) valueSchema(name string, predicate map[string]interface{}, absentMatches bool) map[string]interface{} {
+	schema := map[string]interface{}{
+		"properties": map[string]interface{}{name: predicate},
+	}
+	if !absentMatches {
+		schema["required"] = []string{name}
+	}
+	return schema
+}
+
+// activeSchema mirrors isActive: it matches documents in which the @when
+// condition of param, and of every parameter controlling it, holds.
+func (m *TemplateMetadata) activeSchema(param ParameterInfo, depth int) map[string]interface{} {
+	if param.When == "" {
+		return map[string]interface{}{}
+	}
+	if depth < 0 {
+		return map[string]interface{}{"not": map[string]interface{}{}}
+	}
+
+	condition := param.When
+	negate := strings.HasPrefix(condition, "!")
+	condition = strings.TrimPrefix(condition, "!")
+
+	name, expected, hasValue := strings.Cut(condition, "=")
+	controller, declared := m.Parameter(name)
+
+	var schema map[string]interface{}
+	if hasValue {
+		schema = m.valueSchema(name, map[string]interface{}{"const": controller.typedValue(expected)}, controller.Default == expected)
+	} else {
+		schema = m.setSchema(name)
+	}
+	if negate {
+		schema = map[string]interface{}{"not": schema}
+	}
+
+	if declared && controller.When != "" {
+		schema = map[string]interface{}{"allOf": []interface{}{m.activeSchema(controller, depth-1), schema}}
+	}
+
+	return schema
+}
@@ -0,0 +1,215 @@
+package template
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v2"
+)
+
+// schemaTemplate declares a parameter for each type and constraint.
+const schemaTemplate = `{{/* ---
+template: schema-test
+version: "1.0"
+parameters:
+  - name: domain
+    type: string
+    required: true
+    pattern: '^[a-z0-9.-]+$'
+  - name: port
+    type: integer
+    min: 1
+    max: 65535
+    default: 80
+  - name: ratio
+    type: string
+    min: 0
+    max: 1
+  - name: mode
+    type: string
+    options: [proxy, static]
+  - name: label
+    type: string
+    minlen: 3
+    maxlen: 8
+  - name: enable_ssl
+    type: boolean
+  - name: ssl_cert
+    type: file_path
+  - name: proxy_pass
+    type: string
+  - name: root
+    type: string
+  - name: enable_cache
+    type: boolean
+  - name: cache_ttl
+    type: integer
+    required: true
+    when: enable_cache
+  - name: upstreams
+    type: array
+    items:
+      type: object
+      properties:
+        - name: host
+          type: string
+          required: true
+        - name: weight
+          type: integer
+          min: 1
+  - name: token
+    type: secret
+  - name: snippet
+    type: raw
+requires:
+  - params: [ssl_cert]
+    if: enable_ssl
+conflicts:
+  - [proxy_pass, root]
+--- */}}
+server { server_name "{{.domain}}"; }
+`
+
+func TestValidateParametersAgreesWithJSONSchema(t *testing.T) {
+	tmpl, err := ParseTemplate("schema-test", "schema-test.conf.tpl", schemaTemplate)
+	if err != nil {
+		t.Fatalf("ParseTemplate: %v", err)
+	}
+	m := tmpl.Metadata
+
+	encoded, err := json.Marshal(m.JSONSchema(""))
+	if err != nil {
+		t.Fatalf("encoding the schema: %v", err)
+	}
+	schema, err := jsonschema.CompileString("schema.json", string(encoded))
+	if err != nil {
+		t.Fatalf("compiling the schema: %v\n%s", err, encoded)
+	}
+
+	tests := []struct {
+		name   string
+		values string
+		valid  bool
+	}{
+		{"required set", `domain: example.com`, true},
+		{"required missing", `port: 8080`, false},
+		{"pattern match", `domain: api.example.com`, true},
+		{"pattern mismatch", `domain: Example.com`, false},
+
+		{"integer", "domain: a.b\nport: 8080", true},
+		{"integer below min", "domain: a.b\nport: 0", false},
+		{"integer above max", "domain: a.b\nport: 70000", false},
+		{"integer as string", "domain: a.b\nport: '8080'", false},
+		{"integer with fraction", "domain: a.b\nport: 80.5", false},
+
+		{"number in range", "domain: a.b\nratio: 0.5", true},
+		{"number above max", "domain: a.b\nratio: 2", false},
+		{"number as string", "domain: a.b\nratio: half", false},
+
+		{"option", "domain: a.b\nmode: proxy", true},
+		{"not an option", "domain: a.b\nmode: redirect", false},
+
+		{"length in range", "domain: a.b\nlabel: abcd", true},
+		{"too short", "domain: a.b\nlabel: ab", false},
+		{"too long", "domain: a.b\nlabel: abcdefghij", false},
+
+		{"boolean", "domain: a.b\nenable_cache: false", true},
+		{"boolean as string", "domain: a.b\nenable_ssl: 'yes'", false},
+		{"file path blank", "domain: a.b\nssl_cert: ' '", false},
+
+		{"require satisfied", "domain: a.b\nenable_ssl: true\nssl_cert: /etc/ssl/a.pem", true},
+		{"require violated", "domain: a.b\nenable_ssl: true", false},
+		{"require off", "domain: a.b\nenable_ssl: false", true},
+
+		{"conflicts one set", "domain: a.b\nproxy_pass: http://app", true},
+		{"conflicts both set", "domain: a.b\nproxy_pass: http://app\nroot: /srv", false},
+		{"conflicts one empty", "domain: a.b\nproxy_pass: http://app\nroot: ''", true},
+
+		{"when active and set", "domain: a.b\nenable_cache: true\ncache_ttl: 60", true},
+		{"when active and missing", "domain: a.b\nenable_cache: true", false},
+		{"when active and invalid", "domain: a.b\nenable_cache: true\ncache_ttl: soon", false},
+		{"when inactive ignores value", "domain: a.b\ncache_ttl: soon", true},
+
+		{"items valid", "domain: a.b\nupstreams:\n  - host: app1\n    weight: 2\n  - host: app2", true},
+		{"items missing field", "domain: a.b\nupstreams:\n  - weight: 2", false},
+		{"items below min", "domain: a.b\nupstreams:\n  - host: app1\n    weight: 0", false},
+		{"items wrong type", "domain: a.b\nupstreams:\n  - host: app1\n    weight: heavy", false},
+		{"items not a list", "domain: a.b\nupstreams: {host: app1}", false},
+
+		{"secret reference", "domain: a.b\ntoken: env:API_TOKEN", true},
+		{"secret literal", "domain: a.b\ntoken: hunter2", false},
+
+		{"raw allows directives", "domain: a.b\nsnippet: 'add_header X-Frame-Options DENY;'", true},
+		{"string with spaces", "domain: a.b\nproxy_pass: 'http://app keepalive'", true},
+		{"injection semicolon", "domain: a.b\nproxy_pass: 'http://app; return 200'", false},
+		{"injection brace", "domain: a.b\nroot: '/srv } server {'", false},
+		{"injection open quote", "domain: a.b\nroot: '\"/srv'", false},
+		{"injection comment", "domain: a.b\nproxy_pass: 'http://app #'", false},
+		{"injection line break", "domain: a.b\nroot: \"/srv\\ninclude /etc/passwd\"", false},
+		{"injection trailing backslash", "domain: a.b\nroot: '/srv\\'", false},
+		{"hash inside a word", "domain: a.b\nroot: '/srv/a#b'", true},
+		{"injection in nested item", "domain: a.b\nupstreams:\n  - host: 'app; deny all'", false},
+
+		{"undeclared scalar", "domain: a.b\nextra: 1", true},
+		{"undeclared injection", "domain: a.b\nextra: 'x; include /etc/passwd'", false},
+		{"undeclared list", "domain: a.b\nextra: [1, 2]", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := make(map[string]interface{})
+			if err := yaml.Unmarshal([]byte(tt.values), &doc); err != nil {
+				t.Fatalf("parsing values: %v", err)
+			}
+			for key, value := range doc {
+				if doc[key], err = normalizeValue(value); err != nil {
+					t.Fatalf("normalizing %s: %v", key, err)
+				}
+			}
+
+			goErr := validateDocument(m, doc)
+
+			var instance interface{}
+			data, _ := json.Marshal(doc)
+			if err := json.Unmarshal(data, &instance); err != nil {
+				t.Fatalf("encoding values: %v", err)
+			}
+			schemaErr := schema.Validate(instance)
+
+			if (goErr == nil) != (schemaErr == nil) {
+				t.Fatalf("validator and schema disagree:\nValidateParameters: %v\nJSON Schema: %v", goErr, schemaErr)
+			}
+			if (goErr == nil) != tt.valid {
+				t.Errorf("valid = %v, want %v (error: %v)", goErr == nil, tt.valid, goErr)
+			}
+		})
+	}
+}
+
+// validateDocument checks a values document as 'ngcli validate-values'
+// does.
+func validateDocument(m *TemplateMetadata, doc map[string]interface{}) error {
+	params, err := m.ParseValues(doc)
+	if err != nil {
+		return err
+	}
+	return m.ValidateParameters(m.ApplyDefaults(params))
+}
+
+func TestJSONSchemaOfSecretsOnlyAcceptsReferences(t *testing.T) {
+	tmpl, err := ParseTemplate("s", "s.conf.tpl", "# Template: s\n# @param token secret required \"Token\" minlen=20\n{{.token}}\n")
+	if err != nil {
+		t.Fatalf("ParseTemplate: %v", err)
+	}
+	param, _ := tmpl.Metadata.Parameter("token")
+	property := param.propertySchema()
+	if property["pattern"] != secretReferencePattern {
+		t.Errorf("pattern = %v, want %s", property["pattern"], secretReferencePattern)
+	}
+	// Constraints apply to the resolved secret, not to the reference
+	if _, ok := property["minLength"]; ok || strings.Contains(property["pattern"].(string), "20") {
+		t.Errorf("secret schema constrains the reference: %v", property)
+	}
+}
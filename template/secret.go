@@ -0,0 +1,281 @@
+package template
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// SecretMask replaces secret values in previews, diffs and logs.
+const SecretMask = "********"
+
+// secretReferencePattern is the form of a secret value outside the secret
+// itself: env:VAR, file:/path or store:key. It is also the JSON Schema
+// pattern of secret parameters.
+const secretReferencePattern = `^(env|file|store):\S`
+
+var secretReferenceRegex = regexp.MustCompile(secretReferencePattern)
+
+// LiteralSecretMessage is the problem reported for a secret given as its
+// value instead of a reference, wherever it is given.
+const LiteralSecretMessage = "secrets cannot be given as literal values; use env:VAR, file:/path or store:key"
+
+// IsSecretReference reports whether value names where a secret is kept
+// rather than being the secret itself.
+func IsSecretReference(value string) bool {
+	return secretReferenceRegex.MatchString(value)
+}
+
+// SecretResolver looks up secret references. Store references read a YAML
+// or JSON file encrypted with sops, or with age when the file name ends in
+// ".age"; the store is decrypted once, on first use.
+type SecretResolver struct {
+	StorePath string
+	Identity  string
+	// Env, if not nil, holds the variables env: references read instead
+	// of the environment
+	Env map[string]string
+
+	store map[string]interface{}
+}
+
+// DefaultSecretStore returns the store used when none is configured.
+func DefaultSecretStore() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".ngcli", "secrets.yaml")
+}
+
+// Resolve returns the secret a reference points to. File contents lose
+// their trailing newline.
+func (r *SecretResolver) Resolve(ref string) (string, error) {
+	kind, key, _ := strings.Cut(ref, ":")
+	key = strings.TrimSpace(key)
+
+	switch kind {
+	case "env":
+		value, ok := os.LookupEnv(key)
+		if r.Env != nil {
+			value, ok = r.Env[key]
+		}
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", key)
+		}
+		return value, nil
+	case "file":
+		data, err := os.ReadFile(key)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case "store":
+		return r.lookup(key)
+	}
+
+	return "", fmt.Errorf("not a secret reference (use env:VAR, file:/path or store:key)")
+}
+
+// lookup finds a key in the store; dots separate nested mappings, as in
+// "basic_auth.admin".
+func (r *SecretResolver) lookup(key string) (string, error) {
+	if r.store == nil {
+		store, err := r.decrypt()
+		if err != nil {
+			return "", err
+		}
+		r.store = store
+	}
+
+	var value interface{} = r.store
+	for _, part := range strings.Split(key, ".") {
+		mapping, ok := value.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("secret %s not found in %s", key, r.storePath())
+		}
+		if value, ok = mapping[part]; !ok {
+			return "", fmt.Errorf("secret %s not found in %s", key, r.storePath())
+		}
+	}
+
+	text, ok := scalarString(value)
+	if !ok {
+		return "", fmt.Errorf("secret %s in %s is not a single value", key, r.storePath())
+	}
+	return text, nil
+}
+
+func (r *SecretResolver) storePath() string {
+	if r.StorePath != "" {
+		return r.StorePath
+	}
+	return DefaultSecretStore()
+}
+
+// decrypt runs sops or age on the store and parses the plaintext.
+func (r *SecretResolver) decrypt() (map[string]interface{}, error) {
+	path := r.storePath()
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("secret store not found: %w", err)
+	}
+
+	var cmd *exec.Cmd
+	if strings.HasSuffix(path, ".age") {
+		args := []string{"--decrypt"}
+		if r.Identity != "" {
+			args = append(args, "--identity", r.Identity)
+		}
+		cmd = exec.Command("age", append(args, path)...)
+	} else {
+		cmd = exec.Command("sops", "--decrypt", path)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	plaintext, err := cmd.Output()
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("%s is required to decrypt the secret store %s", cmd.Args[0], path)
+		}
+		return nil, fmt.Errorf("failed to decrypt secret store %s: %s", path, strings.TrimSpace(stderr.String()))
+	}
+
+	store := make(map[string]interface{})
+	if err := yaml.Unmarshal(plaintext, &store); err != nil {
+		return nil, fmt.Errorf("failed to parse secret store %s: %w", path, err)
+	}
+	for key, value := range store {
+		normalized, err := normalizeValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret %s in %s: %w", key, path, err)
+		}
+		store[key] = normalized
+	}
+
+	return store, nil
+}
+
+// ResolveSecrets returns a copy of params in which every secret parameter,
+// given or defaulted, holds the secret its reference points to. Secrets
+// given as literal values are rejected so they stay out of shell history
+// and values files. Problems are reported as a *ValidationError.
+func (m *TemplateMetadata) ResolveSecrets(params map[string]string, resolver *SecretResolver) (map[string]string, error) {
+	resolved := make(map[string]string, len(params))
+	for key, value := range params {
+		resolved[key] = value
+	}
+
+	withDefaults := m.ApplyDefaults(params)
+
+	verr := &ValidationError{}
+	for _, param := range m.Parameters {
+		if param.Type != "secret" {
+			continue
+		}
+
+		value, exists := withDefaults[param.Name]
+		if !exists {
+			continue
+		}
+
+		if !IsSecretReference(value) {
+			verr.add(param.Name, "%s", LiteralSecretMessage)
+			continue
+		}
+
+		secret, err := resolver.Resolve(value)
+		if err != nil {
+			verr.add(param.Name, "%v", err)
+			continue
+		}
+		resolved[param.Name] = secret
+	}
+
+	if len(verr.Errors) > 0 {
+		return nil, verr
+	}
+
+	return resolved, nil
+}
+
+// SecretValues returns the values of secret and sensitive parameters in
+// params, for MaskSecrets.
+func (m *TemplateMetadata) SecretValues(params map[string]string) []string {
+	if m == nil {
+		return nil
+	}
+
+	var secrets []string
+	for _, param := range m.Parameters {
+		if !param.Sensitive {
+			continue
+		}
+		if value := params[param.Name]; value != "" {
+			secrets = append(secrets, value)
+		}
+	}
+	return secrets
+}
+
+// MaskSecrets replaces every occurrence of the given secrets in text with
+// SecretMask. The lines of multi-line secrets are masked separately, since
+// templates may indent them. Secrets shorter than four characters are only
+// masked where they stand alone, not inside longer words.
+func MaskSecrets(text string, secrets []string) string {
+	var values []string
+	for _, secret := range secrets {
+		values = append(values, secret)
+		if strings.Contains(secret, "\n") {
+			for _, line := range strings.Split(secret, "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					values = append(values, line)
+				}
+			}
+		}
+	}
+
+	// Longer secrets first, so a secret containing another is masked whole
+	sort.SliceStable(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	for _, value := range values {
+		switch {
+		case value == "":
+		case len(value) < 4:
+			text = maskWord(text, value)
+		default:
+			text = strings.ReplaceAll(text, value, SecretMask)
+		}
+	}
+	return text
+}
+
+// maskWord masks occurrences of value that are not part of a longer word.
+func maskWord(text, value string) string {
+	isWord := func(b byte) bool {
+		return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+	}
+
+	var masked strings.Builder
+	for {
+		i := strings.Index(text, value)
+		if i < 0 {
+			break
+		}
+		end := i + len(value)
+		standalone := (i == 0 || !isWord(text[i-1])) && (end == len(text) || !isWord(text[end]))
+		masked.WriteString(text[:i])
+		if standalone {
+			masked.WriteString(SecretMask)
+		} else {
+			masked.WriteString(value)
+		}
+		text = text[end:]
+	}
+	masked.WriteString(text)
+	return masked.String()
+}
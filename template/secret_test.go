@@ -0,0 +1,113 @@
+package template
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMaskSecrets(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		secrets []string
+		want    string
+	}{
+		{
+			name:    "every occurrence",
+			text:    `auth "s3cret"; # s3cret`,
+			secrets: []string{"s3cret"},
+			want:    `auth "********"; # ********`,
+		},
+		{
+			name:    "secret containing another",
+			text:    "token abcd-efgh and abcd",
+			secrets: []string{"abcd", "abcd-efgh"},
+			want:    "token ******** and ********",
+		},
+		{
+			name:    "multi-line secret indented by the template",
+			text:    "    -----BEGIN KEY-----\n    MIIEvQIBADANBg\n    -----END KEY-----",
+			secrets: []string{"-----BEGIN KEY-----\nMIIEvQIBADANBg\n-----END KEY-----"},
+			want:    "    ********\n    ********\n    ********",
+		},
+		{
+			name:    "short secret inside a word",
+			text:    "proxy_pass http://abc; location /abcdef { }",
+			secrets: []string{"abc"},
+			want:    "proxy_pass http://********; location /abcdef { }",
+		},
+		{
+			name:    "empty secret",
+			text:    "server {}",
+			secrets: []string{""},
+			want:    "server {}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MaskSecrets(tt.text, tt.secrets); got != tt.want {
+				t.Errorf("MaskSecrets = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecretValues(t *testing.T) {
+	tmpl, err := ParseTemplate("secret", "secret.conf.tpl", secretTemplate)
+	if err != nil {
+		t.Fatalf("ParseTemplate: %v", err)
+	}
+	secrets := tmpl.Metadata.SecretValues(map[string]string{"domain": "example.com", "api_token": "t0ken"})
+	if len(secrets) != 1 || secrets[0] != "t0ken" {
+		t.Errorf("SecretValues = %q, want [t0ken]", secrets)
+	}
+}
+
+// authTemplate writes its secret into an output without a declared
+// mode and its domain into one with a declared mode.
+const authTemplate = `{{/*
+# Template: auth
+# Version: 1.0
+# @param domain string required "Domain"
+# @param password secret required "Password"
+# @output htpasswd "/etc/nginx/{{.domain}}.htpasswd"
+# @output hosts "/etc/nginx/{{.domain}}.hosts" mode=0644
+*/ -}}
+server { server_name "{{.domain}}"; auth_basic_user_file /etc/nginx/{{.domain}}.htpasswd; }
+{{define "htpasswd"}}admin:{{.password}}{{end}}
+{{define "hosts"}}{{.domain}}{{end}}
+`
+
+func TestOutputMode(t *testing.T) {
+	tmpl, err := ParseTemplate("auth", "auth.conf.tpl", authTemplate)
+	if err != nil {
+		t.Fatalf("ParseTemplate: %v", err)
+	}
+	params := map[string]string{"domain": "example.com", "password": "pa55word"}
+	secrets := tmpl.Metadata.SecretValues(params)
+
+	content, err := tmpl.RenderWithValidation(params)
+	if err != nil {
+		t.Fatalf("RenderWithValidation: %v", err)
+	}
+	if mode := OutputMode(0, content, secrets); mode != DefaultOutputMode {
+		t.Errorf("mode of the configuration = %04o, want %04o", mode, DefaultOutputMode)
+	}
+
+	files, err := tmpl.RenderOutputs(params)
+	if err != nil {
+		t.Fatalf("RenderOutputs: %v", err)
+	}
+	want := map[string]os.FileMode{"htpasswd": 0600, "hosts": 0644}
+	for _, file := range files {
+		if mode := OutputMode(file.Mode, file.Content, secrets); mode != want[file.Name] {
+			t.Errorf("mode of %s = %04o, want %04o", file.Name, mode, want[file.Name])
+		}
+	}
+
+	// A declared mode is kept even for content with secrets
+	if mode := OutputMode(0640, "admin:pa55word", secrets); mode != 0640 {
+		t.Errorf("declared mode = %04o, want 0640", mode)
+	}
+}
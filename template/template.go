@@ -4,10 +4,32 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"text/template"
+
+	"github.com/vourteen14/ngcli/errcode"
 )
 
+// templateFuncs are available to templates besides the text/template
+// builtins.
+var templateFuncs = template.FuncMap{
+	// quote makes a value a single nginx argument, whatever it contains
+	"quote": nginxQuote,
+	// regexQuote escapes regular expression metacharacters, for values used
+	// in regex locations such as "location ~ ^/{{regexQuote .prefix}}/"
+	"regexQuote": regexQuote,
+}
+
+// nginxQuote returns value as a double-quoted nginx string.
+func nginxQuote(value interface{}) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(fmt.Sprint(value)) + `"`
+}
+
+func regexQuote(value interface{}) string {
+	return regexp.QuoteMeta(fmt.Sprint(value))
+}
+
 type Template struct {
 	Name     string
 	Path     string
@@ -25,7 +47,7 @@ func LoadTemplate(name, templateDir string) (*Template, error) {
 	templatePath := filepath.Join(templateDir, templateName)
 	
 	if _, err := os.Stat(templatePath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("template not found: %s", templatePath)
+		return nil, errcode.Errorf(errcode.TemplateNotFound, "template not found: %s", templatePath)
 	}
 	
 	content, err := os.ReadFile(templatePath)
@@ -33,37 +55,70 @@ func LoadTemplate(name, templateDir string) (*Template, error) {
 		return nil, fmt.Errorf("failed to read template %s: %w", templatePath, err)
 	}
 	
-	tmpl, err := template.New(name).Parse(string(content))
+	tmpl, err := ParseTemplate(name, templatePath, string(content))
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse template %s: %w", templatePath, err)
+		return nil, errcode.Wrap(errcode.TemplateInvalid, err)
 	}
 	
-	metadata, err := ParseTemplateMetadata(string(content))
+	return tmpl, nil
+}
+
+// ParseTemplate builds a Template from content. Partials are loaded from the
+// partials directory next to path.
+func ParseTemplate(name, path, content string) (*Template, error) {
+	// Metadata first: an unwrapped header with computed defaults gets a
+	// clearer error than the template parser would give
+	metadata, err := ParseTemplateMetadata(content)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse template metadata: %w", err)
 	}
 	
-	return &Template{
+	// Referencing a parameter that was never provided is an error rather
+	// than "<no value>" silently rendered into the nginx config
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", path, err)
+	}
+	
+	if err := parsePartials(tmpl, filepath.Join(filepath.Dir(path), partialsDir)); err != nil {
+		return nil, err
+	}
+	
+	t := &Template{
 		Name:     name,
-		Path:     templatePath,
-		Content:  string(content),
+		Path:     path,
+		Content:  content,
 		Template: tmpl,
 		Metadata: metadata,
-	}, nil
+	}
+	if err := t.checkOutputs(); err != nil {
+		return nil, fmt.Errorf("template %s: %w", path, err)
+	}
+	
+	return t, nil
 }
 
 func (t *Template) Render(params map[string]string) (string, error) {
 	var output strings.Builder
 	
-	if err := t.Template.Execute(&output, params); err != nil {
-		return "", fmt.Errorf("failed to render template %s: %w", t.Name, err)
+	var data interface{} = params
+	if t.Metadata != nil {
+		data = t.Metadata.TypedValues(params)
+	}
+	
+	// Execution fails on a key the metadata does not declare, among others
+	if err := t.Template.Execute(&output, data); err != nil {
+		return "", errcode.Errorf(errcode.TemplateInvalid, "failed to render template %s: %w", t.Name, err)
 	}
 	
 	return output.String(), nil
 }
 
 func (t *Template) RenderWithValidation(params map[string]string) (string, error) {
-	paramsWithDefaults := t.Metadata.ApplyDefaults(params)
+	paramsWithDefaults, err := t.Metadata.ComputeDefaults(params)
+	if err != nil {
+		return "", err
+	}
 	
 	if err := t.Metadata.ValidateParameters(paramsWithDefaults); err != nil {
 		return "", err
@@ -72,12 +127,60 @@ func (t *Template) RenderWithValidation(params map[string]string) (string, error
 	return t.Render(paramsWithDefaults)
 }
 
-func ListTemplates(templateDir string) ([]string, error) {
+// parsePartials makes every *.tpl file in dir available to tmpl as a named
+// template, so {{template "ssl" .}} includes partials/ssl.tpl.
+func parsePartials(tmpl *template.Template, dir string) error {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.tpl"))
+	if err != nil {
+		return err
+	}
+	
+	for _, match := range matches {
+		content, err := os.ReadFile(match)
+		if err != nil {
+			return fmt.Errorf("failed to read partial %s: %w", match, err)
+		}
+		
+		name := strings.TrimSuffix(filepath.Base(match), ".tpl")
+		if _, err := tmpl.New(name).Parse(string(content)); err != nil {
+			return fmt.Errorf("failed to parse partial %s: %w", match, err)
+		}
+	}
+	
+	return nil
+}
+
+// ListTemplates returns local templates followed by the templates of
+// installed packages, which are namespaced as <package>/<template>. The
+// warnings report installed packages that were skipped.
+func ListTemplates(templateDir string) ([]string, []string, error) {
+	templates, err := listTemplateFiles(templateDir, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	
+	packages, warnings, err := ListInstalledPackages(templateDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	
+	for _, pkg := range packages {
+		namespaced, err := listTemplateFiles(filepath.Join(templateDir, pkg.Name), pkg.Name+"/")
+		if err != nil {
+			return nil, nil, err
+		}
+		templates = append(templates, namespaced...)
+	}
+	
+	return templates, warnings, nil
+}
+
+func listTemplateFiles(dir, prefix string) ([]string, error) {
 	var templates []string
 	
-	entries, err := os.ReadDir(templateDir)
+	entries, err := os.ReadDir(dir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read template directory %s: %w", templateDir, err)
+		return nil, fmt.Errorf("failed to read template directory %s: %w", dir, err)
 	}
 	
 	for _, entry := range entries {
@@ -88,7 +191,7 @@ func ListTemplates(templateDir string) ([]string, error) {
 		name := entry.Name()
 		if strings.HasSuffix(name, ".conf.tpl") {
 			templateName := strings.TrimSuffix(name, ".conf.tpl")
-			templates = append(templates, templateName)
+			templates = append(templates, prefix+templateName)
 		}
 	}
 	
@@ -101,7 +204,7 @@ func ValidateTemplate(templatePath string) error {
 		return fmt.Errorf("failed to read template: %w", err)
 	}
 	
-	_, err = template.New("validate").Parse(string(content))
+	_, err = template.New("validate").Funcs(templateFuncs).Parse(string(content))
 	if err != nil {
 		return fmt.Errorf("template syntax error: %w", err)
 	}
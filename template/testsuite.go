@@ -0,0 +1,246 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vourteen14/ngcli/utils"
+	"gopkg.in/yaml.v2"
+)
+
+// TestSuite is the content of a <name>.tests.yaml file that sits next to
+// <name>.conf.tpl.
+type TestSuite struct {
+	Template string     `yaml:"-"`
+	Path     string     `yaml:"-"`
+	Cases    []TestCase `yaml:"cases"`
+}
+
+// TestCase renders the template with Params and checks the result. Golden is
+// a file path relative to the test file; ExpectErrors lists substrings that
+// must appear in the validation error; Directives are nginx directives that
+// must appear on a line of their own (whitespace and the trailing ";" are
+// not significant). Secret parameters are resolved as by generate, with
+// env: references read from Env rather than the environment and file:
+// paths relative to the test file.
+type TestCase struct {
+	Name         string            `yaml:"name"`
+	Params       map[string]string `yaml:"params"`
+	Env          map[string]string `yaml:"env"`
+	Golden       string            `yaml:"golden"`
+	ExpectErrors []string          `yaml:"expect_errors"`
+	Contains     []string          `yaml:"contains"`
+	NotContains  []string          `yaml:"not_contains"`
+	Directives   []string          `yaml:"directives"`
+	NginxTest    bool              `yaml:"nginx_test"`
+}
+
+// TestOptions control how a suite is run.
+type TestOptions struct {
+	// Update rewrites golden files with the rendered output instead of comparing.
+	Update bool
+	// NginxTest runs NginxCheck for every case, not only cases that ask for it.
+	NginxTest bool
+	// NginxCheck validates rendered output, typically with an isolated nginx -t.
+	// A nil check skips nginx validation.
+	NginxCheck func(content string) error
+	// NginxSkipped reports whether an error from NginxCheck means the check
+	// could not run (for example nginx is not installed).
+	NginxSkipped func(err error) bool
+}
+
+// TestResult is the outcome of a single test case.
+type TestResult struct {
+	Suite    string
+	Case     string
+	Failures []string
+	Skipped  string
+	Duration time.Duration
+}
+
+func (r TestResult) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// TestSuitePath returns the path of the test file for a template.
+func TestSuitePath(name, templateDir string) string {
+	return filepath.Join(templateDir, strings.TrimSuffix(name, ".conf.tpl")+".tests.yaml")
+}
+
+// LoadTestSuite reads the test file of a template.
+func LoadTestSuite(name, templateDir string) (*TestSuite, error) {
+	path := TestSuitePath(name, templateDir)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test suite %s: %w", path, err)
+	}
+
+	var suite TestSuite
+	if err := yaml.UnmarshalStrict(data, &suite); err != nil {
+		return nil, fmt.Errorf("failed to parse test suite %s: %w", path, err)
+	}
+
+	suite.Template = name
+	suite.Path = path
+
+	for i := range suite.Cases {
+		if suite.Cases[i].Name == "" {
+			suite.Cases[i].Name = fmt.Sprintf("case-%d", i+1)
+		}
+	}
+
+	return &suite, nil
+}
+
+// Run executes every case of the suite against tmpl.
+func (s *TestSuite) Run(tmpl *Template, opts TestOptions) []TestResult {
+	results := make([]TestResult, 0, len(s.Cases))
+
+	for _, tc := range s.Cases {
+		start := time.Now()
+		result := s.runCase(tmpl, tc, opts)
+		result.Duration = time.Since(start)
+		results = append(results, result)
+	}
+
+	return results
+}
+
+func (s *TestSuite) runCase(tmpl *Template, tc TestCase, opts TestOptions) TestResult {
+	result := TestResult{Suite: s.Template, Case: tc.Name}
+	fail := func(format string, args ...interface{}) {
+		result.Failures = append(result.Failures, fmt.Sprintf(format, args...))
+	}
+
+	params := tc.Params
+	if params == nil {
+		params = map[string]string{}
+	}
+
+	params, err := s.resolveSecrets(tmpl, tc, params)
+	var content string
+	if err == nil {
+		content, err = tmpl.RenderWithValidation(params)
+	}
+
+	if len(tc.ExpectErrors) > 0 {
+		if err == nil {
+			fail("expected validation errors %v, but rendering succeeded", tc.ExpectErrors)
+			return result
+		}
+		for _, expected := range tc.ExpectErrors {
+			if !strings.Contains(err.Error(), expected) {
+				fail("expected error containing %q, got: %v", expected, err)
+			}
+		}
+		return result
+	}
+
+	if err != nil {
+		fail("%v", err)
+		return result
+	}
+
+	if tc.Golden != "" {
+		goldenPath := tc.Golden
+		if !filepath.IsAbs(goldenPath) {
+			goldenPath = filepath.Join(filepath.Dir(s.Path), goldenPath)
+		}
+
+		if opts.Update {
+			if err := os.MkdirAll(filepath.Dir(goldenPath), 0755); err != nil {
+				fail("failed to create golden directory: %v", err)
+			} else if err := os.WriteFile(goldenPath, []byte(content), 0644); err != nil {
+				fail("failed to update golden file: %v", err)
+			}
+		} else {
+			expected, err := os.ReadFile(goldenPath)
+			if err != nil {
+				fail("failed to read golden file (run with --update to create it): %v", err)
+			} else if diff := utils.UnifiedDiff(string(expected), content, tc.Golden, "rendered"); diff != "" {
+				fail("output differs from golden file:\n%s", MaskSecrets(diff, tmpl.Metadata.SecretValues(params)))
+			}
+		}
+	}
+
+	for _, expected := range tc.Contains {
+		if !strings.Contains(content, expected) {
+			fail("output does not contain %q", expected)
+		}
+	}
+
+	for _, unexpected := range tc.NotContains {
+		if strings.Contains(content, unexpected) {
+			fail("output unexpectedly contains %q", unexpected)
+		}
+	}
+
+	for _, directive := range tc.Directives {
+		if !containsDirective(content, directive) {
+			fail("output does not contain directive %q", directive)
+		}
+	}
+
+	if (tc.NginxTest || opts.NginxTest) && opts.NginxCheck != nil {
+		if err := opts.NginxCheck(content); err != nil {
+			if opts.NginxSkipped != nil && opts.NginxSkipped(err) {
+				result.Skipped = err.Error()
+			} else {
+				fail("%v", err)
+			}
+		}
+	}
+
+	return result
+}
+
+// resolveSecrets resolves the secret references of a case. Store
+// references are refused: a test would depend on a store and its keys.
+func (s *TestSuite) resolveSecrets(tmpl *Template, tc TestCase, params map[string]string) (map[string]string, error) {
+	m := tmpl.Metadata
+	env := tc.Env
+	if env == nil {
+		env = map[string]string{}
+	}
+
+	refs := make(map[string]string, len(params))
+	for key, value := range params {
+		refs[key] = value
+	}
+	withDefaults := m.ApplyDefaults(params)
+	for _, param := range m.Parameters {
+		value, exists := withDefaults[param.Name]
+		if param.Type != "secret" || !exists {
+			continue
+		}
+		kind, path, _ := strings.Cut(value, ":")
+		switch {
+		case kind == "store":
+			return nil, fmt.Errorf("%s: store: references cannot be used in tests; use env:VAR and set VAR under env", param.Name)
+		case kind == "file" && !filepath.IsAbs(path):
+			refs[param.Name] = "file:" + filepath.Join(filepath.Dir(s.Path), path)
+		}
+	}
+
+	return m.ResolveSecrets(refs, &SecretResolver{Env: env})
+}
+
+func containsDirective(content, directive string) bool {
+	want := normalizeDirective(directive)
+	for _, line := range strings.Split(content, "\n") {
+		if normalizeDirective(line) == want {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeDirective(line string) string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimSuffix(line, ";")
+	return strings.Join(strings.Fields(line), " ")
+}
@@ -0,0 +1,119 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const secretTemplate = `# Template: secret
+# Version: 1.0
+# @param domain string required "Domain"
+# @param api_token secret required "API token"
+server {
+    server_name "{{.domain}}";
+    proxy_set_header Authorization "Bearer {{.api_token}}";
+}
+`
+
+const secretSuite = `cases:
+  - name: env reference
+    params: {domain: example.com, api_token: "env:API_TOKEN"}
+    env: {API_TOKEN: test-token}
+    golden: testdata/secret.conf
+    directives: ['proxy_set_header Authorization "Bearer test-token"']
+    not_contains: ["env:"]
+  - name: file reference
+    params: {domain: example.com, api_token: "file:testdata/token"}
+    contains: ["Bearer file-token"]
+  - name: unset variable
+    params: {domain: example.com, api_token: "env:API_TOKEN"}
+    expect_errors: ["environment variable API_TOKEN is not set"]
+  - name: literal secret
+    params: {domain: example.com, api_token: hunter2}
+    expect_errors: ["literal"]
+  - name: store reference
+    params: {domain: example.com, api_token: "store:api.token"}
+    expect_errors: ["store: references cannot be used in tests"]
+`
+
+func TestSuiteResolvesSecrets(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"secret.conf.tpl":   secretTemplate,
+		"secret.tests.yaml": secretSuite,
+		"testdata/token":    "file-token\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The case env, not the environment, is used
+	t.Setenv("API_TOKEN", "from-the-environment")
+
+	tmpl, err := LoadTemplate("secret", dir)
+	if err != nil {
+		t.Fatalf("LoadTemplate: %v", err)
+	}
+	suite, err := LoadTestSuite("secret", dir)
+	if err != nil {
+		t.Fatalf("LoadTestSuite: %v", err)
+	}
+
+	for _, update := range []bool{true, false} {
+		for _, result := range suite.Run(tmpl, TestOptions{Update: update}) {
+			if !result.Passed() {
+				t.Errorf("update=%v, case %s: %s", update, result.Case, strings.Join(result.Failures, "; "))
+			}
+		}
+	}
+
+	golden, err := os.ReadFile(filepath.Join(dir, "testdata", "secret.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(golden), `"Bearer test-token"`) {
+		t.Errorf("golden file holds the reference, not the secret:\n%s", golden)
+	}
+}
+
+func TestSuiteMasksSecretsInGoldenDiffs(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"secret.conf.tpl":   secretTemplate,
+		"secret.tests.yaml": "cases:\n  - params: {domain: example.com, api_token: \"env:API_TOKEN\"}\n    env: {API_TOKEN: s3cret-value}\n    golden: golden.conf\n",
+		"golden.conf":       "server {}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tmpl, err := LoadTemplate("secret", dir)
+	if err != nil {
+		t.Fatalf("LoadTemplate: %v", err)
+	}
+	suite, err := LoadTestSuite("secret", dir)
+	if err != nil {
+		t.Fatalf("LoadTestSuite: %v", err)
+	}
+
+	results := suite.Run(tmpl, TestOptions{})
+	if len(results) != 1 || results[0].Passed() {
+		t.Fatalf("results = %+v, want one failure", results)
+	}
+	failure := strings.Join(results[0].Failures, "\n")
+	if strings.Contains(failure, "s3cret-value") || !strings.Contains(failure, SecretMask) {
+		t.Errorf("golden diff does not mask the secret:\n%s", failure)
+	}
+}
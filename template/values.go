@@ -0,0 +1,388 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vourteen14/ngcli/errcode"
+	"gopkg.in/yaml.v2"
+)
+
+// LoadValuesFile reads a YAML or JSON values file: a mapping of parameter
+// names to values.
+func LoadValuesFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read values file: %w", err)
+	}
+
+	doc := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, errcode.Errorf(errcode.InvalidValues, "failed to parse values file %s: %w", path, err)
+	}
+
+	for key, value := range doc {
+		normalized, err := normalizeValue(value)
+		if err != nil {
+			return nil, errcode.Errorf(errcode.InvalidValues, "invalid value for %s in %s: %w", key, path, err)
+		}
+		doc[key] = normalized
+	}
+
+	return doc, nil
+}
+
+// normalizeValue converts YAML mappings, which decode with interface{} keys,
+// into JSON-compatible map[string]interface{} values.
+func normalizeValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			name, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("mapping keys must be strings, got %v", key)
+			}
+			normalized, err := normalizeValue(item)
+			if err != nil {
+				return nil, err
+			}
+			m[name] = normalized
+		}
+		return m, nil
+	case map[string]interface{}:
+		for key, item := range v {
+			normalized, err := normalizeValue(item)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = normalized
+		}
+		return v, nil
+	case []interface{}:
+		for i, item := range v {
+			normalized, err := normalizeValue(item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = normalized
+		}
+		return v, nil
+	}
+	return value, nil
+}
+
+// scalarString formats a decoded scalar the way it is given with --set.
+func scalarString(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+// encodeValue returns the --set form of a decoded value: scalars as text,
+// lists and mappings as JSON.
+func encodeValue(value interface{}) (string, error) {
+	if text, ok := scalarString(value); ok {
+		return text, nil
+	}
+
+	normalized, err := normalizeValue(value)
+	if err != nil {
+		return "", err
+	}
+	switch normalized.(type) {
+	case []interface{}, map[string]interface{}:
+	default:
+		return "", fmt.Errorf("unsupported value %v", value)
+	}
+
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// decodeComposite parses the --set form of an array or object value. Arrays
+// are a JSON list or comma-separated text; typed reports a JSON list, whose
+// items keep their JSON types.
+func (p ParameterInfo) decodeComposite(value string) (interface{}, bool, error) {
+	trimmed := strings.TrimSpace(value)
+
+	if p.jsonType() == "object" {
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(trimmed), &object); err != nil || object == nil {
+			return nil, true, fmt.Errorf("must be a JSON object")
+		}
+		return object, true, nil
+	}
+
+	if strings.HasPrefix(trimmed, "[") {
+		var list []interface{}
+		if err := json.Unmarshal([]byte(trimmed), &list); err != nil {
+			return nil, true, fmt.Errorf("must be a JSON list or comma-separated values")
+		}
+		return list, true, nil
+	}
+
+	list := []interface{}{}
+	if trimmed != "" {
+		for _, item := range strings.Split(trimmed, ",") {
+			list = append(list, strings.TrimSpace(item))
+		}
+	}
+	return list, false, nil
+}
+
+// checkValue validates a decoded value. Strict values come from JSON and
+// must already have the JSON type of the parameter; other strings are
+// parsed as with --set.
+func (p ParameterInfo) checkValue(value interface{}, strict bool) []string {
+	if text, ok := value.(string); ok && (!strict || p.jsonType() == "string") {
+		return p.checkString(text)
+	}
+	if !p.matchesJSONType(value) {
+		return []string{fmt.Sprintf("must be of type %s, got %s", p.jsonType(), describeJSONValue(value))}
+	}
+
+	text, err := encodeValue(value)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	return p.checkString(text)
+}
+
+func (p ParameterInfo) checkString(value string) []string {
+	var problems []string
+	for _, constraint := range p.valueConstraints() {
+		if constraint.check == nil {
+			continue
+		}
+		problems = append(problems, constraint.check(value)...)
+	}
+	return problems
+}
+
+// templateValue converts the --set form of a value into template data:
+// booleans become bool, arrays and objects with declared items/properties
+// become lists and maps, everything else stays a string.
+func (p ParameterInfo) templateValue(value string) interface{} {
+	switch p.jsonType() {
+	case "boolean":
+		return value == "true"
+	case "array":
+		decoded, _, err := p.decodeComposite(value)
+		if err != nil {
+			return []interface{}{}
+		}
+		var items []interface{}
+		for _, item := range decoded.([]interface{}) {
+			items = append(items, p.Items.templateData(item))
+		}
+		if items == nil {
+			items = []interface{}{}
+		}
+		return items
+	case "object":
+		object := map[string]interface{}{}
+		if decoded, _, err := p.decodeComposite(value); err == nil {
+			object = decoded.(map[string]interface{})
+		}
+		fields := make(map[string]interface{}, len(p.Properties))
+		for key, field := range object {
+			fields[key] = field
+		}
+		// Every declared property is present, as for top-level parameters
+		for _, prop := range p.Properties {
+			if field, exists := object[prop.Name]; exists {
+				fields[prop.Name] = prop.templateData(field)
+			} else {
+				fields[prop.Name] = prop.templateValue(prop.Default)
+			}
+		}
+		return fields
+	}
+	return value
+}
+
+func (p ParameterInfo) templateData(value interface{}) interface{} {
+	text, err := encodeValue(value)
+	if err != nil {
+		return value
+	}
+	return p.templateValue(text)
+}
+
+// matchesJSONType reports whether a decoded values-file value has the JSON
+// type of the parameter.
+func (p ParameterInfo) matchesJSONType(value interface{}) bool {
+	switch value.(type) {
+	case string:
+		return p.jsonType() == "string"
+	case bool:
+		return p.jsonType() == "boolean"
+	case int, int64, uint64:
+		return p.jsonType() == "integer" || p.jsonType() == "number"
+	case float64:
+		if p.jsonType() == "integer" {
+			return value.(float64) == float64(int64(value.(float64)))
+		}
+		return p.jsonType() == "number"
+	case []interface{}:
+		return p.jsonType() == "array"
+	case map[string]interface{}:
+		return p.jsonType() == "object"
+	}
+	return false
+}
+
+// ParseValues converts a decoded values file (YAML or JSON) into parameter
+// values. Values must have the JSON type of their parameter, exactly as the
+// schema from JSONSchema requires; "8080" is not an integer. Lists and
+// mappings are only accepted for array and object parameters, and secrets
+// must be references. The result
+// still has to pass ValidateParameters.
+func (m *TemplateMetadata) ParseValues(doc map[string]interface{}) (map[string]string, error) {
+	params := make(map[string]string, len(doc))
+	verr := &ValidationError{}
+
+	for name, value := range doc {
+		if text, ok := scalarString(value); ok {
+			params[name] = text
+			continue
+		}
+
+		param, declared := m.Parameter(name)
+		if _, composite := value.([]interface{}); !composite {
+			_, composite = value.(map[string]interface{})
+			if !composite {
+				verr.add(name, "must be a single value, not %s", describeJSONValue(value))
+				continue
+			}
+		}
+		if !declared || (param.jsonType() != "array" && param.jsonType() != "object") {
+			verr.add(name, "must be a single value, not %s", describeJSONValue(value))
+			continue
+		}
+
+		text, err := encodeValue(value)
+		if err != nil {
+			verr.add(name, "%v", err)
+			continue
+		}
+		params[name] = text
+	}
+
+	withDefaults := m.ApplyDefaults(params)
+	for _, param := range m.Parameters {
+		value, exists := doc[param.Name]
+		if !exists || !m.IsActive(param, withDefaults) {
+			continue
+		}
+		if _, converted := params[param.Name]; converted && !param.matchesJSONType(value) {
+			verr.add(param.Name, "must be of type %s, got %s", param.jsonType(), describeJSONValue(value))
+		} else if param.Type == "secret" && !IsSecretReference(params[param.Name]) {
+			verr.add(param.Name, "%s", LiteralSecretMessage)
+		}
+	}
+
+	if len(verr.Errors) > 0 {
+		sort.SliceStable(verr.Errors, func(i, j int) bool { return verr.Errors[i].Parameter < verr.Errors[j].Parameter })
+		return params, verr
+	}
+
+	return params, nil
+}
+
+func describeJSONValue(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int64, uint64, float64:
+		return "number"
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
+
+// ValuesDocument converts parameter values into a values file document,
+// the inverse of ParseValues: declared parameters come first, in
+// declaration order, with the JSON type of their parameter. Values that do
+// not convert stay strings, for ParseValues to report.
+func (m *TemplateMetadata) ValuesDocument(params map[string]string) yaml.MapSlice {
+	var doc yaml.MapSlice
+	for _, param := range m.Parameters {
+		if value, exists := params[param.Name]; exists {
+			doc = append(doc, yaml.MapItem{Key: param.Name, Value: param.jsonValue(value)})
+		}
+	}
+
+	var others []string
+	for name := range params {
+		if _, declared := m.Parameter(name); !declared {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+	for _, name := range others {
+		doc = append(doc, yaml.MapItem{Key: name, Value: params[name]})
+	}
+	return doc
+}
+
+// jsonValue converts the --set form of a value to the JSON type of the
+// parameter, or returns it unchanged if it does not convert.
+func (p ParameterInfo) jsonValue(value string) interface{} {
+	switch p.jsonType() {
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	case "integer":
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	case "array":
+		decoded, _, err := p.decodeComposite(value)
+		if err != nil {
+			break
+		}
+		items := decoded.([]interface{})
+		for i, item := range items {
+			if text, ok := item.(string); ok && p.Items != nil {
+				items[i] = p.Items.jsonValue(text)
+			}
+		}
+		return items
+	case "object":
+		if decoded, _, err := p.decodeComposite(value); err == nil {
+			return decoded
+		}
+	}
+	return value
+}
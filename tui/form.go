@@ -0,0 +1,732 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/vourteen14/ngcli/template"
+)
+
+// FormOptions configures EditParameters.
+type FormOptions struct {
+	// Title heads the form, e.g. "Generate shop.conf".
+	Title string
+	// Confirm returns the question asked before the values are accepted,
+	// such as which files are about to be written. Without it, or when it
+	// returns "", the values are accepted without asking.
+	Confirm func(values map[string]string) string
+}
+
+// EditParameters shows a form for the parameters of tmpl, prefilled with
+// params and the declared defaults, next to a live preview of the rendered
+// configuration. Fields are checked against their declarations while
+// typing, and the values are only returned once they render. It returns
+// ErrCancelled if the user leaves instead.
+func EditParameters(tmpl *template.Template, params map[string]string, opts FormOptions) (map[string]string, error) {
+	t, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer t.close()
+
+	f := newForm(tmpl, params, opts)
+	for {
+		t.resize()
+		t.draw(f.view(t.width, t.height))
+
+		keys, err := t.readKeys()
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range keys {
+			if values, done, err := f.handle(k); done {
+				return values, err
+			}
+		}
+	}
+}
+
+// field is the input of one parameter.
+type field struct {
+	param  template.ParameterInfo
+	doc    template.ParameterDoc
+	value  []rune
+	cursor int
+	// touched is set once the value was edited; untouched fields only show
+	// their problems after a submit attempt
+	touched bool
+	// placeholder is the default the parameter takes while empty
+	placeholder string
+}
+
+func (fl *field) text() string {
+	return string(fl.value)
+}
+
+func (fl *field) set(value string) {
+	fl.value = []rune(value)
+	fl.cursor = len(fl.value)
+	fl.touched = true
+}
+
+// choices returns the values an options field cycles through; optional
+// parameters can also be left empty.
+func (fl *field) choices() []string {
+	if fl.param.Required {
+		return fl.param.Options
+	}
+	return append([]string{""}, fl.param.Options...)
+}
+
+// form is the state of EditParameters.
+type form struct {
+	tmpl *template.Template
+	opts FormOptions
+	// base holds the values that have no field, such as config_name
+	base   map[string]string
+	fields []*field
+	// focus is the focused field; nil is the submit button
+	focus *field
+
+	// dropdown is the highlighted choice while the option list of the
+	// focused field is open, or -1
+	dropdown int
+	// question is the confirmation being asked, if any
+	question string
+	// submitted is set by the first submit attempt
+	submitted bool
+	status    string
+	formTop   int
+	scroll    int
+
+	// Results of the last check
+	active   map[string]bool
+	problems map[string][]string
+	failure  string
+	preview  []string
+}
+
+func newForm(tmpl *template.Template, params map[string]string, opts FormOptions) *form {
+	f := &form{tmpl: tmpl, opts: opts, base: make(map[string]string), dropdown: -1}
+
+	m := tmpl.Metadata
+	docs := m.ParameterDocs()
+	for i, param := range m.Parameters {
+		fl := &field{param: param, doc: docs[i]}
+		if value, given := params[param.Name]; given {
+			fl.value = []rune(value)
+		} else if param.Default != "" && !param.IsComputed() {
+			fl.value = []rune(param.Default)
+		}
+		fl.cursor = len(fl.value)
+		f.fields = append(f.fields, fl)
+	}
+	for name, value := range params {
+		if _, declared := m.Parameter(name); !declared {
+			f.base[name] = value
+		}
+	}
+
+	f.check()
+	if visible := f.visible(); len(visible) > 0 {
+		f.focus = visible[0]
+	}
+	return f
+}
+
+// values returns the parameters as entered. Fields hidden by their @when
+// condition keep their text but are left out.
+func (f *form) values() map[string]string {
+	values := make(map[string]string, len(f.base)+len(f.fields))
+	for name, value := range f.base {
+		values[name] = value
+	}
+	for _, fl := range f.fields {
+		if len(fl.value) > 0 {
+			values[fl.param.Name] = fl.text()
+		}
+	}
+
+	m := f.tmpl.Metadata
+	withDefaults := m.ApplyDefaults(values)
+	for _, fl := range f.fields {
+		if !m.IsActive(fl.param, withDefaults) {
+			delete(values, fl.param.Name)
+		}
+	}
+	return values
+}
+
+// check validates and renders the current values.
+func (f *form) check() {
+	m := f.tmpl.Metadata
+	values := f.values()
+	withDefaults := m.ApplyDefaults(values)
+	secrets := m.SecretValues(values)
+
+	f.active = make(map[string]bool)
+	for _, fl := range f.fields {
+		f.active[fl.param.Name] = m.IsActive(fl.param, withDefaults)
+		fl.placeholder = ""
+		if len(fl.value) == 0 {
+			fl.placeholder = withDefaults[fl.param.Name]
+			if fl.param.Sensitive && fl.placeholder != "" {
+				fl.placeholder = template.SecretMask
+			}
+		}
+	}
+
+	f.problems = make(map[string][]string)
+	f.failure = ""
+	// Secrets are references in the form too; see ResolveSecrets
+	for _, fl := range f.fields {
+		value := values[fl.param.Name]
+		if fl.param.Type == "secret" && f.active[fl.param.Name] && value != "" && !template.IsSecretReference(value) {
+			f.problems[fl.param.Name] = append(f.problems[fl.param.Name], template.LiteralSecretMessage)
+		}
+	}
+	if len(f.problems) > 0 {
+		return
+	}
+	content, err := f.tmpl.RenderWithValidation(values)
+	var outputs []template.RenderedFile
+	if err == nil {
+		outputs, err = f.tmpl.RenderOutputs(values)
+	}
+
+	var verr *template.ValidationError
+	switch {
+	case errors.As(err, &verr):
+		for _, pe := range verr.Errors {
+			f.problems[pe.Parameter] = append(f.problems[pe.Parameter], pe.Message)
+		}
+		return
+	case err != nil:
+		f.failure = template.MaskSecrets(err.Error(), secrets)
+		return
+	}
+
+	text := content
+	for _, output := range outputs {
+		text += fmt.Sprintf("\n# ── output %s: %s ──\n%s", output.Name, output.Path, output.Content)
+	}
+	f.preview = strings.Split(strings.TrimRight(template.MaskSecrets(text, secrets), "\n"), "\n")
+}
+
+// visible returns the fields of active parameters. Deprecated parameters
+// only appear when they have a value.
+func (f *form) visible() []*field {
+	var fields []*field
+	for _, fl := range f.fields {
+		if !f.active[fl.param.Name] || (fl.param.Deprecated != "" && len(fl.value) == 0) {
+			continue
+		}
+		fields = append(fields, fl)
+	}
+	return fields
+}
+
+// move focuses the field delta positions away, the submit button being
+// the last position.
+func (f *form) move(delta int) {
+	visible := f.visible()
+	index := len(visible)
+	for i, fl := range visible {
+		if fl == f.focus {
+			index = i
+		}
+	}
+	index += delta
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(visible) {
+		f.focus = nil
+		return
+	}
+	f.focus = visible[index]
+}
+
+// handle applies a key; done is set once the values are accepted or the
+// form is left.
+func (f *form) handle(k key) (values map[string]string, done bool, err error) {
+	if k.kind == keyCtrlC {
+		return nil, true, ErrCancelled
+	}
+
+	if f.question != "" {
+		switch {
+		case k.kind == keyRune && (k.r == 'y' || k.r == 'Y'):
+			return f.values(), true, nil
+		case k.kind == keyRune && (k.r == 'n' || k.r == 'N'), k.kind == keyEsc:
+			f.question = ""
+			f.status = "Not written; keep editing or press Esc to leave"
+		}
+		return nil, false, nil
+	}
+
+	if f.dropdown >= 0 {
+		f.handleDropdown(k)
+		return nil, false, nil
+	}
+
+	f.status = ""
+	switch k.kind {
+	case keyEsc:
+		return nil, true, ErrCancelled
+	case keyCtrlS:
+		return f.submit()
+	case keyUp, keyBacktab:
+		f.move(-1)
+		return nil, false, nil
+	case keyDown, keyTab:
+		f.move(1)
+		return nil, false, nil
+	case keyPageUp:
+		f.scroll = max(f.scroll-10, 0)
+		return nil, false, nil
+	case keyPageDown:
+		f.scroll = max(min(f.scroll+10, len(f.preview)-1), 0)
+		return nil, false, nil
+	}
+
+	fl := f.focus
+	if fl == nil {
+		if k.kind == keyEnter {
+			return f.submit()
+		}
+		return nil, false, nil
+	}
+
+	switch {
+	case fl.param.Type == "boolean":
+		f.editBoolean(fl, k)
+	case len(fl.param.Options) > 0:
+		f.editOptions(fl, k)
+	default:
+		f.editText(fl, k)
+	}
+	f.check()
+	return nil, false, nil
+}
+
+// submit accepts the values if they render, asking the confirmation
+// question first.
+func (f *form) submit() (map[string]string, bool, error) {
+	f.submitted = true
+	f.check()
+
+	if f.failure != "" {
+		f.status = "The configuration does not render; see the preview"
+		return nil, false, nil
+	}
+	if len(f.problems) > 0 {
+		for _, fl := range f.visible() {
+			if len(f.problems[fl.param.Name]) > 0 {
+				f.focus = fl
+				break
+			}
+		}
+		f.status = fmt.Sprintf("%d parameter(s) need attention", len(f.problems))
+		return nil, false, nil
+	}
+
+	values := f.values()
+	if f.opts.Confirm != nil {
+		f.question = f.opts.Confirm(values)
+	}
+	if f.question == "" {
+		return values, true, nil
+	}
+	return nil, false, nil
+}
+
+func (f *form) editText(fl *field, k key) {
+	switch k.kind {
+	case keyEnter:
+		f.move(1)
+		return
+	case keyLeft:
+		fl.cursor = max(fl.cursor-1, 0)
+		return
+	case keyRight:
+		fl.cursor = min(fl.cursor+1, len(fl.value))
+		return
+	case keyHome:
+		fl.cursor = 0
+		return
+	case keyEnd:
+		fl.cursor = len(fl.value)
+		return
+	case keyRune:
+		fl.value = append(fl.value[:fl.cursor], append([]rune{k.r}, fl.value[fl.cursor:]...)...)
+		fl.cursor++
+	case keyBackspace:
+		if fl.cursor == 0 {
+			return
+		}
+		fl.value = append(fl.value[:fl.cursor-1], fl.value[fl.cursor:]...)
+		fl.cursor--
+	case keyDelete:
+		if fl.cursor == len(fl.value) {
+			return
+		}
+		fl.value = append(fl.value[:fl.cursor], fl.value[fl.cursor+1:]...)
+	case keyCtrlU:
+		fl.value = nil
+		fl.cursor = 0
+	default:
+		return
+	}
+	fl.touched = true
+}
+
+func (f *form) editBoolean(fl *field, k key) {
+	switch {
+	case k.kind == keyEnter:
+		f.move(1)
+	case k.kind == keyLeft, k.kind == keyRight, k.kind == keyRune && k.r == ' ':
+		if fl.text() == "true" {
+			fl.set("false")
+		} else {
+			fl.set("true")
+		}
+	case k.kind == keyRune && strings.ContainsRune("yYtT", k.r):
+		fl.set("true")
+	case k.kind == keyRune && strings.ContainsRune("nNfF", k.r):
+		fl.set("false")
+	case k.kind == keyBackspace, k.kind == keyDelete, k.kind == keyCtrlU:
+		fl.set("")
+	}
+}
+
+func (f *form) editOptions(fl *field, k key) {
+	choices := fl.choices()
+	current := 0
+	for i, choice := range choices {
+		if choice == fl.text() {
+			current = i
+		}
+	}
+
+	switch {
+	case k.kind == keyEnter, k.kind == keyRune && k.r == ' ':
+		f.dropdown = current
+	case k.kind == keyLeft:
+		fl.set(choices[(current+len(choices)-1)%len(choices)])
+	case k.kind == keyRight:
+		fl.set(choices[(current+1)%len(choices)])
+	case k.kind == keyRune:
+		// Jump to the next choice starting with the typed character
+		for i := 1; i <= len(choices); i++ {
+			choice := choices[(current+i)%len(choices)]
+			if strings.HasPrefix(strings.ToLower(choice), strings.ToLower(string(k.r))) {
+				fl.set(choice)
+				break
+			}
+		}
+	}
+}
+
+func (f *form) handleDropdown(k key) {
+	choices := f.focus.choices()
+	switch k.kind {
+	case keyUp, keyBacktab:
+		f.dropdown = max(f.dropdown-1, 0)
+	case keyDown, keyTab:
+		f.dropdown = min(f.dropdown+1, len(choices)-1)
+	case keyEnter:
+		f.focus.set(choices[f.dropdown])
+		f.dropdown = -1
+		f.check()
+	case keyEsc:
+		f.dropdown = -1
+	}
+}
+
+func (f *form) view(width, height int) *canvas {
+	c := newCanvas(width)
+	c.print(styleBold, " "+f.opts.Title)
+	c.newline()
+	m := f.tmpl.Metadata
+	subtitle := " template " + f.tmpl.Name
+	if m.Description != "" {
+		subtitle += " · " + m.Description
+	}
+	c.print(styleDim, subtitle)
+	c.newline()
+	c.rule()
+
+	footer := f.footer(width)
+	bodyHeight := max(height-len(c.lines)-len(footer.lines), 1)
+
+	// The preview goes beside the form on wide terminals and below it
+	// otherwise
+	if width >= 100 {
+		formWidth := width * 2 / 5
+		left := f.formView(formWidth, bodyHeight)
+		right := f.previewView(width-formWidth-1, bodyHeight)
+		c.append(beside(left, right))
+	} else {
+		formHeight := max(bodyHeight/2, 1)
+		c.append(f.formView(width, formHeight))
+		previewHeight := bodyHeight - formHeight - 1
+		if previewHeight > 0 {
+			c.rule()
+			c.append(f.previewView(width, previewHeight))
+		}
+	}
+
+	c.append(footer)
+	if f.question != "" {
+		c.cursorRow = -1
+	}
+	return c
+}
+
+// formView draws the fields, scrolled so the focused one is visible.
+func (f *form) formView(width, height int) *canvas {
+	c := newCanvas(width)
+	labelWidth := 0
+	for _, fl := range f.fields {
+		labelWidth = max(labelWidth, len(fl.param.Name)+1)
+	}
+	labelWidth = min(labelWidth, width/2)
+
+	focusTop, focusBottom := 0, 0
+	for _, fl := range f.visible() {
+		focused := fl == f.focus
+		if focused {
+			focusTop = len(c.lines)
+		}
+
+		label := fl.param.Name
+		if fl.param.Required {
+			label += "*"
+		}
+		if focused {
+			c.print(styleCyan+styleBold, " › ")
+			c.print(styleBold, label)
+		} else {
+			c.print("", "   "+label)
+		}
+		c.pad(3 + labelWidth + 1)
+		f.printValue(c, fl, focused, width-c.used)
+		c.newline()
+
+		if focused && f.dropdown >= 0 {
+			for i, choice := range fl.choices() {
+				c.pad(3 + labelWidth + 1)
+				style := ""
+				if i == f.dropdown {
+					style = styleReverse
+				}
+				if choice == "" {
+					c.print(style+styleDim, " (none) ")
+				} else {
+					c.print(style, " "+choice+" ")
+				}
+				c.newline()
+			}
+		}
+
+		if problems := f.problems[fl.param.Name]; len(problems) > 0 && (fl.touched || f.submitted) {
+			for _, problem := range problems {
+				c.pad(3 + labelWidth + 1)
+				c.print(styleRed, "✗ "+problem)
+				c.newline()
+			}
+		}
+		if focused {
+			focusBottom = len(c.lines)
+		}
+	}
+
+	c.newline()
+	if f.focus == nil {
+		focusTop = len(c.lines)
+		focusBottom = focusTop + 1
+		c.print(styleCyan+styleBold, " › ")
+		c.print(styleReverse+styleBold, " Generate ")
+	} else {
+		c.print("", "   ")
+		c.print(styleBold, "[ Generate ]")
+	}
+	c.newline()
+
+	if focusBottom > f.formTop+height {
+		f.formTop = focusBottom - height
+	}
+	if focusTop < f.formTop {
+		f.formTop = focusTop
+	}
+	return c.window(f.formTop, height)
+}
+
+// printValue draws the input of fl in at most width columns.
+func (f *form) printValue(c *canvas, fl *field, focused bool, width int) {
+	switch {
+	case fl.param.Type == "boolean":
+		switch fl.text() {
+		case "true":
+			c.print(styleGreen, "[x]")
+			c.print("", " true")
+		case "false":
+			c.print("", "[ ] false")
+		case "":
+			// Unset booleans take their default, or false
+			if fl.placeholder == "true" {
+				c.print(styleDim, "[x] true")
+			} else {
+				c.print(styleDim, "[ ] false")
+			}
+		default:
+			c.print(styleRed, "[?] "+fl.text())
+		}
+		return
+	case len(fl.param.Options) > 0:
+		c.print(styleDim, "‹ ")
+		if fl.text() == "" {
+			c.print(styleDim, placeholder(fl))
+		} else {
+			c.print("", fl.text())
+		}
+		c.print(styleDim, " ›")
+		return
+	}
+
+	if len(fl.value) == 0 {
+		if focused {
+			c.setCursor()
+		}
+		c.print(styleDim, fl.placeholder)
+		return
+	}
+
+	shown := fl.value
+	if fl.param.Sensitive {
+		shown = []rune(strings.Repeat("•", len(fl.value)))
+	}
+	// Long values scroll so the cursor stays visible
+	start := 0
+	if width > 1 && fl.cursor >= width {
+		start = fl.cursor - width + 1
+	}
+	for i := start; i <= len(shown); i++ {
+		if focused && i == fl.cursor {
+			c.setCursor()
+		}
+		if i < len(shown) {
+			c.print("", string(shown[i]))
+		}
+	}
+}
+
+func placeholder(fl *field) string {
+	if fl.placeholder != "" {
+		return fl.placeholder
+	}
+	return "(none)"
+}
+
+// previewView draws the rendered configuration, or why there is none.
+func (f *form) previewView(width, height int) *canvas {
+	c := newCanvas(width)
+	c.print(styleBold, " Preview")
+
+	switch {
+	case f.failure != "":
+		c.newline()
+		c.print(styleRed, " "+f.failure)
+		c.newline()
+	case len(f.problems) > 0:
+		c.newline()
+		c.print(styleDim, " Waiting for valid values:")
+		c.newline()
+		for _, fl := range f.fields {
+			for _, problem := range f.problems[fl.param.Name] {
+				c.print(styleDim, fmt.Sprintf("   %s: %s", fl.param.Name, problem))
+				c.newline()
+			}
+		}
+	default:
+		f.scroll = min(f.scroll, max(len(f.preview)-height+1, 0))
+		if len(f.preview) > height-1 {
+			c.print(styleDim, fmt.Sprintf("  lines %d-%d of %d · PgUp/PgDn", f.scroll+1, min(f.scroll+height-1, len(f.preview)), len(f.preview)))
+		}
+		c.newline()
+		for _, line := range f.preview[f.scroll:] {
+			c.print("", " "+line)
+			c.newline()
+		}
+	}
+	return c.window(0, height)
+}
+
+// footer describes the focused field and the keys, or asks the
+// confirmation question.
+func (f *form) footer(width int) *canvas {
+	c := newCanvas(width)
+	c.rule()
+
+	if f.question != "" {
+		for _, line := range strings.Split(f.question, "\n") {
+			c.print(styleBold, " "+line)
+			c.newline()
+		}
+		c.print(styleCyan, " y")
+		c.print(styleDim, " write · ")
+		c.print(styleCyan, "n")
+		c.print(styleDim, " back to the form")
+		c.newline()
+		return c
+	}
+
+	if fl := f.focus; fl != nil {
+		info := fl.doc.Type
+		if fl.param.Required {
+			info += ", required"
+		}
+		if fl.doc.Constraints != "" {
+			info += ", " + fl.doc.Constraints
+		}
+		if len(fl.param.Options) > 0 {
+			info += ", one of " + strings.Join(fl.param.Options, ", ")
+		}
+		c.print(styleBold, " "+fl.param.Name)
+		c.print(styleDim, " ("+info+")")
+		if fl.param.Description != "" {
+			c.print("", " "+fl.param.Description)
+		}
+		c.newline()
+		switch {
+		case fl.param.Deprecated != "":
+			c.print(styleRed, " deprecated: "+fl.param.Deprecated)
+			c.newline()
+		case fl.param.Type == "secret":
+			c.print(styleDim, " env:VAR, file:/path or store:key")
+			c.newline()
+		}
+	}
+
+	if f.status != "" {
+		c.print(styleRed, " "+f.status)
+		c.newline()
+	}
+
+	keys := " ↑↓ move · ctrl-s generate · esc cancel"
+	switch {
+	case f.dropdown >= 0:
+		keys = " ↑↓ choose · enter select · esc close"
+	case f.focus == nil:
+		keys = " enter generate · ↑ back · esc cancel"
+	case f.focus.param.Type == "boolean":
+		keys = " space toggle ·" + keys
+	case len(f.focus.param.Options) > 0:
+		keys = " ←→ or enter choose ·" + keys
+	}
+	c.print(styleDim, keys+" · PgUp/PgDn scroll preview")
+	c.newline()
+	return c
+}
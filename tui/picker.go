@@ -0,0 +1,219 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Item is a template offered by PickTemplate.
+type Item struct {
+	Name        string
+	Description string
+}
+
+// PickTemplate lets the user choose one of items by typing part of its
+// name or description. It returns ErrCancelled if the user leaves instead.
+func PickTemplate(items []Item) (string, error) {
+	t, err := open()
+	if err != nil {
+		return "", err
+	}
+	defer t.close()
+
+	p := &picker{items: items}
+	p.filter()
+	for {
+		t.resize()
+		t.draw(p.view(t.width, t.height))
+
+		keys, err := t.readKeys()
+		if err != nil {
+			return "", err
+		}
+		for _, k := range keys {
+			if name, done, err := p.handle(k); done {
+				return name, err
+			}
+		}
+	}
+}
+
+// picker is the state of PickTemplate.
+type picker struct {
+	items    []Item
+	query    []rune
+	matches  []itemMatch
+	selected int
+	offset   int
+}
+
+// itemMatch is an item matching the query, with the rune positions that
+// matched in its name or description.
+type itemMatch struct {
+	item     Item
+	score    int
+	nameHits map[int]bool
+	descHits map[int]bool
+}
+
+// filter finds the items matching the query, best first. Name matches
+// rank above description matches.
+func (p *picker) filter() {
+	query := string(p.query)
+	p.matches = p.matches[:0]
+	for _, item := range p.items {
+		if score, hits, ok := fuzzyMatch(query, item.Name); ok {
+			p.matches = append(p.matches, itemMatch{item: item, score: 2 * score, nameHits: hits})
+		} else if score, hits, ok := fuzzyMatch(query, item.Description); ok {
+			p.matches = append(p.matches, itemMatch{item: item, score: score, descHits: hits})
+		}
+	}
+	sort.SliceStable(p.matches, func(i, j int) bool {
+		return p.matches[i].score > p.matches[j].score
+	})
+	p.selected, p.offset = 0, 0
+}
+
+// handle applies a key; done is set once a template is chosen or the
+// picker is left.
+func (p *picker) handle(k key) (name string, done bool, err error) {
+	switch k.kind {
+	case keyEsc, keyCtrlC:
+		return "", true, ErrCancelled
+	case keyEnter:
+		if len(p.matches) > 0 {
+			return p.matches[p.selected].item.Name, true, nil
+		}
+	case keyUp, keyBacktab:
+		if p.selected > 0 {
+			p.selected--
+		}
+	case keyDown, keyTab:
+		if p.selected < len(p.matches)-1 {
+			p.selected++
+		}
+	case keyPageUp:
+		p.selected = max(p.selected-10, 0)
+	case keyPageDown:
+		p.selected = max(min(p.selected+10, len(p.matches)-1), 0)
+	case keyBackspace:
+		if len(p.query) > 0 {
+			p.query = p.query[:len(p.query)-1]
+			p.filter()
+		}
+	case keyCtrlU:
+		p.query = nil
+		p.filter()
+	case keyRune:
+		p.query = append(p.query, k.r)
+		p.filter()
+	}
+	return "", false, nil
+}
+
+func (p *picker) view(width, height int) *canvas {
+	c := newCanvas(width)
+	c.print(styleBold, " Select a template")
+	count := fmt.Sprintf("%d/%d ", len(p.matches), len(p.items))
+	c.pad(width - len(count))
+	c.print(styleDim, count)
+	c.newline()
+
+	c.print(styleCyan, " > ")
+	c.print("", string(p.query))
+	c.setCursor()
+	c.newline()
+	c.rule()
+
+	rows := max(height-5, 1)
+	if p.selected < p.offset {
+		p.offset = p.selected
+	}
+	if p.selected >= p.offset+rows {
+		p.offset = p.selected - rows + 1
+	}
+
+	nameWidth := 0
+	for _, m := range p.matches {
+		nameWidth = max(nameWidth, len([]rune(m.item.Name)))
+	}
+
+	list := newCanvas(width)
+	if len(p.matches) == 0 {
+		list.print(styleDim, "   no template matches")
+		list.newline()
+	}
+	for i := p.offset; i < len(p.matches) && i < p.offset+rows; i++ {
+		m := p.matches[i]
+		style := ""
+		if i == p.selected {
+			style = styleReverse
+			list.print(styleCyan+styleBold, " ▸ ")
+		} else {
+			list.print("", "   ")
+		}
+		printHits(list, style+styleBold, style+styleBold+styleCyan, m.item.Name, m.nameHits)
+		list.pad(3 + nameWidth + 2)
+		printHits(list, styleDim, styleCyan, m.item.Description, m.descHits)
+		list.newline()
+	}
+	c.append(list.window(0, rows))
+
+	c.rule()
+	c.print(styleDim, " type to filter · ↑↓ move · enter select · esc cancel")
+	c.newline()
+	return c
+}
+
+// printHits prints text, highlighting the runes at the hit positions.
+func printHits(c *canvas, style, hitStyle, text string, hits map[int]bool) {
+	runes := []rune(text)
+	for start := 0; start < len(runes); {
+		end := start + 1
+		for end < len(runes) && hits[end] == hits[start] {
+			end++
+		}
+		if hits[start] {
+			c.print(hitStyle, string(runes[start:end]))
+		} else {
+			c.print(style, string(runes[start:end]))
+		}
+		start = end
+	}
+}
+
+// fuzzyMatch reports whether the runes of query appear in text in order,
+// ignoring case. Runes that follow each other or start a word score
+// higher; hits are the positions of the matched runes in text.
+func fuzzyMatch(query, text string) (score int, hits map[int]bool, ok bool) {
+	if query == "" {
+		return 0, nil, true
+	}
+
+	q := []rune(query)
+	hits = make(map[int]bool)
+	last := -2
+	qi := 0
+	runes := []rune(text)
+	for i := 0; i < len(runes) && qi < len(q); i++ {
+		if unicode.ToLower(runes[i]) != unicode.ToLower(q[qi]) {
+			continue
+		}
+		score++
+		if i == last+1 {
+			score += 4
+		}
+		if i == 0 || strings.ContainsRune(" -_./", runes[i-1]) {
+			score += 2
+		}
+		hits[i] = true
+		last = i
+		qi++
+	}
+	if qi < len(q) {
+		return 0, nil, false
+	}
+	return score, hits, true
+}
@@ -0,0 +1,386 @@
+// Package tui is the full-screen interface of generate: a fuzzy template
+// picker and a parameter form that validates while typing and previews the
+// rendered configuration. It draws with ANSI escape sequences and switches
+// the terminal to raw mode with stty, so it only runs when prompts are
+// allowed and both stdin and stdout are terminals; callers fall back to the
+// line prompts of package prompt otherwise.
+package tui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/vourteen14/ngcli/prompt"
+)
+
+// ErrCancelled is returned when the user leaves with Esc or Ctrl-C.
+var ErrCancelled = errors.New("cancelled")
+
+// Available reports whether the full-screen interface can be used.
+func Available() bool {
+	if runtime.GOOS == "windows" || !prompt.Interactive() || !prompt.IsTerminal(os.Stdout) {
+		return false
+	}
+	if term := os.Getenv("TERM"); term == "" || term == "dumb" {
+		return false
+	}
+	_, err := exec.LookPath("stty")
+	return err == nil
+}
+
+// Text styles.
+const (
+	styleReset   = "\x1b[0m"
+	styleBold    = "\x1b[1m"
+	styleDim     = "\x1b[2m"
+	styleReverse = "\x1b[7m"
+	styleRed     = "\x1b[31m"
+	styleGreen   = "\x1b[32m"
+	styleCyan    = "\x1b[36m"
+)
+
+// terminal is the screen in raw mode.
+type terminal struct {
+	saved  string
+	out    *bufio.Writer
+	width  int
+	height int
+}
+
+// open switches to the alternate screen in raw mode; close restores the
+// terminal.
+func open() (*terminal, error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read terminal settings: %w", err)
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, fmt.Errorf("failed to switch terminal to raw mode: %w", err)
+	}
+
+	t := &terminal{saved: strings.TrimSpace(saved), out: bufio.NewWriter(os.Stdout)}
+	t.out.WriteString("\x1b[?1049h\x1b[?25l")
+	t.resize()
+	return t, nil
+}
+
+func (t *terminal) close() {
+	t.out.WriteString(styleReset + "\x1b[?25h\x1b[?1049l")
+	t.out.Flush()
+	stty(t.saved)
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// resize reads the size of the terminal. There is no SIGWINCH handling;
+// a resized terminal is redrawn on the next key.
+func (t *terminal) resize() {
+	t.width, t.height = 80, 24
+	size, err := stty("size")
+	if err != nil {
+		return
+	}
+	var rows, cols int
+	if _, err := fmt.Sscan(size, &rows, &cols); err == nil && rows > 0 && cols > 0 {
+		t.width, t.height = cols, rows
+	}
+}
+
+// draw replaces the screen with the lines of c.
+func (t *terminal) draw(c *canvas) {
+	t.out.WriteString("\x1b[?25l\x1b[H")
+	for i := 0; i < t.height; i++ {
+		if i < len(c.lines) {
+			t.out.WriteString(c.lines[i])
+		}
+		t.out.WriteString("\x1b[K")
+		if i < t.height-1 {
+			t.out.WriteString("\r\n")
+		}
+	}
+	if c.cursorRow >= 0 && c.cursorRow < t.height {
+		fmt.Fprintf(t.out, "\x1b[%d;%dH\x1b[?25h", c.cursorRow+1, c.cursorCol+1)
+	}
+	t.out.Flush()
+}
+
+// keyKind identifies a key; printable characters are keyRune.
+type keyKind int
+
+const (
+	keyRune keyKind = iota
+	keyEnter
+	keyTab
+	keyBacktab
+	keyBackspace
+	keyDelete
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyPageUp
+	keyPageDown
+	keyEsc
+	keyCtrlC
+	keyCtrlS
+	keyCtrlU
+)
+
+type key struct {
+	kind keyKind
+	r    rune
+}
+
+// readKeys waits for input and returns the keys read; pasted text arrives
+// as several keys at once.
+func (t *terminal) readKeys() ([]key, error) {
+	buf := make([]byte, 256)
+	n, err := os.Stdin.Read(buf)
+	if n == 0 && err == nil {
+		err = errors.New("no input")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+	return parseKeys(buf[:n]), nil
+}
+
+// parseKeys decodes raw terminal input, including the escape sequences of
+// cursor and editing keys. Unknown sequences are dropped.
+func parseKeys(b []byte) []key {
+	var keys []key
+	for i := 0; i < len(b); {
+		c := b[i]
+		switch {
+		case c == 27 && i+1 < len(b) && (b[i+1] == '[' || b[i+1] == 'O'):
+			j := i + 2
+			for j < len(b) && (b[j] < 0x40 || b[j] > 0x7e) {
+				j++
+			}
+			if j >= len(b) {
+				return keys
+			}
+			if k, ok := escapeKey(string(b[i+2:j]), b[j]); ok {
+				keys = append(keys, key{kind: k})
+			}
+			i = j + 1
+			continue
+		case c == 27:
+			keys = append(keys, key{kind: keyEsc})
+		case c == '\r' || c == '\n':
+			keys = append(keys, key{kind: keyEnter})
+		case c == '\t':
+			keys = append(keys, key{kind: keyTab})
+		case c == 127 || c == 8:
+			keys = append(keys, key{kind: keyBackspace})
+		case c == 3:
+			keys = append(keys, key{kind: keyCtrlC})
+		case c == 19:
+			keys = append(keys, key{kind: keyCtrlS})
+		case c == 21:
+			keys = append(keys, key{kind: keyCtrlU})
+		case c == 1:
+			keys = append(keys, key{kind: keyHome})
+		case c == 5:
+			keys = append(keys, key{kind: keyEnd})
+		case c == 14:
+			keys = append(keys, key{kind: keyDown})
+		case c == 16:
+			keys = append(keys, key{kind: keyUp})
+		case c < 32:
+		default:
+			r, size := utf8.DecodeRune(b[i:])
+			if r != utf8.RuneError {
+				keys = append(keys, key{kind: keyRune, r: r})
+			}
+			i += size
+			continue
+		}
+		i++
+	}
+	return keys
+}
+
+// escapeKey maps the parameters and final byte of a CSI or SS3 sequence.
+func escapeKey(params string, final byte) (keyKind, bool) {
+	switch final {
+	case 'A':
+		return keyUp, true
+	case 'B':
+		return keyDown, true
+	case 'C':
+		return keyRight, true
+	case 'D':
+		return keyLeft, true
+	case 'H':
+		return keyHome, true
+	case 'F':
+		return keyEnd, true
+	case 'Z':
+		return keyBacktab, true
+	case '~':
+		switch params {
+		case "1", "7":
+			return keyHome, true
+		case "4", "8":
+			return keyEnd, true
+		case "3":
+			return keyDelete, true
+		case "5":
+			return keyPageUp, true
+		case "6":
+			return keyPageDown, true
+		}
+	}
+	return 0, false
+}
+
+// canvas collects the lines of a frame, cutting text at its width.
+type canvas struct {
+	width  int
+	lines  []string
+	widths []int
+	line   strings.Builder
+	used   int
+	// cursorRow is -1 while the cursor is hidden
+	cursorRow int
+	cursorCol int
+}
+
+func newCanvas(width int) *canvas {
+	if width < 1 {
+		width = 1
+	}
+	return &canvas{width: width, cursorRow: -1}
+}
+
+// print adds text in style to the current line. Tabs are expanded and
+// other control characters dropped so they cannot move the cursor.
+func (c *canvas) print(style, text string) {
+	var b strings.Builder
+	for _, r := range text {
+		if r == '\t' {
+			for c.used < c.width {
+				b.WriteByte(' ')
+				c.used++
+				if c.used%4 == 0 {
+					break
+				}
+			}
+			continue
+		}
+		if r < 32 || r == 127 || c.used >= c.width {
+			continue
+		}
+		b.WriteRune(r)
+		c.used++
+	}
+	if b.Len() == 0 {
+		return
+	}
+	if style == "" {
+		c.line.WriteString(b.String())
+		return
+	}
+	c.line.WriteString(style + b.String() + styleReset)
+}
+
+// pad fills the current line with spaces up to column col.
+func (c *canvas) pad(col int) {
+	for c.used < col && c.used < c.width {
+		c.line.WriteByte(' ')
+		c.used++
+	}
+}
+
+// setCursor puts the cursor at the current position.
+func (c *canvas) setCursor() {
+	c.cursorRow = len(c.lines)
+	c.cursorCol = c.used
+	if c.cursorCol >= c.width {
+		c.cursorCol = c.width - 1
+	}
+}
+
+func (c *canvas) newline() {
+	c.lines = append(c.lines, c.line.String())
+	c.widths = append(c.widths, c.used)
+	c.line.Reset()
+	c.used = 0
+}
+
+// rule draws a horizontal line.
+func (c *canvas) rule() {
+	c.print(styleDim, strings.Repeat("─", c.width))
+	c.newline()
+}
+
+// window keeps rows [from, from+n) of c, padding with empty lines.
+func (c *canvas) window(from, n int) *canvas {
+	w := newCanvas(c.width)
+	for i := from; i < from+n; i++ {
+		if i >= 0 && i < len(c.lines) {
+			w.lines = append(w.lines, c.lines[i])
+			w.widths = append(w.widths, c.widths[i])
+		} else {
+			w.lines = append(w.lines, "")
+			w.widths = append(w.widths, 0)
+		}
+	}
+	if c.cursorRow >= from && c.cursorRow < from+n {
+		w.cursorRow = c.cursorRow - from
+		w.cursorCol = c.cursorCol
+	}
+	return w
+}
+
+// append adds the lines of other below the lines of c.
+func (c *canvas) append(other *canvas) {
+	if other.cursorRow >= 0 {
+		c.cursorRow = len(c.lines) + other.cursorRow
+		c.cursorCol = other.cursorCol
+	}
+	c.lines = append(c.lines, other.lines...)
+	c.widths = append(c.widths, other.widths...)
+}
+
+// beside joins left and right, row by row, with a vertical line between.
+func beside(left, right *canvas) *canvas {
+	c := newCanvas(left.width + 1 + right.width)
+	rows := len(left.lines)
+	if len(right.lines) > rows {
+		rows = len(right.lines)
+	}
+	for i := 0; i < rows; i++ {
+		var b strings.Builder
+		width := 0
+		if i < len(left.lines) {
+			b.WriteString(left.lines[i])
+			width = left.widths[i]
+		}
+		b.WriteString(strings.Repeat(" ", left.width-width))
+		b.WriteString(styleDim + "│" + styleReset)
+		if i < len(right.lines) {
+			b.WriteString(right.lines[i])
+		}
+		c.lines = append(c.lines, b.String())
+		c.widths = append(c.widths, c.width)
+	}
+	if left.cursorRow >= 0 {
+		c.cursorRow, c.cursorCol = left.cursorRow, left.cursorCol
+	}
+	return c
+}
@@ -0,0 +1,261 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiffOp is a single line operation produced by DiffLines.
+type DiffOp struct {
+	Kind byte // ' ' unchanged, '-' removed from a, '+' added in b
+	Line string
+}
+
+// SplitLines splits content into lines, dropping the empty element that a
+// trailing newline would otherwise produce.
+func SplitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	lines := strings.Split(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// DiffLines computes a line-based diff of a and b using the longest common
+// subsequence. It is meant for configuration-sized inputs.
+func DiffLines(a, b []string) []DiffOp {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []DiffOp
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, DiffOp{Kind: ' ', Line: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, DiffOp{Kind: '-', Line: a[i]})
+			i++
+		default:
+			ops = append(ops, DiffOp{Kind: '+', Line: b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, DiffOp{Kind: '-', Line: a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, DiffOp{Kind: '+', Line: b[j]})
+	}
+
+	return ops
+}
+
+// UnifiedDiff returns a unified diff between a and b with three lines of
+// context, or an empty string when they are identical.
+func UnifiedDiff(a, b, fromName, toName string) string {
+	ops := DiffLines(SplitLines(a), SplitLines(b))
+
+	changed := false
+	for _, op := range ops {
+		if op.Kind != ' ' {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	const context = 3
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+
+	// Line numbers (1-based) in a and b at the start of every op
+	aLine := make([]int, len(ops)+1)
+	bLine := make([]int, len(ops)+1)
+	aLine[0], bLine[0] = 1, 1
+	for k, op := range ops {
+		aLine[k+1], bLine[k+1] = aLine[k], bLine[k]
+		if op.Kind != '+' {
+			aLine[k+1]++
+		}
+		if op.Kind != '-' {
+			bLine[k+1]++
+		}
+	}
+
+	k := 0
+	for k < len(ops) {
+		if ops[k].Kind == ' ' {
+			k++
+			continue
+		}
+
+		start := k - context
+		if start < 0 {
+			start = 0
+		}
+
+		// Extend the hunk while the next change is within reach of the context
+		end := k
+		for end < len(ops) {
+			if ops[end].Kind != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(ops) && ops[next].Kind == ' ' {
+				next++
+			}
+			if next < len(ops) && next-end <= 2*context {
+				end = next
+				continue
+			}
+			end += context
+			if end > len(ops) {
+				end = len(ops)
+			}
+			break
+		}
+
+		aCount, bCount := 0, 0
+		for _, op := range ops[start:end] {
+			if op.Kind != '+' {
+				aCount++
+			}
+			if op.Kind != '-' {
+				bCount++
+			}
+		}
+
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", aLine[start], aCount, bLine[start], bCount)
+		for _, op := range ops[start:end] {
+			fmt.Fprintf(&out, "%c%s\n", op.Kind, op.Line)
+		}
+
+		k = end
+	}
+
+	return out.String()
+}
+
+// Merge3 merges the changes from base to ours and from base to theirs. Where
+// both sides changed the same region differently, the region is emitted
+// between conflict markers and counted in the returned conflict count.
+func Merge3(base, ours, theirs string, oursLabel, theirsLabel string) (string, int) {
+	baseLines := SplitLines(base)
+	oursLines := SplitLines(ours)
+	theirsLines := SplitLines(theirs)
+
+	matchOurs := matchLines(baseLines, oursLines)
+	matchTheirs := matchLines(baseLines, theirsLines)
+
+	var merged []string
+	conflicts := 0
+	i, o, t := 0, 0, 0
+
+	for {
+		// Next base line that is unchanged on both sides
+		j := i
+		for j < len(baseLines) && (matchOurs[j] < 0 || matchTheirs[j] < 0) {
+			j++
+		}
+
+		oEnd, tEnd := len(oursLines), len(theirsLines)
+		if j < len(baseLines) {
+			oEnd, tEnd = matchOurs[j], matchTheirs[j]
+		}
+
+		baseChunk := baseLines[i:j]
+		oursChunk := oursLines[o:oEnd]
+		theirsChunk := theirsLines[t:tEnd]
+
+		switch {
+		case equalLines(oursChunk, baseChunk):
+			merged = append(merged, theirsChunk...)
+		case equalLines(theirsChunk, baseChunk), equalLines(oursChunk, theirsChunk):
+			merged = append(merged, oursChunk...)
+		default:
+			conflicts++
+			merged = append(merged, "<<<<<<< "+oursLabel)
+			merged = append(merged, oursChunk...)
+			merged = append(merged, "=======")
+			merged = append(merged, theirsChunk...)
+			merged = append(merged, ">>>>>>> "+theirsLabel)
+		}
+
+		if j >= len(baseLines) {
+			break
+		}
+
+		merged = append(merged, baseLines[j])
+		i, o, t = j+1, oEnd+1, tEnd+1
+	}
+
+	// The trailing newline follows whichever side changed it
+	trailing := strings.HasSuffix(ours, "\n")
+	if trailing == strings.HasSuffix(base, "\n") {
+		trailing = strings.HasSuffix(theirs, "\n")
+	}
+
+	result := strings.Join(merged, "\n")
+	if trailing && len(merged) > 0 {
+		result += "\n"
+	}
+
+	return result, conflicts
+}
+
+// matchLines returns, for every line of a, the index of the line of b it is
+// paired with in the diff, or -1 when the line was removed.
+func matchLines(a, b []string) []int {
+	matches := make([]int, len(a))
+	i, j := 0, 0
+	for _, op := range DiffLines(a, b) {
+		switch op.Kind {
+		case ' ':
+			matches[i] = j
+			i++
+			j++
+		case '-':
+			matches[i] = -1
+			i++
+		case '+':
+			j++
+		}
+	}
+	return matches
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+
+	"github.com/vourteen14/ngcli/errcode"
 )
 
 func ParseSetFlags(setFlags []string) (map[string]string, error) {
@@ -30,6 +32,9 @@ func ParseSetFlags(setFlags []string) (map[string]string, error) {
 	return params, nil
 }
 
+// DetectNginxConfigPath guesses the configuration directory from the
+// directories that exist. layout.Resolve asks nginx first and only falls
+// back to this guess.
 func DetectNginxConfigPath() (string, error) {
 	sitesAvailable := "/etc/nginx/sites-available"
 	if _, err := os.Stat(sitesAvailable); err == nil {
@@ -51,14 +56,6 @@ func DetectNginxConfigPath() (string, error) {
 	return "", fmt.Errorf("unable to detect nginx configuration directory")
 }
 
-func DetectNginxEnabledPath() (string, bool) {
-	sitesEnabled := "/etc/nginx/sites-enabled"
-	if _, err := os.Stat(sitesEnabled); err == nil {
-		return sitesEnabled, true
-	}
-	return "", false
-}
-
 func isDebianBased() bool {
 	debianFiles := []string{
 		"/etc/debian_version",
@@ -114,5 +111,50 @@ func ResolveConfigPath(configDir, configName string) (string, error) {
 		return confPath, nil
 	}
 
-	return "", fmt.Errorf("configuration file not found: %s", configName)
-}
\ No newline at end of file
+	return "", errcode.Errorf(errcode.ConfigNotFound, "configuration file not found: %s", configName)
+}
+
+// CompareVersions compares dotted version strings such as "1.2.0" and
+// "v1.10". It returns -1, 0 or 1. Missing components count as zero and any
+// pre-release suffix after "-" is ignored.
+func CompareVersions(a, b string) int {
+	partsA := versionParts(a)
+	partsB := versionParts(b)
+
+	for len(partsA) < len(partsB) {
+		partsA = append(partsA, 0)
+	}
+	for len(partsB) < len(partsA) {
+		partsB = append(partsB, 0)
+	}
+
+	for i := range partsA {
+		if partsA[i] < partsB[i] {
+			return -1
+		}
+		if partsA[i] > partsB[i] {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+func versionParts(version string) []int {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	version, _, _ = strings.Cut(version, "-")
+
+	var parts []int
+	for _, field := range strings.Split(version, ".") {
+		n := 0
+		for _, r := range field {
+			if r < '0' || r > '9' {
+				break
+			}
+			n = n*10 + int(r-'0')
+		}
+		parts = append(parts, n)
+	}
+
+	return parts
+}